@@ -0,0 +1,61 @@
+package ddhcprf
+
+import (
+	"crypto/elliptic"
+	"math/big"
+
+	"github.com/sachaservan/cprf/ddh-cprf/ec"
+)
+
+// KeyGenAffine generates a CPRF key for affine predicates <z,x> = c
+// on input vectors of the given length. The key has one extra
+// coordinate used to homogenize the predicate into <z',x'> = 0
+// with z' = (z, -c) and x' = (x, 1).
+// n: number of elements in the Naor-Reingold PRF key
+// length: length of the input vector
+// Outputs public parameters and a master key
+func KeyGenAffine(n int, length int) (*PublicParameters, *MasterKey, error) {
+	return KeyGen(n, length+1)
+}
+
+// ConstrainAffine outputs a constrained key for the predicate <z,x> = c.
+// The key must have been generated with KeyGenAffine for len(z).
+func (msk *MasterKey) ConstrainAffine(z []*big.Int, c *big.Int) (*ConstrainedKey, error) {
+	if len(z)+1 != msk.length {
+		return nil, ErrLengthMismatch
+	}
+
+	// p is the order of the eliptic curve
+	p := elliptic.P256().Params().N
+
+	zh := make([]*big.Int, len(z)+1)
+	copy(zh, z)
+	zh[len(z)] = big.NewInt(0).Neg(c)
+	zh[len(z)].Mod(zh[len(z)], p)
+
+	return msk.Constrain(zh)
+}
+
+// EvalAffine evaluates the CPRF on the homogenized input (x, 1)
+func (msk *MasterKey) EvalAffine(pp *PublicParameters, x []*big.Int) (*ec.Point, error) {
+	if len(x)+1 != msk.length {
+		return nil, ErrLengthMismatch
+	}
+	return msk.Eval(pp, homogenize(x)), nil
+}
+
+// CEvalAffine evaluates the constrained key on the homogenized input (x, 1)
+func (csk *ConstrainedKey) CEvalAffine(pp *PublicParameters, x []*big.Int) (*ec.Point, error) {
+	if len(x)+1 != csk.length {
+		return nil, ErrLengthMismatch
+	}
+	return csk.CEval(pp, homogenize(x)), nil
+}
+
+// homogenize returns the input vector (x, 1)
+func homogenize(x []*big.Int) []*big.Int {
+	xh := make([]*big.Int, len(x)+1)
+	copy(xh, x)
+	xh[len(x)] = big.NewInt(1)
+	return xh
+}
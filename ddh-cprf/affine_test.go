@@ -0,0 +1,48 @@
+package ddhcprf
+
+import (
+	"crypto/elliptic"
+	"testing"
+
+	"github.com/sachaservan/cprf/ddh-cprf/ec"
+	"github.com/sachaservan/cprf/linalg"
+)
+
+func TestCPRFAffine(t *testing.T) {
+	p := elliptic.P256().Params().N
+	field, _ := linalg.NewField(p)
+	n := 128
+	length := 25
+
+	pp, msk, _ := KeyGenAffine(n, length)
+
+	z, _ := field.RandomVector(length)
+	c, _ := field.RandomElement()
+	csk, err := msk.ConstrainAffine(z, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// x = x0 + k with <z,x0> = c and k in the kernel of z
+	x0, _ := field.Solve(linalg.Matrix{z}, linalg.Vector{c})
+	k, _ := field.SampleOrthogonal(z)
+	x, _ := field.Add(x0, k)
+
+	eval, _ := msk.EvalAffine(pp, x)
+	ceval, _ := csk.CEvalAffine(pp, x)
+	if !ec.PointsEqual(eval, ceval) {
+		t.Fatalf("EvalAffine and CEvalAffine are not equal on an authorized input")
+	}
+
+	// very small probability of failure in this test case
+	y, _ := field.RandomVector(length)
+	eval, _ = msk.EvalAffine(pp, y)
+	ceval, _ = csk.CEvalAffine(pp, y)
+	if ec.PointsEqual(eval, ceval) {
+		t.Fatalf("EvalAffine and CEvalAffine are equal on an unauthorized input")
+	}
+
+	if _, err := msk.EvalAffine(pp, y[:length-1]); err != ErrLengthMismatch {
+		t.Fatalf("expected ErrLengthMismatch, got %v", err)
+	}
+}
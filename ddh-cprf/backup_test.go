@@ -0,0 +1,198 @@
+package ddhcprf
+
+import (
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/binary"
+	"math/big"
+	"testing"
+
+	"github.com/sachaservan/cprf/ddh-cprf/ec"
+)
+
+func TestBackupRecover(t *testing.T) {
+	p := elliptic.P256().Params().N
+	n := 16
+	length := 5
+
+	pp, msk, _ := KeyGen(n, length)
+	msk.SetSchema("example/v1/0011223344556677")
+
+	shares, err := msk.Backup(3, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// round trip every share through its encoding
+	for k, share := range shares {
+		data, err := share.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		shares[k] = &BackupShare{}
+		if err := shares[k].UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, subset := range [][]int{{0, 1, 2}, {4, 2, 0}, {0, 1, 2, 3, 4}} {
+		given := make([]*BackupShare, len(subset))
+		for k, s := range subset {
+			given[k] = shares[s]
+		}
+
+		res, bad, err := RecoverMasterKey(given)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(bad) != 0 {
+			t.Fatalf("correct shares reported as wrong: %v", bad)
+		}
+		if res.Schema() != msk.Schema() {
+			t.Fatalf("schema ID was not recovered")
+		}
+
+		for trial := 0; trial < 3; trial++ {
+			x, _ := generateRandomVector(length, p)
+			if !ec.PointsEqual(msk.Eval(pp, x), res.Eval(pp, x)) {
+				t.Fatalf("Eval of the recovered key differs")
+			}
+		}
+	}
+
+	if _, _, err := RecoverMasterKey(shares[:2]); err != ErrNotEnoughShares {
+		t.Fatalf("expected ErrNotEnoughShares, got %v", err)
+	}
+}
+
+func TestBackupRecoverCompact(t *testing.T) {
+	p := elliptic.P256().Params().N
+	n := 16
+	length := 5
+
+	pp, msk, _ := KeyGenCompact(n, length)
+	shares, err := msk.Backup(2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, _, err := RecoverMasterKey(shares[1:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.IsCompact() {
+		t.Fatalf("recovered key is seed-compressed")
+	}
+
+	x, _ := generateRandomVector(length, p)
+	if !ec.PointsEqual(msk.Eval(pp, x), res.Eval(pp, x)) {
+		t.Fatalf("Eval of the recovered key differs")
+	}
+}
+
+func TestBackupWrongShares(t *testing.T) {
+	p := elliptic.P256().Params().N
+	n := 16
+	length := 5
+
+	pp, msk, _ := KeyGen(n, length)
+	_, other, _ := KeyGen(n, length)
+	shares, _ := msk.Backup(2, 4)
+	otherShares, _ := other.Backup(2, 4)
+
+	// corruption in storage is detected by the tag
+	data, _ := shares[0].MarshalBinary()
+	data[len(data)/2] ^= 1
+	if err := (&BackupShare{}).UnmarshalBinary(data); err != ErrInvalidShare {
+		t.Fatalf("expected ErrInvalidShare for a corrupted share, got %v", err)
+	}
+
+	// a share with a wrong value but a consistent encoding
+	wrong := &BackupShare{}
+	*wrong = *shares[1]
+	wrong.values = append([]*big.Int(nil), shares[1].values...)
+	wrong.values[7] = big.NewInt(0).Add(wrong.values[7], big.NewInt(1))
+
+	if _, _, err := RecoverMasterKey([]*BackupShare{shares[0], wrong}); err != ErrRecoveryFailed {
+		t.Fatalf("expected ErrRecoveryFailed with a wrong share, got %v", err)
+	}
+
+	// with an extra share the wrong one is excluded and reported
+	x, _ := generateRandomVector(length, p)
+	res, bad, err := RecoverMasterKey([]*BackupShare{wrong, shares[0], shares[2]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bad) != 1 || bad[0] != 0 {
+		t.Fatalf("expected the wrong share at position 0 to be reported, got %v", bad)
+	}
+	if !ec.PointsEqual(msk.Eval(pp, x), res.Eval(pp, x)) {
+		t.Fatalf("Eval of the recovered key differs")
+	}
+
+	// shares of another key are excluded and reported
+	res, bad, err = RecoverMasterKey([]*BackupShare{shares[3], otherShares[0], shares[1]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bad) != 1 || bad[0] != 1 {
+		t.Fatalf("expected the share of another key to be reported, got %v", bad)
+	}
+	if !ec.PointsEqual(msk.Eval(pp, x), res.Eval(pp, x)) {
+		t.Fatalf("Eval of the recovered key differs")
+	}
+
+	if _, err := msk.Backup(0, 3); err != ErrInvalidBackupShape {
+		t.Fatalf("expected ErrInvalidBackupShape, got %v", err)
+	}
+}
+
+func TestBackupShareUnmarshalCountOverflow(t *testing.T) {
+	_, msk, _ := KeyGen(16, 3)
+	shares, _ := msk.Backup(2, 3)
+
+	// a value count whose byte length is 2^64 with no values
+	empty := &BackupShare{}
+	*empty = *shares[0]
+	empty.values = nil
+	data, _ := empty.MarshalBinary()
+	body := data[:len(data)-sha256.Size-1]
+	body = binary.AppendUvarint(body, 1<<59)
+	data = append(body, backupTag(body)...)
+
+	if err := (&BackupShare{}).UnmarshalBinary(data); err != ErrInvalidShare {
+		t.Fatalf("expected ErrInvalidShare, got %v", err)
+	}
+}
+
+func TestBackupRecoverRotated(t *testing.T) {
+	p := elliptic.P256().Params().N
+	n := 16
+	length := 5
+
+	_, msk, _ := KeyGenCompact(n, length)
+	z, _ := generateRandomVector(length, p)
+	csk, _ := msk.Constrain(z)
+
+	next, token, _ := msk.Rotate()
+	shares, err := next.Backup(2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, _, err := RecoverMasterKey(shares[:2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Epoch() != next.Epoch() {
+		t.Fatalf("recovered key has epoch %d, expected %d", res.Epoch(), next.Epoch())
+	}
+
+	// keys constrained from the recovered key take the same updates
+	csk2, _ := res.Constrain(z)
+	if csk2.Epoch() != 1 {
+		t.Fatalf("key constrained from the recovered key has epoch %d", csk2.Epoch())
+	}
+	if err := csk.ApplyUpdate(token); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,379 @@
+package ddhcprf
+
+import (
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/sachaservan/cprf/ddh-cprf/ec"
+)
+
+// Blinded evaluation lets a client obtain Eval(x) from the key holder
+// without the output appearing in the protocol messages:
+//
+//  1. The client samples r and sends x and the blinded element B = g^r.
+//  2. The server computes the Naor-Reingold exponent P for x (the product
+//     of the selected inner products) and returns Z = B^P.
+//  3. The client outputs Z^{1/r} = g^P = Eval(x).
+//
+// This is not an oblivious PRF and gives no privacy against the server.
+// The server sees x in the clear, since both the inner products and the
+// hash selecting the keys depend on it, so it learns the input and can
+// always recompute the output itself. Blinding only ensures that Z is
+// uniformly distributed and independent of the output, so the output is
+// hidden from anyone who observes or logs the messages without holding
+// the key, such as a proxy or an audit log of the transport.
+//
+// The protocol is not verifiable either: a malicious server can return
+// any element, which the client cannot detect. Clients that need to
+// check the output should use EvalWithProof and VerifyEval instead.
+
+var (
+	ErrProtocolState     = errors.New("protocol message out of order")
+	ErrInvalidMessage    = errors.New("invalid protocol message")
+	ErrMessageTooLarge   = errors.New("protocol message exceeds MaxMessageSize")
+	ErrNegativeInput     = errors.New("blinded evaluation inputs must be non-negative")
+	ErrUnexpectedMessage = errors.New("unexpected protocol message type")
+)
+
+// MaxMessageSize is the largest protocol message accepted
+const MaxMessageSize = 1 << 24
+
+const (
+	messageVersion  = 1
+	messageRequest  = 1
+	messageResponse = 2
+)
+
+// EvalRequest is the client's message: the input and the blinded element
+type EvalRequest struct {
+	X       []*big.Int
+	Blinded *ec.Point
+}
+
+// EvalResponse is the server's message: the blinded element
+// raised to the Naor-Reingold exponent for the input
+type EvalResponse struct {
+	Evaluated *ec.Point
+}
+
+type clientState int
+
+const (
+	clientInit clientState = iota
+	clientAwaitingResponse
+	clientDone
+)
+
+// BlindedEvalClient is the client side of a blinded evaluation.
+// A client is used for a single evaluation: Request, then Finalize.
+type BlindedEvalClient struct {
+	x     []*big.Int
+	r     *big.Int
+	state clientState
+}
+
+// BlindedEvalServer answers blinded evaluation requests
+// with a master key or a constrained key
+type BlindedEvalServer struct {
+	pp     *PublicParameters
+	n      int
+	length int
+	zb     [][]*big.Int
+}
+
+// NewBlindedEvalClient returns a client for an evaluation on x.
+// The coordinates of x must be non-negative.
+func NewBlindedEvalClient(x []*big.Int) (*BlindedEvalClient, error) {
+	for _, v := range x {
+		if v.Sign() < 0 {
+			return nil, ErrNegativeInput
+		}
+	}
+
+	c := &BlindedEvalClient{}
+	c.x = append([]*big.Int(nil), x...)
+	c.state = clientInit
+	return c, nil
+}
+
+// Request returns the request message and waits for the response
+func (c *BlindedEvalClient) Request() (*EvalRequest, error) {
+	if c.state != clientInit {
+		return nil, ErrProtocolState
+	}
+
+	curve := elliptic.P256()
+
+	// r is uniform in [1, p)
+	for c.r == nil || c.r.Sign() == 0 {
+		_, r, err := ec.RandomCurveScalar(curve, rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate blinding factor: %w", err)
+		}
+		c.r = r
+	}
+
+	req := &EvalRequest{}
+	req.X = c.x
+	req.Blinded = ec.BaseScalarMult(curve, c.r)
+
+	c.state = clientAwaitingResponse
+	return req, nil
+}
+
+// Finalize unblinds the response and returns the output
+func (c *BlindedEvalClient) Finalize(resp *EvalResponse) (*ec.Point, error) {
+	if c.state != clientAwaitingResponse {
+		return nil, ErrProtocolState
+	}
+	if resp == nil || !validPoint(resp.Evaluated) {
+		return nil, ErrInvalidMessage
+	}
+
+	curve := elliptic.P256()
+	p := curve.Params().N
+
+	rInv := big.NewInt(0).ModInverse(c.r, p)
+	y := ec.PointScalarMult(curve, resp.Evaluated, rInv)
+
+	c.r = nil
+	c.state = clientDone
+	return y, nil
+}
+
+// Run runs the client over a connection and returns the output
+func (c *BlindedEvalClient) Run(conn io.ReadWriter) (*ec.Point, error) {
+	req, err := c.Request()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := req.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	if err := writeMessage(conn, data); err != nil {
+		return nil, err
+	}
+
+	data, err = readMessage(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &EvalResponse{}
+	if err := resp.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+
+	return c.Finalize(resp)
+}
+
+// NewBlindedEvalServer returns a server evaluating with the master key
+func NewBlindedEvalServer(pp *PublicParameters, msk *MasterKey) *BlindedEvalServer {
+	return newBlindedEvalServer(pp, msk.n, msk.length, msk.rows())
+}
+
+// NewConstrainedBlindedEvalServer returns a server evaluating with the constrained key
+func NewConstrainedBlindedEvalServer(pp *PublicParameters, csk *ConstrainedKey) *BlindedEvalServer {
+	return newBlindedEvalServer(pp, csk.n, csk.length, csk.z1)
+}
+
+func newBlindedEvalServer(pp *PublicParameters, n int, length int, zb [][]*big.Int) *BlindedEvalServer {
+	s := &BlindedEvalServer{}
+	s.pp = pp
+	s.n = n
+	s.length = length
+	s.zb = zb
+	return s
+}
+
+// Respond answers a request. The server holds no state between requests.
+func (s *BlindedEvalServer) Respond(req *EvalRequest) (*EvalResponse, error) {
+	if req == nil || !validPoint(req.Blinded) {
+		return nil, ErrInvalidMessage
+	}
+	if len(req.X) != s.length {
+		return nil, ErrLengthMismatch
+	}
+
+	curve := elliptic.P256()
+
+	keys, keyFPs := innerProductKeys(s.n, s.length, s.zb, req.X)
+	bits := hashDL(s.pp, req.X, keyFPs)[:s.n]
+	P := naorReingoldExponent(s.n, keys, bits)
+
+	resp := &EvalResponse{}
+	resp.Evaluated = ec.PointScalarMult(curve, req.Blinded, P)
+	return resp, nil
+}
+
+// Serve reads one request from the connection and writes the response
+func (s *BlindedEvalServer) Serve(conn io.ReadWriter) error {
+	data, err := readMessage(conn)
+	if err != nil {
+		return err
+	}
+
+	req := &EvalRequest{}
+	if err := req.UnmarshalBinary(data); err != nil {
+		return err
+	}
+
+	resp, err := s.Respond(req)
+	if err != nil {
+		return err
+	}
+
+	data, err = resp.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return writeMessage(conn, data)
+}
+
+// validPoint returns true if P is a point on its curve
+func validPoint(P *ec.Point) bool {
+	return P != nil && P.Curve != nil && P.X != nil && P.Y != nil && P.IsOnCurve()
+}
+
+// MarshalBinary encodes the request as
+// [version, type, uvarint len(x), (uvarint len, bytes) per coordinate, compressed point]
+func (req *EvalRequest) MarshalBinary() ([]byte, error) {
+	if !validPoint(req.Blinded) {
+		return nil, ErrInvalidMessage
+	}
+
+	data := []byte{messageVersion, messageRequest}
+	data = binary.AppendUvarint(data, uint64(len(req.X)))
+	for _, v := range req.X {
+		if v.Sign() < 0 {
+			return nil, ErrNegativeInput
+		}
+		data = binary.AppendUvarint(data, uint64(len(v.Bytes())))
+		data = append(data, v.Bytes()...)
+	}
+	return append(data, req.Blinded.MarshalCompressed()...), nil
+}
+
+// UnmarshalBinary decodes a request encoded by MarshalBinary
+func (req *EvalRequest) UnmarshalBinary(data []byte) error {
+	data, err := checkHeader(data, messageRequest)
+	if err != nil {
+		return err
+	}
+
+	length, read := binary.Uvarint(data)
+	if read <= 0 || length > uint64(len(data)) {
+		return ErrInvalidMessage
+	}
+	data = data[read:]
+
+	x := make([]*big.Int, length)
+	for j := range x {
+		l, read := binary.Uvarint(data)
+		if read <= 0 || l > uint64(len(data)-read) {
+			return ErrInvalidMessage
+		}
+		x[j] = big.NewInt(0).SetBytes(data[read : read+int(l)])
+		data = data[read+int(l):]
+	}
+
+	blinded, err := unmarshalPoint(data)
+	if err != nil {
+		return err
+	}
+
+	req.X = x
+	req.Blinded = blinded
+	return nil
+}
+
+// MarshalBinary encodes the response as [version, type, compressed point]
+func (resp *EvalResponse) MarshalBinary() ([]byte, error) {
+	if !validPoint(resp.Evaluated) {
+		return nil, ErrInvalidMessage
+	}
+
+	data := []byte{messageVersion, messageResponse}
+	return append(data, resp.Evaluated.MarshalCompressed()...), nil
+}
+
+// UnmarshalBinary decodes a response encoded by MarshalBinary
+func (resp *EvalResponse) UnmarshalBinary(data []byte) error {
+	data, err := checkHeader(data, messageResponse)
+	if err != nil {
+		return err
+	}
+
+	evaluated, err := unmarshalPoint(data)
+	if err != nil {
+		return err
+	}
+
+	resp.Evaluated = evaluated
+	return nil
+}
+
+// checkHeader checks the version and type of a message and returns its body
+func checkHeader(data []byte, kind byte) ([]byte, error) {
+	if len(data) < 2 || data[0] != messageVersion {
+		return nil, ErrInvalidMessage
+	}
+	if data[1] != kind {
+		return nil, ErrUnexpectedMessage
+	}
+	return data[2:], nil
+}
+
+// unmarshalPoint decodes a compressed point that must make up all of data
+func unmarshalPoint(data []byte) (*ec.Point, error) {
+	curve := elliptic.P256()
+	if len(data) != (curve.Params().BitSize+7)/8+1 {
+		return nil, ErrInvalidMessage
+	}
+
+	P := &ec.Point{}
+	if err := P.Unmarshal(curve, data); err != nil {
+		return nil, ErrInvalidMessage
+	}
+	return P, nil
+}
+
+// writeMessage writes a message prefixed by its 4 byte big-endian length
+func writeMessage(w io.Writer, data []byte) error {
+	if len(data) > MaxMessageSize {
+		return ErrMessageTooLarge
+	}
+
+	frame := make([]byte, 4+len(data))
+	binary.BigEndian.PutUint32(frame, uint32(len(data)))
+	copy(frame[4:], data)
+
+	_, err := w.Write(frame)
+	return err
+}
+
+// readMessage reads a message written by writeMessage
+func readMessage(r io.Reader) ([]byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+
+	size := binary.BigEndian.Uint32(header[:])
+	if size > MaxMessageSize {
+		return nil, ErrMessageTooLarge
+	}
+
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
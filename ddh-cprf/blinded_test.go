@@ -0,0 +1,198 @@
+package ddhcprf
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"math/big"
+	"net"
+	"testing"
+
+	"github.com/sachaservan/cprf/ddh-cprf/ec"
+)
+
+func TestBlindedEval(t *testing.T) {
+	p := elliptic.P256().Params().N
+	n := 128
+	length := 10
+
+	pp, msk, _ := KeyGen(n, length)
+	z, _ := generateRandomVector(length, p)
+	csk, _ := msk.Constrain(z)
+
+	x, _ := generateRandomVector(length, p)
+
+	for _, tc := range []struct {
+		server   *BlindedEvalServer
+		expected *ec.Point
+	}{
+		{NewBlindedEvalServer(pp, msk), msk.Eval(pp, x)},
+		{NewConstrainedBlindedEvalServer(pp, csk), csk.CEval(pp, x)},
+	} {
+		client, err := NewBlindedEvalClient(x)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req, err := client.Request()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the response does not contain the output
+		resp, err := tc.server.Respond(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ec.PointsEqual(resp.Evaluated, tc.expected) {
+			t.Fatalf("response contains the output in the clear")
+		}
+
+		y, err := client.Finalize(resp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ec.PointsEqual(y, tc.expected) {
+			t.Fatalf("blinded evaluation does not match Eval")
+		}
+	}
+}
+
+func TestBlindedEvalPipe(t *testing.T) {
+	p := elliptic.P256().Params().N
+	n := 128
+	length := 10
+
+	pp, msk, _ := KeyGen(n, length)
+	server := NewBlindedEvalServer(pp, msk)
+
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+	defer serverConn.Close()
+
+	errs := make(chan error, 1)
+	go func() {
+		for i := 0; i < 3; i++ {
+			if err := server.Serve(serverConn); err != nil {
+				errs <- err
+				return
+			}
+		}
+		errs <- nil
+	}()
+
+	for i := 0; i < 3; i++ {
+		x, _ := generateRandomVector(length, p)
+		x[0] = big.NewInt(0) // zero coordinates have an empty encoding
+
+		client, _ := NewBlindedEvalClient(x)
+		y, err := client.Run(clientConn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ec.PointsEqual(y, msk.Eval(pp, x)) {
+			t.Fatalf("blinded evaluation over the pipe does not match Eval")
+		}
+	}
+
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBlindedEvalClientState(t *testing.T) {
+	n := 128
+	length := 3
+
+	pp, msk, _ := KeyGen(n, length)
+	server := NewBlindedEvalServer(pp, msk)
+
+	x := []*big.Int{big.NewInt(1), big.NewInt(2), big.NewInt(3)}
+	client, _ := NewBlindedEvalClient(x)
+
+	if _, err := client.Finalize(&EvalResponse{}); err != ErrProtocolState {
+		t.Fatalf("expected ErrProtocolState before the request, got %v", err)
+	}
+
+	req, _ := client.Request()
+	if _, err := client.Request(); err != ErrProtocolState {
+		t.Fatalf("expected ErrProtocolState for a second request, got %v", err)
+	}
+	if _, err := client.Finalize(&EvalResponse{}); err != ErrInvalidMessage {
+		t.Fatalf("expected ErrInvalidMessage for an empty response, got %v", err)
+	}
+
+	resp, _ := server.Respond(req)
+	if _, err := client.Finalize(resp); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Finalize(resp); err != ErrProtocolState {
+		t.Fatalf("expected ErrProtocolState after finalizing, got %v", err)
+	}
+
+	if _, err := NewBlindedEvalClient([]*big.Int{big.NewInt(-1)}); err != ErrNegativeInput {
+		t.Fatalf("expected ErrNegativeInput, got %v", err)
+	}
+}
+
+func TestBlindedEvalMessages(t *testing.T) {
+	n := 128
+	length := 3
+
+	pp, msk, _ := KeyGen(n, length)
+	server := NewBlindedEvalServer(pp, msk)
+
+	x := []*big.Int{big.NewInt(0), big.NewInt(1000), big.NewInt(1)}
+	client, _ := NewBlindedEvalClient(x)
+	req, _ := client.Request()
+
+	data, err := req.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := &EvalRequest{}
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	for j := range x {
+		if decoded.X[j].Cmp(x[j]) != 0 {
+			t.Fatalf("decoded request has a different input")
+		}
+	}
+	if !ec.PointsEqual(decoded.Blinded, req.Blinded) {
+		t.Fatalf("decoded request has a different blinded element")
+	}
+
+	// malformed requests
+	for _, bad := range [][]byte{
+		nil,
+		data[:len(data)-1],
+		append(append([]byte{}, data...), 0),
+		append([]byte{messageVersion + 1}, data[1:]...),
+	} {
+		if err := decoded.UnmarshalBinary(bad); err != ErrInvalidMessage {
+			t.Fatalf("expected ErrInvalidMessage, got %v", err)
+		}
+	}
+
+	resp, _ := server.Respond(req)
+	respData, _ := resp.MarshalBinary()
+	if err := decoded.UnmarshalBinary(respData); err != ErrUnexpectedMessage {
+		t.Fatalf("expected ErrUnexpectedMessage for a response, got %v", err)
+	}
+
+	// the server rejects inputs of the wrong length and invalid points
+	if _, err := server.Respond(&EvalRequest{X: x[:2], Blinded: req.Blinded}); err != ErrLengthMismatch {
+		t.Fatalf("expected ErrLengthMismatch, got %v", err)
+	}
+	off := &ec.Point{Curve: elliptic.P256(), X: big.NewInt(1), Y: big.NewInt(1)}
+	if _, err := server.Respond(&EvalRequest{X: x, Blinded: off}); err != ErrInvalidMessage {
+		t.Fatalf("expected ErrInvalidMessage for a point off the curve, got %v", err)
+	}
+
+	// oversized frames are rejected before reading the body
+	var buf bytes.Buffer
+	buf.Write([]byte{0xff, 0xff, 0xff, 0xff})
+	if _, err := readMessage(&buf); err != ErrMessageTooLarge {
+		t.Fatalf("expected ErrMessageTooLarge, got %v", err)
+	}
+}
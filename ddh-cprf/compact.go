@@ -0,0 +1,291 @@
+package ddhcprf
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+)
+
+var (
+	ErrInvalidSeed        = errors.New("seed must be SeedSize bytes")
+	ErrInvalidKeyEncoding = errors.New("invalid master key encoding")
+)
+
+// SeedSize is the size in bytes of a master key seed
+const SeedSize = 32
+
+// MaxN is the largest number of Naor-Reingold key elements; the
+// elements are selected by the 256 bits of the DL hash
+const MaxN = 256
+
+// MaxLength is the largest inner product length accepted when decoding keys
+const MaxLength = 1 << 24
+
+const (
+	keyEncodingVersion  = 3
+	keyEncodingExpanded = 0
+	keyEncodingSeeded   = 1
+)
+
+// KeyGenCompact generates a new CPRF key whose master key is represented
+// by a SeedSize byte seed; the rows of z0 are expanded on demand by a PRG.
+// n: number of elements in the Naor-Reingold PRF key
+// length: length of the inner product
+// Outputs public parameters and a master key
+func KeyGenCompact(n int, length int) (*PublicParameters, *MasterKey, error) {
+
+	seed := make([]byte, SeedSize)
+	if _, err := io.ReadFull(rand.Reader, seed); err != nil {
+		return nil, nil, fmt.Errorf("failed to generate master key seed: %w", err)
+	}
+
+	msk, err := NewMasterKeyFromSeed(seed, n, length)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return newPublicParameters(n, length), msk, nil
+}
+
+// NewMasterKeyFromSeed returns the master key expanded from seed.
+// The same seed, n and length always give the same key.
+func NewMasterKeyFromSeed(seed []byte, n int, length int) (*MasterKey, error) {
+	if len(seed) != SeedSize {
+		return nil, ErrInvalidSeed
+	}
+
+	msk := &MasterKey{}
+	msk.n = n
+	msk.length = length
+	msk.seed = append([]byte(nil), seed...)
+
+	return msk, nil
+}
+
+// SetCache sets whether rows expanded from the seed are kept in memory.
+// Without caching, every operation expands the rows it needs again.
+// Disabling the cache drops any rows expanded so far.
+// It has no effect on keys that are not seed-compressed.
+func (msk *MasterKey) SetCache(enabled bool) {
+	if msk.seed == nil {
+		return
+	}
+
+	msk.mu.Lock()
+	defer msk.mu.Unlock()
+
+	msk.cache = enabled
+	if !enabled {
+		msk.z0 = nil
+	}
+}
+
+// IsCompact returns true if the master key is represented by a seed
+func (msk *MasterKey) IsCompact() bool {
+	return msk.seed != nil
+}
+
+// row returns row i of z0, expanding it from the seed if needed
+func (msk *MasterKey) row(i int) []*big.Int {
+	if msk.seed == nil {
+		return msk.z0[i]
+	}
+
+	msk.mu.Lock()
+	defer msk.mu.Unlock()
+
+	if msk.z0 != nil && msk.z0[i] != nil {
+		return msk.z0[i]
+	}
+
+	row := expandRow(msk.seed, i, msk.length)
+	if msk.cache {
+		if msk.z0 == nil {
+			msk.z0 = make([][]*big.Int, msk.n)
+		}
+		msk.z0[i] = row
+	}
+
+	return row
+}
+
+// rows returns all rows of z0, expanding them from the seed if needed
+func (msk *MasterKey) rows() [][]*big.Int {
+	if msk.seed == nil {
+		return msk.z0
+	}
+
+	rows := make([][]*big.Int, msk.n)
+	for i := 0; i < msk.n; i++ {
+		rows[i] = msk.row(i)
+	}
+
+	return rows
+}
+
+// expandRow expands row i of z0 from the seed using AES-256 in
+// counter mode with the row index as IV. Elements are sampled
+// uniformly mod the group order by rejection sampling.
+func expandRow(seed []byte, i int, length int) []*big.Int {
+
+	// p is the order of the eliptic curve
+	p := elliptic.P256().Params().N
+
+	block, err := aes.NewCipher(seed)
+	if err != nil {
+		// seed length is checked when the key is created
+		panic(err)
+	}
+
+	iv := make([]byte, aes.BlockSize)
+	binary.BigEndian.PutUint64(iv[:8], uint64(i))
+	stream := cipher.NewCTR(block, iv)
+
+	row := make([]*big.Int, length)
+	buf := make([]byte, (p.BitLen()+7)/8)
+	for j := 0; j < length; j++ {
+		for {
+			for k := range buf {
+				buf[k] = 0
+			}
+			stream.XORKeyStream(buf, buf)
+			row[j] = big.NewInt(0).SetBytes(buf)
+			if row[j].Cmp(p) < 0 {
+				break
+			}
+		}
+	}
+
+	return row
+}
+
+// MarshalBinary encodes the master key. Seed-compressed keys
+// encode to their seed, dimensions, schema ID and epoch only.
+func (msk *MasterKey) MarshalBinary() ([]byte, error) {
+
+	p := elliptic.P256().Params().N
+	elemLen := (p.BitLen() + 7) / 8
+
+	data := []byte{keyEncodingVersion, keyEncodingExpanded}
+	if msk.seed != nil {
+		data[1] = keyEncodingSeeded
+	}
+	data = binary.AppendUvarint(data, uint64(msk.n))
+	data = binary.AppendUvarint(data, uint64(msk.length))
+	data = binary.AppendUvarint(data, uint64(len(msk.schema)))
+	data = append(data, msk.schema...)
+	data = binary.AppendUvarint(data, msk.epoch)
+
+	if msk.seed != nil {
+		return append(data, msk.seed...), nil
+	}
+
+	elem := make([]byte, elemLen)
+	for i := 0; i < msk.n; i++ {
+		for j := 0; j < msk.length; j++ {
+			v := big.NewInt(0).Mod(msk.z0[i][j], p)
+			data = append(data, v.FillBytes(elem)...)
+		}
+	}
+
+	return data, nil
+}
+
+// UnmarshalBinary decodes a master key encoded by MarshalBinary.
+// Version 1 encodings, which have no schema ID, and version 2
+// encodings, which have no epoch, are also accepted.
+func (msk *MasterKey) UnmarshalBinary(data []byte) error {
+
+	p := elliptic.P256().Params().N
+	elemLen := (p.BitLen() + 7) / 8
+
+	if len(data) < 2 || data[0] < 1 || data[0] > keyEncodingVersion {
+		return ErrInvalidKeyEncoding
+	}
+	version := data[0]
+	kind := data[1]
+	data = data[2:]
+
+	n, read := binary.Uvarint(data)
+	if read <= 0 {
+		return ErrInvalidKeyEncoding
+	}
+	data = data[read:]
+
+	length, read := binary.Uvarint(data)
+	if read <= 0 {
+		return ErrInvalidKeyEncoding
+	}
+	data = data[read:]
+
+	// keys[0] and keys[1] are always used, and the bounds
+	// keep n*length*elemLen from overflowing
+	if n < 2 || n > MaxN || length < 1 || length > MaxLength {
+		return ErrInvalidKeyEncoding
+	}
+
+	schema := ""
+	if version >= 2 {
+		schemaLen, read := binary.Uvarint(data)
+		if read <= 0 || schemaLen > uint64(len(data)-read) {
+			return ErrInvalidKeyEncoding
+		}
+		schema = string(data[read : read+int(schemaLen)])
+		data = data[read+int(schemaLen):]
+	}
+
+	epoch := uint64(0)
+	if version >= 3 {
+		epoch, read = binary.Uvarint(data)
+		if read <= 0 {
+			return ErrInvalidKeyEncoding
+		}
+		data = data[read:]
+	}
+
+	msk.mu.Lock()
+	defer msk.mu.Unlock()
+
+	switch kind {
+	case keyEncodingSeeded:
+		if len(data) != SeedSize {
+			return ErrInvalidKeyEncoding
+		}
+		msk.seed = append([]byte(nil), data...)
+		msk.z0 = nil
+
+	case keyEncodingExpanded:
+		if uint64(len(data)) != n*length*uint64(elemLen) {
+			return ErrInvalidKeyEncoding
+		}
+		msk.seed = nil
+		msk.z0 = make([][]*big.Int, n)
+		for i := range msk.z0 {
+			msk.z0[i] = make([]*big.Int, length)
+			for j := range msk.z0[i] {
+				msk.z0[i][j] = big.NewInt(0).SetBytes(data[:elemLen])
+				data = data[elemLen:]
+				if msk.z0[i][j].Cmp(p) >= 0 {
+					return ErrInvalidKeyEncoding
+				}
+			}
+		}
+
+	default:
+		return ErrInvalidKeyEncoding
+	}
+
+	msk.n = int(n)
+	msk.length = int(length)
+	msk.schema = schema
+	msk.epoch = epoch
+	msk.cache = false
+
+	return nil
+}
@@ -0,0 +1,184 @@
+package ddhcprf
+
+import (
+	"crypto/elliptic"
+	"encoding/binary"
+	"math/big"
+	"testing"
+
+	"github.com/sachaservan/cprf/ddh-cprf/ec"
+)
+
+func TestCompactKeyMatchesExpanded(t *testing.T) {
+	p := elliptic.P256().Params().N
+	n := 128
+	length := 25
+
+	pp, msk, err := KeyGenCompact(n, length)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expanded := &MasterKey{}
+	expanded.n = n
+	expanded.length = length
+	expanded.z0 = msk.rows()
+
+	// compute x and z such that <z,x> = 0
+	z, _ := generateRandomVector(length, p)
+	x := make([]*big.Int, length)
+	for i := 0; i < length; i++ {
+		x[i] = big.NewInt(0)
+		if i%2 == 0 {
+			x[i], _ = generateRandomBigInt(p)
+			z[i] = big.NewInt(0)
+		}
+	}
+
+	eval := msk.Eval(pp, x)
+	if !ec.PointsEqual(eval, expanded.Eval(pp, x)) {
+		t.Fatalf("compact and expanded Eval are not equal")
+	}
+
+	csk, _ := msk.Constrain(z)
+	if !ec.PointsEqual(eval, csk.CEval(pp, x)) {
+		t.Fatalf("Eval and CEval are not equal for a compact key")
+	}
+
+	ecsk, _ := expanded.Constrain(z)
+	if !ec.PointsEqual(eval, ecsk.CEval(pp, x)) {
+		t.Fatalf("compact Eval and expanded CEval are not equal")
+	}
+}
+
+func TestCompactKeyDeterministic(t *testing.T) {
+	seed := make([]byte, SeedSize)
+	seed[0] = 1
+
+	msk1, _ := NewMasterKeyFromSeed(seed, 4, 10)
+	msk2, _ := NewMasterKeyFromSeed(seed, 4, 10)
+	seed[0] = 2
+	msk3, _ := NewMasterKeyFromSeed(seed, 4, 10)
+
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 10; j++ {
+			if msk1.row(i)[j].Cmp(msk2.row(i)[j]) != 0 {
+				t.Fatalf("same seed expanded to different keys")
+			}
+			if msk1.row(i)[j].Cmp(msk3.row(i)[j]) == 0 {
+				t.Fatalf("different seeds expanded to the same key")
+			}
+		}
+	}
+
+	if _, err := NewMasterKeyFromSeed(seed[:16], 4, 10); err != ErrInvalidSeed {
+		t.Fatalf("expected ErrInvalidSeed, got %v", err)
+	}
+}
+
+func TestCompactKeyCache(t *testing.T) {
+	_, msk, _ := KeyGenCompact(4, 10)
+
+	if msk.row(1)[0] == msk.row(1)[0] {
+		t.Fatalf("rows are cached without SetCache")
+	}
+
+	msk.SetCache(true)
+	if msk.row(1)[0] != msk.row(1)[0] {
+		t.Fatalf("rows are not cached with SetCache")
+	}
+
+	msk.SetCache(false)
+	if msk.z0 != nil {
+		t.Fatalf("cached rows are kept after disabling the cache")
+	}
+}
+
+func TestMasterKeyMarshalRoundTrip(t *testing.T) {
+	p := elliptic.P256().Params().N
+	n := 128
+	length := 25
+
+	x, _ := generateRandomVector(length, p)
+
+	pp, compact, _ := KeyGenCompact(n, length)
+	_, expanded, _ := KeyGen(n, length)
+
+	data, err := compact.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) > 48 {
+		t.Fatalf("compact key encodes to %d bytes", len(data))
+	}
+
+	for _, msk := range []*MasterKey{compact, expanded} {
+		data, err := msk.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		decoded := &MasterKey{}
+		if err := decoded.UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		}
+		if decoded.IsCompact() != msk.IsCompact() {
+			t.Fatalf("decoded key has a different representation")
+		}
+		if !ec.PointsEqual(decoded.Eval(pp, x), msk.Eval(pp, x)) {
+			t.Fatalf("decoded key evaluates differently")
+		}
+
+		if err := decoded.UnmarshalBinary(data[:len(data)-1]); err != ErrInvalidKeyEncoding {
+			t.Fatalf("expected ErrInvalidKeyEncoding, got %v", err)
+		}
+	}
+}
+
+func TestMasterKeyUnmarshalDimensions(t *testing.T) {
+	seed := make([]byte, SeedSize)
+
+	for _, dims := range []struct{ n, length uint64 }{
+		{0, 10},
+		{1, 10},
+		{MaxN + 1, 10},
+		{128, 0},
+		{128, MaxLength + 1},
+		{1 << 62, 1 << 62},
+	} {
+		for _, kind := range []byte{keyEncodingSeeded, keyEncodingExpanded} {
+			data := []byte{keyEncodingVersion, kind}
+			data = binary.AppendUvarint(data, dims.n)
+			data = binary.AppendUvarint(data, dims.length)
+			data = binary.AppendUvarint(data, 0) // schema
+			data = binary.AppendUvarint(data, 0) // epoch
+			data = append(data, seed...)
+
+			if err := (&MasterKey{}).UnmarshalBinary(data); err != ErrInvalidKeyEncoding {
+				t.Fatalf("expected ErrInvalidKeyEncoding for n = %d, length = %d, got %v", dims.n, dims.length, err)
+			}
+		}
+	}
+}
+
+func BenchmarkEvalCompact(b *testing.B) {
+	p := elliptic.P256().Params().N
+	n := 128
+	length := 100
+
+	pp, msk, _ := KeyGenCompact(n, length)
+	x, _ := generateRandomVector(length, p)
+
+	b.Run("cache=false", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			msk.Eval(pp, x)
+		}
+	})
+
+	msk.SetCache(true)
+	b.Run("cache=true", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			msk.Eval(pp, x)
+		}
+	})
+}
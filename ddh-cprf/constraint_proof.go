@@ -0,0 +1,483 @@
+package ddhcprf
+
+import (
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/sachaservan/cprf/ddh-cprf/ec"
+)
+
+// A constraint proof lets the holder of a constrained key check that it
+// was honestly derived from a committed master key, without learning z0.
+//
+// The issuer publishes Pedersen vector commitments C0_i = Com(z0_i; s0_i)
+// to the rows of the master key, where Com(v; s) = SUM_j v_j G_j + s H for
+// generators G_1, ..., G_length, H with unknown discrete logarithms. For a
+// constrained key z1_i = z0_i - Delta_i z it commits Cz = Com(z; t), and since
+//
+//	C0_i - Com(z1_i; 0) = Delta_i Cz + (s0_i - Delta_i t) H
+//
+// it proves for each row the knowledge of a representation of the left-hand
+// side in the bases Cz and H. The proof is zero-knowledge, so it reveals
+// neither Delta_i nor z0. It does not reveal z either: Cz is hiding, and
+// the issuer hands the opening t to whoever may learn z (see CheckOpening).
+//
+// The proof does not show that Delta_i is nonzero, and without the opening
+// it does not show that Cz commits to a nonzero z. An issuer may use
+// Delta_i = 0, which hands out the master key row z0_i, or z = 0, which
+// hands out a key that is not constrained at all. CheckOpening rejects
+// z = 0; nothing in this file detects Delta_i = 0.
+
+var (
+	ErrInvalidConstraintProof = errors.New("constraint proof is invalid")
+)
+
+// PedersenParams are the generators G_1, ..., G_length and H of
+// the Pedersen vector commitments, derived by hashing to the curve
+type PedersenParams struct {
+	G []*ec.Point
+	H *ec.Point
+}
+
+// MasterKeyCommitment is a Pedersen commitment C0_i to each row of z0
+type MasterKeyCommitment struct {
+	rows []*ec.Point
+}
+
+// MasterKeyOpening holds the blinding factors s0_i of a
+// MasterKeyCommitment; it must be kept secret by the issuer
+type MasterKeyOpening struct {
+	blinding []*big.Int
+}
+
+// ConstraintProof proves that a constrained key is z0 - Delta_i z
+// row by row for the committed master key and constraint
+// Cz: commitment to the constraint z
+// rows: representation proofs of C0_i - Com(z1_i; 0) in the bases Cz and H
+type ConstraintProof struct {
+	Cz   *ec.Point
+	rows []*representationProof
+}
+
+// representationProof is a non-interactive proof of knowledge of a, b
+// such that D = a P + b H, with challenge c and responses sa, sb
+type representationProof struct {
+	c  *big.Int
+	sa *big.Int
+	sb *big.Int
+}
+
+// NewPedersenParams returns the commitment generators for vectors of the
+// given length. Generators are derived deterministically, so every party
+// computes the same ones and nobody knows their discrete logarithms.
+func NewPedersenParams(length int) (*PedersenParams, error) {
+	params := &PedersenParams{}
+	params.G = make([]*ec.Point, length)
+	for j := 0; j < length; j++ {
+		G, err := pedersenGenerator("G", j)
+		if err != nil {
+			return nil, err
+		}
+		params.G[j] = G
+	}
+
+	H, err := pedersenGenerator("H", 0)
+	if err != nil {
+		return nil, err
+	}
+	params.H = H
+
+	return params, nil
+}
+
+// pedersenGenerator hashes the generator label and index to the curve,
+// retrying with a counter in the unlikely case hashing fails
+func pedersenGenerator(label string, index int) (*ec.Point, error) {
+	h2c, err := ec.GetDefaultCurveHash()
+	if err != nil {
+		return nil, err
+	}
+
+	for counter := uint32(0); ; counter++ {
+		hasher := sha256.New()
+		hasher.Write([]byte("ddh-cprf pedersen generator " + label))
+		binary.Write(hasher, binary.BigEndian, uint64(index))
+		binary.Write(hasher, binary.BigEndian, counter)
+
+		P, err := h2c.HashToCurve(hasher.Sum(nil))
+		if err == nil {
+			return P, nil
+		}
+		if err != ec.ErrNoPointFound {
+			return nil, err
+		}
+	}
+}
+
+// commit returns Com(v; s) = SUM_j v_j G_j + s H
+func (params *PedersenParams) commit(v []*big.Int, s *big.Int) *ec.Point {
+
+	curve := elliptic.P256()
+	p := curve.Params().N
+
+	C := ec.PointScalarMult(curve, params.H, big.NewInt(0).Mod(s, p))
+	for j := range v {
+		term := ec.PointScalarMult(curve, params.G[j], big.NewInt(0).Mod(v[j], p))
+		C = ec.PointAdd(curve, C, term)
+	}
+	return C
+}
+
+// CommitPedersen returns a commitment to the master key, which may be
+// published, and its opening, which the issuer must keep secret
+func (msk *MasterKey) CommitPedersen(params *PedersenParams) (*MasterKeyCommitment, *MasterKeyOpening, error) {
+	if params == nil {
+		return nil, nil, ErrInvalidCommitment
+	}
+	if len(params.G) != msk.length {
+		return nil, nil, ErrLengthMismatch
+	}
+
+	p := elliptic.P256().Params().N
+
+	com := &MasterKeyCommitment{}
+	com.rows = make([]*ec.Point, msk.n)
+	opening := &MasterKeyOpening{}
+	opening.blinding = make([]*big.Int, msk.n)
+
+	for i := 0; i < msk.n; i++ {
+		s, err := generateRandomBigInt(p)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to generate blinding factor %d: %w", i, err)
+		}
+		opening.blinding[i] = s
+		com.rows[i] = params.commit(msk.row(i), s)
+	}
+
+	return com, opening, nil
+}
+
+// ConstrainWithProof outputs a constrained key for z together with a proof
+// that it was derived from the master key committed to with the given
+// opening, and the blinding factor t of the constraint commitment Cz.
+func (msk *MasterKey) ConstrainWithProof(
+	params *PedersenParams,
+	com *MasterKeyCommitment,
+	opening *MasterKeyOpening,
+	z []*big.Int) (*ConstrainedKey, *ConstraintProof, *big.Int, error) {
+
+	if params == nil || com == nil || opening == nil {
+		return nil, nil, nil, ErrInvalidCommitment
+	}
+	if len(z) != msk.length || len(params.G) != msk.length {
+		return nil, nil, nil, ErrLengthMismatch
+	}
+	if len(com.rows) != msk.n || len(opening.blinding) != msk.n {
+		return nil, nil, nil, ErrLengthMismatch
+	}
+
+	curve := elliptic.P256()
+	p := curve.Params().N
+
+	csk, deltas, err := msk.constrain(z)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	t, err := generateRandomBigInt(p)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to generate constraint blinding factor: %w", err)
+	}
+
+	proof := &ConstraintProof{}
+	proof.Cz = params.commit(z, t)
+	proof.rows = make([]*representationProof, msk.n)
+
+	tr := constraintTranscript(params, com, proof.Cz)
+	for i := 0; i < msk.n; i++ {
+		D := constraintStatement(params, com.rows[i], csk.z1[i])
+
+		// u_i = s0_i - Delta_i t
+		u := big.NewInt(0).Mul(deltas[i], t)
+		u.Sub(opening.blinding[i], u).Mod(u, p)
+
+		proof.rows[i], err = proveRepresentation(tr, proof.Cz, params.H, D, deltas[i], u)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	return csk, proof, t, nil
+}
+
+// VerifyConstraint checks that every row of the constrained key is
+// z0_i - Delta_i z for the master key committed to by com and the
+// constraint committed to by proof.Cz. It does not check that Delta_i
+// or z is nonzero; see the note at the top of this file.
+func VerifyConstraint(params *PedersenParams, com *MasterKeyCommitment, csk *ConstrainedKey, proof *ConstraintProof) error {
+	if params == nil || com == nil || csk == nil || proof == nil || !validPoint(proof.Cz) {
+		return ErrInvalidConstraintProof
+	}
+	if len(params.G) != csk.length {
+		return ErrLengthMismatch
+	}
+	if len(com.rows) != csk.n || len(proof.rows) != csk.n || len(csk.z1) != csk.n {
+		return ErrInvalidConstraintProof
+	}
+	for _, C0 := range com.rows {
+		if !validPoint(C0) {
+			return ErrInvalidConstraintProof
+		}
+	}
+
+	tr := constraintTranscript(params, com, proof.Cz)
+	for i := 0; i < csk.n; i++ {
+		if len(csk.z1[i]) != csk.length {
+			return ErrInvalidConstraintProof
+		}
+
+		D := constraintStatement(params, com.rows[i], csk.z1[i])
+		if !verifyRepresentation(tr, proof.Cz, params.H, D, proof.rows[i]) {
+			return ErrInvalidConstraintProof
+		}
+	}
+
+	return nil
+}
+
+// CheckOpening checks that the constraint commitment of the proof opens
+// to z with blinding factor t, and that z is nonzero
+func (proof *ConstraintProof) CheckOpening(params *PedersenParams, z []*big.Int, t *big.Int) error {
+	if params == nil || t == nil {
+		return ErrInvalidConstraintProof
+	}
+	if len(z) != len(params.G) {
+		return ErrLengthMismatch
+	}
+	if isZeroVector(z) {
+		return ErrInvalidConstraintProof
+	}
+	if !validPoint(proof.Cz) || !ec.PointsEqual(proof.Cz, params.commit(z, t)) {
+		return ErrInvalidConstraintProof
+	}
+	return nil
+}
+
+// isZeroVector reports whether every coordinate of z is 0 mod p
+func isZeroVector(z []*big.Int) bool {
+	p := elliptic.P256().Params().N
+	for j := range z {
+		if z[j] == nil || big.NewInt(0).Mod(z[j], p).Sign() != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// constraintStatement returns D_i = C0_i - Com(z1_i; 0)
+func constraintStatement(params *PedersenParams, C0 *ec.Point, z1 []*big.Int) *ec.Point {
+	p := elliptic.P256().Params().N
+
+	neg := make([]*big.Int, len(z1))
+	for j := range z1 {
+		neg[j] = big.NewInt(0).Neg(z1[j])
+		neg[j].Mod(neg[j], p)
+	}
+
+	return ec.PointAdd(elliptic.P256(), C0, params.commit(neg, big.NewInt(0)))
+}
+
+// constraintTranscript returns the transcript binding the
+// proof to the generators and both commitments
+func constraintTranscript(params *PedersenParams, com *MasterKeyCommitment, Cz *ec.Point) *ec.Transcript {
+	tr := ec.NewTranscript("ddh-cprf constraint proof v1")
+	for _, G := range params.G {
+		tr.AppendPoint("G", G)
+	}
+	tr.AppendPoint("H", params.H)
+	for _, C0 := range com.rows {
+		tr.AppendPoint("C0", C0)
+	}
+	tr.AppendPoint("Cz", Cz)
+	return tr
+}
+
+// proveRepresentation proves knowledge of a, b such that D = a P + b H
+func proveRepresentation(tr *ec.Transcript, P, H, D *ec.Point, a, b *big.Int) (*representationProof, error) {
+
+	curve := elliptic.P256()
+	p := curve.Params().N
+
+	ra, err := generateRandomBigInt(p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate proof nonce: %w", err)
+	}
+	rb, err := generateRandomBigInt(p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate proof nonce: %w", err)
+	}
+
+	T := ec.PointAdd(curve, ec.PointScalarMult(curve, P, ra), ec.PointScalarMult(curve, H, rb))
+
+	tr.AppendPoint("D", D)
+	tr.AppendPoint("T", T)
+
+	proof := &representationProof{}
+	proof.c = tr.ChallengeScalar("c", curve)
+	proof.sa = big.NewInt(0).Mul(proof.c, a)
+	proof.sa.Add(proof.sa, ra).Mod(proof.sa, p)
+	proof.sb = big.NewInt(0).Mul(proof.c, b)
+	proof.sb.Add(proof.sb, rb).Mod(proof.sb, p)
+
+	return proof, nil
+}
+
+// verifyRepresentation checks a proof by recomputing T = sa P + sb H - c D
+func verifyRepresentation(tr *ec.Transcript, P, H, D *ec.Point, proof *representationProof) bool {
+	if proof == nil || proof.c == nil || proof.sa == nil || proof.sb == nil {
+		return false
+	}
+
+	curve := elliptic.P256()
+	p := curve.Params().N
+
+	negC := big.NewInt(0).Neg(proof.c)
+	negC.Mod(negC, p)
+
+	T := ec.PointAdd(curve, ec.PointScalarMult(curve, P, big.NewInt(0).Mod(proof.sa, p)), ec.PointScalarMult(curve, H, big.NewInt(0).Mod(proof.sb, p)))
+	T = ec.PointAdd(curve, T, ec.PointScalarMult(curve, D, negC))
+
+	tr.AppendPoint("D", D)
+	tr.AppendPoint("T", T)
+
+	return tr.ChallengeScalar("c", curve).Cmp(proof.c) == 0
+}
+
+// MarshalBinary encodes the commitment as the number of rows
+// followed by the compressed row commitments
+func (com *MasterKeyCommitment) MarshalBinary() ([]byte, error) {
+	data := binary.AppendUvarint(nil, uint64(len(com.rows)))
+	for _, C0 := range com.rows {
+		if !validPoint(C0) {
+			return nil, ErrInvalidCommitment
+		}
+		data = append(data, C0.MarshalCompressed()...)
+	}
+	return data, nil
+}
+
+// UnmarshalBinary decodes a commitment encoded by MarshalBinary
+func (com *MasterKeyCommitment) UnmarshalBinary(data []byte) error {
+
+	curve := elliptic.P256()
+	pointLen := (curve.Params().BitSize+7)/8 + 1
+
+	n, read := binary.Uvarint(data)
+	if read <= 0 {
+		return ErrInvalidCommitment
+	}
+	data = data[read:]
+
+	if n < 2 || n > MaxN || uint64(len(data)) != n*uint64(pointLen) {
+		return ErrInvalidCommitment
+	}
+
+	rows := make([]*ec.Point, n)
+	for i := range rows {
+		rows[i] = &ec.Point{}
+		if err := rows[i].Unmarshal(curve, data[:pointLen]); err != nil {
+			return ErrInvalidCommitment
+		}
+		data = data[pointLen:]
+	}
+
+	com.rows = rows
+
+	return nil
+}
+
+// MarshalBinary encodes the proof as the compressed constraint commitment,
+// the number of rows and the fixed-length challenge and responses of each row
+func (proof *ConstraintProof) MarshalBinary() ([]byte, error) {
+	if !validPoint(proof.Cz) {
+		return nil, ErrInvalidProofEncoding
+	}
+
+	p := elliptic.P256().Params().N
+
+	data := proof.Cz.MarshalCompressed()
+	data = binary.AppendUvarint(data, uint64(len(proof.rows)))
+	for _, row := range proof.rows {
+		if row == nil {
+			return nil, ErrInvalidProofEncoding
+		}
+		for _, v := range []*big.Int{row.c, row.sa, row.sb} {
+			if v == nil || v.Sign() < 0 || v.Cmp(p) >= 0 {
+				return nil, ErrInvalidProofEncoding
+			}
+			data = append(data, v.FillBytes(make([]byte, ec.ScalarSize))...)
+		}
+	}
+	return data, nil
+}
+
+// UnmarshalBinary decodes a proof encoded by MarshalBinary
+func (proof *ConstraintProof) UnmarshalBinary(data []byte) error {
+
+	curve := elliptic.P256()
+	p := curve.Params().N
+	pointLen := (curve.Params().BitSize+7)/8 + 1
+
+	if len(data) < pointLen {
+		return ErrInvalidProofEncoding
+	}
+	Cz := &ec.Point{}
+	if err := Cz.Unmarshal(curve, data[:pointLen]); err != nil {
+		return ErrInvalidProofEncoding
+	}
+	data = data[pointLen:]
+
+	n, read := binary.Uvarint(data)
+	if read <= 0 {
+		return ErrInvalidProofEncoding
+	}
+	data = data[read:]
+
+	if n < 2 || n > MaxN || uint64(len(data)) != n*3*ec.ScalarSize {
+		return ErrInvalidProofEncoding
+	}
+
+	scalar := func() (*big.Int, error) {
+		v := big.NewInt(0).SetBytes(data[:ec.ScalarSize])
+		data = data[ec.ScalarSize:]
+		if v.Cmp(p) >= 0 {
+			return nil, ErrInvalidProofEncoding
+		}
+		return v, nil
+	}
+
+	rows := make([]*representationProof, n)
+	for i := range rows {
+		row := &representationProof{}
+		var err error
+		if row.c, err = scalar(); err != nil {
+			return err
+		}
+		if row.sa, err = scalar(); err != nil {
+			return err
+		}
+		if row.sb, err = scalar(); err != nil {
+			return err
+		}
+		rows[i] = row
+	}
+
+	proof.Cz = Cz
+	proof.rows = rows
+
+	return nil
+}
@@ -0,0 +1,316 @@
+package ddhcprf
+
+import (
+	"crypto/elliptic"
+	"encoding/binary"
+	"math/big"
+	"testing"
+
+	"github.com/sachaservan/cprf/ddh-cprf/ec"
+)
+
+func TestConstrainWithProof(t *testing.T) {
+	p := elliptic.P256().Params().N
+	n := 16
+	length := 5
+
+	pp, msk, _ := KeyGen(n, length)
+	params, err := NewPedersenParams(length)
+	if err != nil {
+		t.Fatal(err)
+	}
+	com, opening, err := msk.CommitPedersen(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	z, _ := generateRandomVector(length, p)
+	csk, proof, blinding, err := msk.ConstrainWithProof(params, com, opening, z)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := VerifyConstraint(params, com, csk, proof); err != nil {
+		t.Fatalf("valid proof rejected: %v", err)
+	}
+	if err := proof.CheckOpening(params, z, blinding); err != nil {
+		t.Fatalf("valid opening rejected: %v", err)
+	}
+
+	// the proven key still behaves as a constrained key
+	x := make([]*big.Int, length)
+	for i := range x {
+		x[i] = big.NewInt(0)
+	}
+	x[0] = big.NewInt(0).Set(z[1])
+	x[1] = big.NewInt(0).Neg(z[0])
+	x[1].Mod(x[1], p)
+	if !ec.PointsEqual(msk.Eval(pp, x), csk.CEval(pp, x)) {
+		t.Fatalf("Eval and CEval are not equal on an authorized input")
+	}
+}
+
+func TestVerifyConstraintMalformed(t *testing.T) {
+	p := elliptic.P256().Params().N
+	n := 16
+	length := 5
+
+	_, msk, _ := KeyGen(n, length)
+	_, other, _ := KeyGen(n, length)
+	params, _ := NewPedersenParams(length)
+	com, opening, _ := msk.CommitPedersen(params)
+	otherCom, _, _ := other.CommitPedersen(params)
+
+	z, _ := generateRandomVector(length, p)
+	csk, proof, blinding, _ := msk.ConstrainWithProof(params, com, opening, z)
+
+	// constrained key of another master key
+	otherCsk, _ := other.Constrain(z)
+	if err := VerifyConstraint(params, com, otherCsk, proof); err != ErrInvalidConstraintProof {
+		t.Fatalf("expected ErrInvalidConstraintProof for another key, got %v", err)
+	}
+
+	// tampered row
+	tampered := copyConstrainedKey(csk)
+	tampered.z1[3][2].Add(tampered.z1[3][2], big.NewInt(1))
+	if err := VerifyConstraint(params, com, tampered, proof); err != ErrInvalidConstraintProof {
+		t.Fatalf("expected ErrInvalidConstraintProof for a tampered row, got %v", err)
+	}
+
+	// swapped rows
+	swapped := copyConstrainedKey(csk)
+	swapped.z1[0], swapped.z1[1] = swapped.z1[1], swapped.z1[0]
+	if err := VerifyConstraint(params, com, swapped, proof); err != ErrInvalidConstraintProof {
+		t.Fatalf("expected ErrInvalidConstraintProof for swapped rows, got %v", err)
+	}
+
+	// honest key for a different constraint with the original proof
+	z2, _ := generateRandomVector(length, p)
+	csk2, _ := msk.Constrain(z2)
+	if err := VerifyConstraint(params, com, csk2, proof); err != ErrInvalidConstraintProof {
+		t.Fatalf("expected ErrInvalidConstraintProof for a different constraint, got %v", err)
+	}
+
+	// missing row
+	short := copyConstrainedKey(csk)
+	short.n--
+	short.z1 = short.z1[:short.n]
+	if err := VerifyConstraint(params, com, short, proof); err != ErrInvalidConstraintProof {
+		t.Fatalf("expected ErrInvalidConstraintProof for a missing row, got %v", err)
+	}
+
+	// commitment to another master key
+	if err := VerifyConstraint(params, otherCom, csk, proof); err != ErrInvalidConstraintProof {
+		t.Fatalf("expected ErrInvalidConstraintProof for another commitment, got %v", err)
+	}
+
+	// generators of another length
+	params2, _ := NewPedersenParams(length + 1)
+	if err := VerifyConstraint(params2, com, csk, proof); err != ErrLengthMismatch {
+		t.Fatalf("expected ErrLengthMismatch for other generators, got %v", err)
+	}
+
+	// wrong opening of the constraint commitment
+	if err := proof.CheckOpening(params, z2, blinding); err != ErrInvalidConstraintProof {
+		t.Fatalf("expected ErrInvalidConstraintProof for another constraint, got %v", err)
+	}
+	if err := proof.CheckOpening(params, z, big.NewInt(0).Add(blinding, big.NewInt(1))); err != ErrInvalidConstraintProof {
+		t.Fatalf("expected ErrInvalidConstraintProof for another blinding factor, got %v", err)
+	}
+}
+
+func TestConstrainWithProofCompact(t *testing.T) {
+	p := elliptic.P256().Params().N
+	n := 8
+	length := 4
+
+	_, msk, _ := KeyGenCompact(n, length)
+	params, _ := NewPedersenParams(length)
+	com, opening, _ := msk.CommitPedersen(params)
+
+	z, _ := generateRandomVector(length, p)
+	csk, proof, _, err := msk.ConstrainWithProof(params, com, opening, z)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyConstraint(params, com, csk, proof); err != nil {
+		t.Fatalf("valid proof rejected for a compact key: %v", err)
+	}
+}
+
+func TestVerifyConstraintNil(t *testing.T) {
+	p := elliptic.P256().Params().N
+	n := 8
+	length := 3
+
+	_, msk, _ := KeyGen(n, length)
+	params, _ := NewPedersenParams(length)
+	com, opening, _ := msk.CommitPedersen(params)
+	z, _ := generateRandomVector(length, p)
+	csk, proof, _, _ := msk.ConstrainWithProof(params, com, opening, z)
+
+	if err := VerifyConstraint(nil, com, csk, proof); err != ErrInvalidConstraintProof {
+		t.Fatalf("expected ErrInvalidConstraintProof for nil params, got %v", err)
+	}
+	if err := VerifyConstraint(params, nil, csk, proof); err != ErrInvalidConstraintProof {
+		t.Fatalf("expected ErrInvalidConstraintProof for a nil commitment, got %v", err)
+	}
+	if err := VerifyConstraint(params, com, nil, proof); err != ErrInvalidConstraintProof {
+		t.Fatalf("expected ErrInvalidConstraintProof for a nil key, got %v", err)
+	}
+	if err := VerifyConstraint(params, &MasterKeyCommitment{rows: make([]*ec.Point, n)}, csk, proof); err != ErrInvalidConstraintProof {
+		t.Fatalf("expected ErrInvalidConstraintProof for missing row commitments, got %v", err)
+	}
+}
+
+func TestConstrainWithProofArguments(t *testing.T) {
+	p := elliptic.P256().Params().N
+	n := 8
+	length := 3
+
+	_, msk, _ := KeyGen(n, length)
+	_, other, _ := KeyGen(n/2, length)
+	params, _ := NewPedersenParams(length)
+	com, opening, _ := msk.CommitPedersen(params)
+	otherCom, otherOpening, _ := other.CommitPedersen(params)
+	z, _ := generateRandomVector(length, p)
+
+	if _, _, _, err := msk.ConstrainWithProof(nil, com, opening, z); err != ErrInvalidCommitment {
+		t.Fatalf("expected ErrInvalidCommitment for nil params, got %v", err)
+	}
+	if _, _, _, err := msk.ConstrainWithProof(params, nil, opening, z); err != ErrInvalidCommitment {
+		t.Fatalf("expected ErrInvalidCommitment for a nil commitment, got %v", err)
+	}
+	if _, _, _, err := msk.ConstrainWithProof(params, com, nil, z); err != ErrInvalidCommitment {
+		t.Fatalf("expected ErrInvalidCommitment for a nil opening, got %v", err)
+	}
+
+	// a commitment or opening of a key with fewer rows
+	if _, _, _, err := msk.ConstrainWithProof(params, otherCom, opening, z); err != ErrLengthMismatch {
+		t.Fatalf("expected ErrLengthMismatch for another commitment, got %v", err)
+	}
+	if _, _, _, err := msk.ConstrainWithProof(params, com, otherOpening, z); err != ErrLengthMismatch {
+		t.Fatalf("expected ErrLengthMismatch for another opening, got %v", err)
+	}
+}
+
+func TestCheckOpeningZeroConstraint(t *testing.T) {
+	length := 3
+
+	params, _ := NewPedersenParams(length)
+	zero := []*big.Int{big.NewInt(0), big.NewInt(0), big.NewInt(0)}
+	blinding := big.NewInt(12345)
+
+	proof := &ConstraintProof{}
+	proof.Cz = params.commit(zero, blinding)
+	if err := proof.CheckOpening(params, zero, blinding); err != ErrInvalidConstraintProof {
+		t.Fatalf("expected ErrInvalidConstraintProof for z = 0, got %v", err)
+	}
+}
+
+func TestConstraintProofMarshal(t *testing.T) {
+	p := elliptic.P256().Params().N
+	n := 8
+	length := 3
+
+	_, msk, _ := KeyGen(n, length)
+	params, _ := NewPedersenParams(length)
+	com, opening, _ := msk.CommitPedersen(params)
+	z, _ := generateRandomVector(length, p)
+	csk, proof, _, _ := msk.ConstrainWithProof(params, com, opening, z)
+
+	comData, err := com.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	proofData, err := proof.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decodedCom := &MasterKeyCommitment{}
+	if err := decodedCom.UnmarshalBinary(comData); err != nil {
+		t.Fatal(err)
+	}
+	decodedProof := &ConstraintProof{}
+	if err := decodedProof.UnmarshalBinary(proofData); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyConstraint(params, decodedCom, csk, decodedProof); err != nil {
+		t.Fatalf("decoded proof rejected: %v", err)
+	}
+}
+
+func TestConstraintProofUnmarshalMalformed(t *testing.T) {
+	p := elliptic.P256().Params().N
+	n := 8
+	length := 3
+	pointLen := 33
+
+	_, msk, _ := KeyGen(n, length)
+	params, _ := NewPedersenParams(length)
+	com, opening, _ := msk.CommitPedersen(params)
+	z, _ := generateRandomVector(length, p)
+	_, proof, _, _ := msk.ConstrainWithProof(params, com, opening, z)
+
+	comData, _ := com.MarshalBinary()
+	proofData, _ := proof.MarshalBinary()
+
+	// the x-coordinate of a compressed point must be on the curve
+	badPoint := append([]byte{}, comData...)
+	badPoint[1] = 0x05
+	for i := 2; i < 1+pointLen; i++ {
+		badPoint[i] = 0xff
+	}
+
+	// a row count whose byte length overflows uint64
+	hugeCount := binary.AppendUvarint(nil, 1<<62)
+	hugeCount = append(hugeCount, comData[1:]...)
+
+	for name, data := range map[string][]byte{
+		"empty":      nil,
+		"truncated":  comData[:len(comData)-1],
+		"trailing":   append(append([]byte{}, comData...), 0),
+		"one row":    append(binary.AppendUvarint(nil, 1), comData[1:1+pointLen]...),
+		"invalid":    badPoint,
+		"huge count": hugeCount,
+	} {
+		if err := (&MasterKeyCommitment{}).UnmarshalBinary(data); err != ErrInvalidCommitment {
+			t.Fatalf("%s commitment: expected ErrInvalidCommitment, got %v", name, err)
+		}
+	}
+
+	// a response equal to the group order is not reduced
+	unreduced := append([]byte{}, proofData...)
+	p.FillBytes(unreduced[len(unreduced)-ec.ScalarSize:])
+
+	hugeRows := append([]byte{}, proofData[:pointLen]...)
+	hugeRows = binary.AppendUvarint(hugeRows, 1<<62)
+	hugeRows = append(hugeRows, proofData[pointLen+1:]...)
+
+	for name, data := range map[string][]byte{
+		"empty":      nil,
+		"truncated":  proofData[:len(proofData)-1],
+		"trailing":   append(append([]byte{}, proofData...), 0),
+		"unreduced":  unreduced,
+		"no point":   proofData[:pointLen-1],
+		"huge count": hugeRows,
+	} {
+		if err := (&ConstraintProof{}).UnmarshalBinary(data); err != ErrInvalidProofEncoding {
+			t.Fatalf("%s proof: expected ErrInvalidProofEncoding, got %v", name, err)
+		}
+	}
+}
+
+func copyConstrainedKey(csk *ConstrainedKey) *ConstrainedKey {
+	res := &ConstrainedKey{}
+	*res = *csk
+	res.z1 = make([][]*big.Int, len(csk.z1))
+	for i := range csk.z1 {
+		res.z1[i] = make([]*big.Int, len(csk.z1[i]))
+		for j := range csk.z1[i] {
+			res.z1[i][j] = big.NewInt(0).Set(csk.z1[i][j])
+		}
+	}
+	return res
+}
@@ -5,7 +5,9 @@ import (
 	"crypto/rand"
 	"crypto/sha256"
 	"fmt"
+	"hash"
 	"math/big"
+	"sync"
 
 	"github.com/sachaservan/cprf/ddh-cprf/ec"
 )
@@ -20,21 +22,34 @@ type PublicParameters struct {
 // Master key for the CPRF
 // length: length of the inner product
 // n: number of elements in the Naor-Reingold PRF key
-// z0: master key
+// z0: master key (rows may be nil for seed-compressed keys)
+// seed: PRG seed the rows of z0 are expanded from, if any
+// cache: whether expanded rows are kept in z0
+// schema: ID of the schema inputs are encoded with, if any
+// epoch: number of times the key has been rotated
 type MasterKey struct {
 	length int
 	n      int
 	z0     [][]*big.Int
+	seed   []byte
+	cache  bool
+	schema string
+	epoch  uint64
+	mu     sync.Mutex
 }
 
 // Constrained key for the CPRF
 // length: length of the inner product
 // n: number of elements in the Naor-Reingold PRF key
 // z1: constrained key
+// schema: ID of the schema inputs are encoded with, if any
+// epoch: epoch of the master key the key was constrained from
 type ConstrainedKey struct {
 	length int
 	n      int
 	z1     [][]*big.Int
+	schema string
+	epoch  uint64
 }
 
 // KeyGen generates a new CPRF key
@@ -63,6 +78,13 @@ func KeyGen(n int, length int) (*PublicParameters, *MasterKey, error) {
 		}
 	}
 
+	return newPublicParameters(n, length), msk, nil
+}
+
+// newPublicParameters samples the hash elements for keys
+// with the given number of elements n and length
+func newPublicParameters(n int, length int) *PublicParameters {
+
 	// hash elements for the public parameters
 	// bound ensures there are enough elements
 	bound := 2 * (length + n)
@@ -74,11 +96,17 @@ func KeyGen(n int, length int) (*PublicParameters, *MasterKey, error) {
 	pp := &PublicParameters{}
 	pp.hashElements = hashElements
 
-	return pp, msk, nil
+	return pp
 }
 
 // Constrain outputs a constrained key for the CPRF
 func (msk *MasterKey) Constrain(z []*big.Int) (*ConstrainedKey, error) {
+	csk, _, err := msk.constrain(z)
+	return csk, err
+}
+
+// constrain outputs a constrained key and the deltas it was built with
+func (msk *MasterKey) constrain(z []*big.Int) (*ConstrainedKey, []*big.Int, error) {
 
 	// p is the order of the eliptic curve
 	p := elliptic.P256().Params().N
@@ -88,35 +116,41 @@ func (msk *MasterKey) Constrain(z []*big.Int) (*ConstrainedKey, error) {
 	csk := &ConstrainedKey{}
 	csk.n = n
 	csk.length = length
+	csk.schema = msk.schema
+	csk.epoch = msk.epoch
 	csk.z1 = make([][]*big.Int, n)
 
+	deltas := make([]*big.Int, n)
+
 	// the constraint key is computed as z0 - z*Delta_i
 	// for a random Delta_i with i = 1 ... n
 	for i := 0; i < n; i++ {
 		csk.z1[i] = make([]*big.Int, length)
+		z0i := msk.row(i)
 
 		deltai, err := generateRandomBigInt(p)
 		if err != nil {
-			return nil, fmt.Errorf("failed to generate delta_%d for constraint: %w", i, err)
+			return nil, nil, fmt.Errorf("failed to generate delta_%d for constraint: %w", i, err)
 		}
+		deltas[i] = deltai
 
 		for j := 0; j < length; j++ {
 			csk.z1[i][j] = big.NewInt(0)
-			csk.z1[i][j].Mul(deltai, z[j])               // z*Delta_i
-			csk.z1[i][j].Sub(msk.z0[i][j], csk.z1[i][j]) // z0 - z*Delta_i
+			csk.z1[i][j].Mul(deltai, z[j])         // z*Delta_i
+			csk.z1[i][j].Sub(z0i[j], csk.z1[i][j]) // z0 - z*Delta_i
 			if err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 		}
 	}
 
-	return csk, nil
+	return csk, deltas, nil
 }
 
 func (msk *MasterKey) Eval(pp *PublicParameters, x []*big.Int) *ec.Point {
 	n := msk.n
 	length := msk.length
-	return commonEval(pp, n, length, msk.z0, x)
+	return commonEval(pp, n, length, msk.rows(), x)
 }
 
 func (csk *ConstrainedKey) CEval(pp *PublicParameters, x []*big.Int) *ec.Point {
@@ -132,6 +166,18 @@ func commonEval(
 	zb [][]*big.Int,
 	x []*big.Int) *ec.Point {
 
+	keys, keyFPs := innerProductKeys(n, length, zb, x)
+	return combineKeys(pp, n, keys, keyFPs, x)
+}
+
+// innerProductKeys returns the Naor-Reingold keys keys[i] = <zb_i, x>
+// and their fingerprints keyFPs[i] = g^keys[i]
+func innerProductKeys(
+	n int,
+	length int,
+	zb [][]*big.Int,
+	x []*big.Int) ([]*big.Int, []*ec.Point) {
+
 	curve := elliptic.P256()
 	p := elliptic.P256().Params().N
 
@@ -151,11 +197,39 @@ func commonEval(
 		keyFPs[i] = ec.BaseScalarMult(curve, acc)
 	}
 
+	return keys, keyFPs
+}
+
+// combineKeys computes the Naor-Reingold output from the inner products
+// keys[i] = <z_i, x> and their fingerprints keyFPs[i] = g^keys[i]
+func combineKeys(
+	pp *PublicParameters,
+	n int,
+	keys []*big.Int,
+	keyFPs []*ec.Point,
+	x []*big.Int) *ec.Point {
+
 	bits := hashDL(pp, x, keyFPs)[:n] // hashes to n points
 
 	// Alternative: use SHA256
 	// bits := hashSHA256(x, keyFPs)[:n]
 
+	return naorReingold(n, keys, bits)
+}
+
+// naorReingold evaluates the Naor-Reingold PRF with key (keys[0], ..., keys[n-1])
+// on the input 11 || bits[2:n], i.e., outputs g^(keys[0]*keys[1]*PROD keys[i]^bits[i])
+func naorReingold(n int, keys []*big.Int, bits []bool) *ec.Point {
+	curve := elliptic.P256()
+	return ec.BaseScalarMult(curve, naorReingoldExponent(n, keys, bits))
+}
+
+// naorReingoldExponent returns the exponent keys[0]*keys[1]*PROD keys[i]^bits[i]
+// of the Naor-Reingold output
+func naorReingoldExponent(n int, keys []*big.Int, bits []bool) *big.Int {
+
+	p := elliptic.P256().Params().N
+
 	prod := big.NewInt(1)
 
 	// Recall: the input is always prefixed by 11
@@ -169,8 +243,7 @@ func commonEval(
 		}
 	}
 
-	res := ec.BaseScalarMult(curve, prod)
-	return res
+	return prod
 }
 
 // Variant of the Damgard group-based hash function.
@@ -182,59 +255,119 @@ func hashDL(
 	x []*big.Int,
 	keyFPs []*ec.Point) []bool {
 
+	h := newDLHasher(pp)
+
 	// convert everything into a byte array
-	byteInput := make([]byte, 0)
 	for i := 0; i < len(x); i++ {
-		byteInput = append(byteInput, x[i].Bytes()...)
+		h.writeInt(x[i])
 	}
 
 	for i := 0; i < len(keyFPs); i++ {
-		byteInput = append(byteInput, keyFPs[i].MarshalCompressed()...)
+		h.Write(keyFPs[i].MarshalCompressed())
 	}
 
-	// chunk everything up into 256 bit chunks
-	curve := elliptic.P256()
-	blocklen := 256 / 8
+	return h.sum()
+}
 
-	// pad out to the block length if needed
-	paddingRequired := len(byteInput) % blocklen
-	for i := 0; i < paddingRequired; i++ {
-		byteInput = append(byteInput, byte(0))
+// dlHasher computes hashDL incrementally, absorbing the input one
+// 256 bit block at a time so that the input never has to be held
+// in memory. It can be reused for another input after reset.
+type dlHasher struct {
+	pp        *PublicParameters
+	block     [256 / 8]byte // pending bytes of the current block
+	pending   int           // number of pending bytes
+	numBlocks int           // number of absorbed blocks
+	res       *ec.Point     // running product PROD h_i^b_i
+	scalar    big.Int
+	intBuf    []byte
+	extractor hash.Hash
+	hashBits  []bool
+}
+
+func newDLHasher(pp *PublicParameters) *dlHasher {
+	h := &dlHasher{}
+	h.extractor = sha256.New()
+	h.reset(pp)
+	return h
+}
+
+func (h *dlHasher) reset(pp *PublicParameters) {
+	h.pp = pp
+	h.pending = 0
+	h.numBlocks = 0
+	h.res = ec.BaseScalarMult(elliptic.P256(), big.NewInt(1))
+}
+
+// Write absorbs p into the hash; it never returns an error
+func (h *dlHasher) Write(p []byte) (int, error) {
+	written := len(p)
+	for len(p) > 0 {
+		c := copy(h.block[h.pending:], p)
+		h.pending += c
+		p = p[c:]
+		if h.pending == len(h.block) {
+			h.absorb()
+		}
 	}
+	return written, nil
+}
 
-	numBlocks := len(byteInput) / blocklen
-
-	// compute hash of the message as PROD h_i^b_i
-	// where h_i is the i-th element in the public parameters
-	// and b_i is the i-th block of bytes
-	res := ec.BaseScalarMult(curve, big.NewInt(1))
-	for i := 0; i < numBlocks; i++ {
-		start := i * blocklen
-		end := start + blocklen
-		blockNext := big.NewInt(0).SetBytes(byteInput[start:end])
-		a := ec.PointScalarMult(curve, pp.hashElements[i], blockNext)
-		res = ec.PointAdd(curve, res, a)
+// writeInt absorbs the minimal big-endian encoding of v,
+// i.e., the bytes of v.Bytes()
+func (h *dlHasher) writeInt(v *big.Int) {
+	l := (v.BitLen() + 7) / 8
+	if cap(h.intBuf) < l {
+		h.intBuf = make([]byte, l)
 	}
+	h.Write(v.FillBytes(h.intBuf[:l]))
+}
+
+// absorb multiplies the pending block into the running product,
+// where the block is the exponent of the next hash element
+func (h *dlHasher) absorb() {
+	curve := elliptic.P256()
+	h.scalar.SetBytes(h.block[:])
+	a := ec.PointScalarMult(curve, h.pp.hashElements[h.numBlocks], &h.scalar)
+	h.res = ec.PointAdd(curve, h.res, a)
+	h.numBlocks++
+	h.pending = 0
+}
 
-	hash := big.NewInt(0).SetBytes(res.MarshalCompressed()).Bytes()
+// sum finalizes the hash and returns its bits.
+// The returned slice is reused by the next call to sum.
+func (h *dlHasher) sum() []bool {
+
+	// pad out to the block length if needed
+	// (note: the input is padded with as many zeros as there are
+	// pending bytes, so a final block is only absorbed if it is
+	// at least half full)
+	if 2*h.pending >= len(h.block) {
+		for i := h.pending; i < len(h.block); i++ {
+			h.block[i] = 0
+		}
+		h.absorb()
+	}
+	h.pending = 0
+
+	hash := big.NewInt(0).SetBytes(h.res.MarshalCompressed()).Bytes()
 
 	// Apply randomness extractor to the output
 	// bits of the group representation to ensure uniform distribution.
 	// Doesn't need to be sha256 but convenient and doesn't add much overhead.
-	hasher := sha256.New()
-	hasher.Write(hash)
-	hash = hasher.Sum(nil)
+	h.extractor.Reset()
+	h.extractor.Write(hash)
+	hash = h.extractor.Sum(nil)
 
 	// Convert the hash to a bit-wise representation
-	hashBits := make([]bool, 0, len(hash)*8)
+	h.hashBits = h.hashBits[:0]
 	for _, b := range hash {
 		for i := 7; i >= 0; i-- {
 			bit := (b>>uint(i))&1 == 1
-			hashBits = append(hashBits, bit)
+			h.hashBits = append(h.hashBits, bit)
 		}
 	}
 
-	return hashBits
+	return h.hashBits
 }
 
 // SHÁ256 as a collision-resistant hash function.
@@ -9,6 +9,7 @@ import (
 	"testing"
 
 	"github.com/sachaservan/cprf/ddh-cprf/ec"
+	"github.com/sachaservan/cprf/linalg"
 )
 
 func generateRandomBit() int {
@@ -76,6 +77,28 @@ func TestCPRFUnauthorized(t *testing.T) {
 	}
 }
 
+func TestCPRFOrthogonalInputs(t *testing.T) {
+	p := elliptic.P256().Params().N
+	field, _ := linalg.NewField(p)
+	n := 128
+	length := 25
+
+	// arbitrary (dense) constraint
+	z, _ := field.RandomVector(length)
+	pp, msk, _ := KeyGen(n, length)
+	csk, _ := msk.Constrain(z)
+
+	x, _ := field.SampleOrthogonal(z)
+	if !ec.PointsEqual(msk.Eval(pp, x), csk.CEval(pp, x)) {
+		t.Fatalf("Eval and CEval are not equal on an authorized input")
+	}
+
+	y, _ := field.SampleNonOrthogonal(z)
+	if ec.PointsEqual(msk.Eval(pp, y), csk.CEval(pp, y)) {
+		t.Fatalf("Eval and CEval are equal on an unauthorized input")
+	}
+}
+
 func BenchmarkEval(b *testing.B) {
 	p := elliptic.P256().Params().N
 	n := 128
@@ -0,0 +1,174 @@
+package ec
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+)
+
+var (
+	ErrInvalidDLEQProof     = errors.New("DLEQ proof is invalid")
+	ErrBatchLength          = errors.New("batch has mismatched or zero length")
+	ErrInvalidProofEncoding = errors.New("invalid DLEQ proof encoding")
+)
+
+// ScalarSize is the size in bytes of an encoded P-256 scalar
+const ScalarSize = 32
+
+// DLEQProof is a non-interactive Chaum-Pedersen proof that
+// log_G A = log_H B, with challenge C and response S
+type DLEQProof struct {
+	C *big.Int
+	S *big.Int
+}
+
+// ProveDLEQ proves that A = G^k and B = H^k. The statement and the
+// commitments are absorbed into the transcript, which must be in the
+// same state when the proof is verified.
+func ProveDLEQ(t *Transcript, G, A, H, B *Point, k *big.Int) (*DLEQProof, error) {
+	curve := G.Curve
+	N := curve.Params().N
+
+	_, r, err := RandomCurveScalar(curve, rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	T1 := PointScalarMult(curve, G, r)
+	T2 := PointScalarMult(curve, H, r)
+
+	proof := &DLEQProof{}
+	proof.C = dleqChallenge(t, G, A, H, B, T1, T2)
+	proof.S = new(big.Int).Mul(proof.C, k)
+	proof.S.Sub(r, proof.S).Mod(proof.S, N)
+
+	return proof, nil
+}
+
+// VerifyDLEQ checks a proof that log_G A = log_H B by recomputing the
+// commitments T1 = G^S A^C and T2 = H^S B^C
+func VerifyDLEQ(t *Transcript, G, A, H, B *Point, proof *DLEQProof) error {
+	if proof == nil || proof.C == nil || proof.S == nil {
+		return ErrInvalidDLEQProof
+	}
+	for _, P := range []*Point{G, A, H, B} {
+		if !validPoint(P) {
+			return ErrInvalidDLEQProof
+		}
+	}
+
+	curve := G.Curve
+	N := curve.Params().N
+	if proof.C.Sign() < 0 || proof.C.Cmp(N) >= 0 || proof.S.Sign() < 0 || proof.S.Cmp(N) >= 0 {
+		return ErrInvalidDLEQProof
+	}
+
+	T1 := PointAdd(curve, PointScalarMult(curve, G, proof.S), PointScalarMult(curve, A, proof.C))
+	T2 := PointAdd(curve, PointScalarMult(curve, H, proof.S), PointScalarMult(curve, B, proof.C))
+
+	if dleqChallenge(t, G, A, H, B, T1, T2).Cmp(proof.C) != 0 {
+		return ErrInvalidDLEQProof
+	}
+	return nil
+}
+
+// ProveBatchDLEQ proves that A = G^k and B_i = H_i^k for all i with a
+// single proof. The pairs are combined into M = SUM_i c_i H_i and
+// Z = SUM_i c_i B_i with coefficients c_i derived from the transcript
+// after absorbing all points, and the proof is a DLEQ proof for
+// log_G A = log_M Z.
+func ProveBatchDLEQ(t *Transcript, G, A *Point, Hs, Bs []*Point, k *big.Int) (*DLEQProof, error) {
+	M, Z, err := batchCombine(t, G, A, Hs, Bs)
+	if err != nil {
+		return nil, err
+	}
+	return ProveDLEQ(t, G, A, M, Z, k)
+}
+
+// VerifyBatchDLEQ checks a proof created by ProveBatchDLEQ. If some B_i is
+// not H_i^k the proof verifies with probability at most 1/N over the
+// choice of the coefficients.
+func VerifyBatchDLEQ(t *Transcript, G, A *Point, Hs, Bs []*Point, proof *DLEQProof) error {
+	if len(Hs) == 0 || len(Hs) != len(Bs) {
+		return ErrBatchLength
+	}
+	for i := range Hs {
+		if !validPoint(Hs[i]) || !validPoint(Bs[i]) {
+			return ErrInvalidDLEQProof
+		}
+	}
+
+	M, Z, err := batchCombine(t, G, A, Hs, Bs)
+	if err != nil {
+		return err
+	}
+	return VerifyDLEQ(t, G, A, M, Z, proof)
+}
+
+// batchCombine absorbs the batch into the transcript and returns
+// the random linear combinations M = SUM_i c_i H_i and Z = SUM_i c_i B_i
+func batchCombine(t *Transcript, G, A *Point, Hs, Bs []*Point) (*Point, *Point, error) {
+	if len(Hs) == 0 || len(Hs) != len(Bs) {
+		return nil, nil, ErrBatchLength
+	}
+
+	curve := G.Curve
+
+	t.AppendMessage("batch", nil)
+	t.AppendPoint("G", G)
+	t.AppendPoint("A", A)
+	for i := range Hs {
+		t.AppendPoint("H", Hs[i])
+		t.AppendPoint("B", Bs[i])
+	}
+
+	M := &Point{Curve: curve, X: new(big.Int), Y: new(big.Int)}
+	Z := &Point{Curve: curve, X: new(big.Int), Y: new(big.Int)}
+	for i := range Hs {
+		c := t.ChallengeScalar("batch coefficient", curve)
+		M = PointAdd(curve, M, PointScalarMult(curve, Hs[i], c))
+		Z = PointAdd(curve, Z, PointScalarMult(curve, Bs[i], c))
+	}
+
+	return M, Z, nil
+}
+
+// validPoint returns true if P is a point on its curve
+func validPoint(P *Point) bool {
+	return P != nil && P.Curve != nil && P.X != nil && P.Y != nil && P.IsOnCurve()
+}
+
+func dleqChallenge(t *Transcript, G, A, H, B, T1, T2 *Point) *big.Int {
+	t.AppendMessage("dleq", nil)
+	t.AppendPoint("G", G)
+	t.AppendPoint("A", A)
+	t.AppendPoint("H", H)
+	t.AppendPoint("B", B)
+	t.AppendPoint("T1", T1)
+	t.AppendPoint("T2", T2)
+	return t.ChallengeScalar("c", G.Curve)
+}
+
+// MarshalBinary encodes the proof as the fixed-length big-endian
+// encodings of C and S for a P-256 sized group order
+func (proof *DLEQProof) MarshalBinary() ([]byte, error) {
+	if proof.C == nil || proof.S == nil || proof.C.BitLen() > 8*ScalarSize || proof.S.BitLen() > 8*ScalarSize {
+		return nil, ErrInvalidProofEncoding
+	}
+
+	data := make([]byte, 2*ScalarSize)
+	proof.C.FillBytes(data[:ScalarSize])
+	proof.S.FillBytes(data[ScalarSize:])
+	return data, nil
+}
+
+// UnmarshalBinary decodes a proof encoded by MarshalBinary
+func (proof *DLEQProof) UnmarshalBinary(data []byte) error {
+	if len(data) != 2*ScalarSize {
+		return ErrInvalidProofEncoding
+	}
+
+	proof.C = new(big.Int).SetBytes(data[:ScalarSize])
+	proof.S = new(big.Int).SetBytes(data[ScalarSize:])
+	return nil
+}
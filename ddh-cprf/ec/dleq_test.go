@@ -0,0 +1,216 @@
+package ec
+
+import (
+	"crypto/elliptic"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"testing"
+)
+
+const testDomain = "ec DLEQ test v1"
+
+// dleqInstance returns G, A = G^k, H, B = H^k for random G, H and k
+func dleqInstance(t testing.TB) (*Point, *Point, *Point, *Point, *big.Int) {
+	curve := elliptic.P256()
+	_, G, err := NewRandomPoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, H, err := NewRandomPoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, k, _ := RandomCurveScalar(curve, rand.Reader)
+
+	return G, PointScalarMult(curve, G, k), H, PointScalarMult(curve, H, k), k
+}
+
+// batchInstance returns size pairs H_i, B_i = H_i^k
+func batchInstance(t testing.TB, size int, k *big.Int) ([]*Point, []*Point) {
+	curve := elliptic.P256()
+	Hs := make([]*Point, size)
+	Bs := make([]*Point, size)
+	for i := 0; i < size; i++ {
+		_, r, _ := RandomCurveScalar(curve, rand.Reader)
+		Hs[i] = BaseScalarMult(curve, r)
+		Bs[i] = PointScalarMult(curve, Hs[i], k)
+	}
+	return Hs, Bs
+}
+
+func TestDLEQ(t *testing.T) {
+	G, A, H, B, k := dleqInstance(t)
+
+	proof, err := ProveDLEQ(NewTranscript(testDomain), G, A, H, B, k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyDLEQ(NewTranscript(testDomain), G, A, H, B, proof); err != nil {
+		t.Fatalf("valid proof rejected: %v", err)
+	}
+}
+
+func TestDLEQInvalid(t *testing.T) {
+	curve := elliptic.P256()
+	G, A, H, B, k := dleqInstance(t)
+	proof, _ := ProveDLEQ(NewTranscript(testDomain), G, A, H, B, k)
+
+	_, C, _ := NewRandomPoint()
+	one := big.NewInt(1)
+
+	for name, verify := range map[string]func() error{
+		"wrong B": func() error {
+			return VerifyDLEQ(NewTranscript(testDomain), G, A, H, C, proof)
+		},
+		"swapped statement": func() error {
+			return VerifyDLEQ(NewTranscript(testDomain), H, B, G, A, proof)
+		},
+		"other domain": func() error {
+			return VerifyDLEQ(NewTranscript("other domain"), G, A, H, B, proof)
+		},
+		"extra transcript message": func() error {
+			tr := NewTranscript(testDomain)
+			tr.AppendMessage("context", []byte("x"))
+			return VerifyDLEQ(tr, G, A, H, B, proof)
+		},
+		"tampered challenge": func() error {
+			c := new(big.Int).Add(proof.C, one)
+			return VerifyDLEQ(NewTranscript(testDomain), G, A, H, B, &DLEQProof{C: c, S: proof.S})
+		},
+		"tampered response": func() error {
+			s := new(big.Int).Add(proof.S, one)
+			return VerifyDLEQ(NewTranscript(testDomain), G, A, H, B, &DLEQProof{C: proof.C, S: s})
+		},
+		"unreduced response": func() error {
+			s := new(big.Int).Add(proof.S, curve.Params().N)
+			return VerifyDLEQ(NewTranscript(testDomain), G, A, H, B, &DLEQProof{C: proof.C, S: s})
+		},
+		"missing proof": func() error {
+			return VerifyDLEQ(NewTranscript(testDomain), G, A, H, B, nil)
+		},
+		"point off curve": func() error {
+			off := &Point{Curve: curve, X: new(big.Int).Set(B.X), Y: new(big.Int).Add(B.Y, one)}
+			return VerifyDLEQ(NewTranscript(testDomain), G, A, H, off, proof)
+		},
+	} {
+		if err := verify(); err != ErrInvalidDLEQProof {
+			t.Fatalf("%s: expected ErrInvalidDLEQProof, got %v", name, err)
+		}
+	}
+
+	// a proof with a different exponent does not verify
+	_, k2, _ := RandomCurveScalar(curve, rand.Reader)
+	bad, _ := ProveDLEQ(NewTranscript(testDomain), G, A, H, PointScalarMult(curve, H, k2), k)
+	if err := VerifyDLEQ(NewTranscript(testDomain), G, A, H, PointScalarMult(curve, H, k2), bad); err != ErrInvalidDLEQProof {
+		t.Fatalf("expected ErrInvalidDLEQProof for unequal logarithms, got %v", err)
+	}
+}
+
+func TestBatchDLEQ(t *testing.T) {
+	G, A, _, _, k := dleqInstance(t)
+
+	for _, size := range []int{1, 2, 16} {
+		Hs, Bs := batchInstance(t, size, k)
+
+		proof, err := ProveBatchDLEQ(NewTranscript(testDomain), G, A, Hs, Bs, k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := VerifyBatchDLEQ(NewTranscript(testDomain), G, A, Hs, Bs, proof); err != nil {
+			t.Fatalf("size %d: valid proof rejected: %v", size, err)
+		}
+
+		// one wrong element
+		_, C, _ := NewRandomPoint()
+		saved := Bs[size-1]
+		Bs[size-1] = C
+		if err := VerifyBatchDLEQ(NewTranscript(testDomain), G, A, Hs, Bs, proof); err != ErrInvalidDLEQProof {
+			t.Fatalf("size %d: expected ErrInvalidDLEQProof for a wrong element, got %v", size, err)
+		}
+		Bs[size-1] = saved
+
+		// reordered batch
+		if size > 1 {
+			Hs[0], Hs[1], Bs[0], Bs[1] = Hs[1], Hs[0], Bs[1], Bs[0]
+			if err := VerifyBatchDLEQ(NewTranscript(testDomain), G, A, Hs, Bs, proof); err != ErrInvalidDLEQProof {
+				t.Fatalf("size %d: expected ErrInvalidDLEQProof for a reordered batch, got %v", size, err)
+			}
+		}
+	}
+
+	Hs, Bs := batchInstance(t, 3, k)
+	if _, err := ProveBatchDLEQ(NewTranscript(testDomain), G, A, Hs, Bs[:2], k); err != ErrBatchLength {
+		t.Fatalf("expected ErrBatchLength, got %v", err)
+	}
+	if err := VerifyBatchDLEQ(NewTranscript(testDomain), G, A, nil, nil, &DLEQProof{}); err != ErrBatchLength {
+		t.Fatalf("expected ErrBatchLength, got %v", err)
+	}
+}
+
+func TestDLEQProofMarshal(t *testing.T) {
+	G, A, H, B, k := dleqInstance(t)
+	proof, _ := ProveDLEQ(NewTranscript(testDomain), G, A, H, B, k)
+
+	data, err := proof.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 2*ScalarSize {
+		t.Fatalf("proof encodes to %d bytes", len(data))
+	}
+
+	decoded := &DLEQProof{}
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyDLEQ(NewTranscript(testDomain), G, A, H, B, decoded); err != nil {
+		t.Fatalf("decoded proof rejected: %v", err)
+	}
+
+	if err := decoded.UnmarshalBinary(data[1:]); err != ErrInvalidProofEncoding {
+		t.Fatalf("expected ErrInvalidProofEncoding, got %v", err)
+	}
+	if _, err := (&DLEQProof{}).MarshalBinary(); err != ErrInvalidProofEncoding {
+		t.Fatalf("expected ErrInvalidProofEncoding, got %v", err)
+	}
+}
+
+func TestTranscriptSeparation(t *testing.T) {
+	curve := elliptic.P256()
+
+	a := NewTranscript("d")
+	a.AppendMessage("ab", []byte("c"))
+	b := NewTranscript("d")
+	b.AppendMessage("a", []byte("bc"))
+	if a.ChallengeScalar("c", curve).Cmp(b.ChallengeScalar("c", curve)) == 0 {
+		t.Fatalf("transcripts with different message splits collide")
+	}
+
+	// consecutive challenges differ
+	c := NewTranscript("d")
+	if c.ChallengeScalar("c", curve).Cmp(c.ChallengeScalar("c", curve)) == 0 {
+		t.Fatalf("consecutive challenges are equal")
+	}
+}
+
+func BenchmarkBatchDLEQ(b *testing.B) {
+	G, A, _, _, k := dleqInstance(b)
+
+	for _, size := range []int{1, 4, 16, 64, 256, 1024} {
+		Hs, Bs := batchInstance(b, size, k)
+		proof, _ := ProveBatchDLEQ(NewTranscript(testDomain), G, A, Hs, Bs, k)
+
+		b.Run(fmt.Sprintf("Prove/size=%d", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				ProveBatchDLEQ(NewTranscript(testDomain), G, A, Hs, Bs, k)
+			}
+		})
+
+		b.Run(fmt.Sprintf("Verify/size=%d", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				VerifyBatchDLEQ(NewTranscript(testDomain), G, A, Hs, Bs, proof)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package ec
+
+import (
+	"crypto/elliptic"
+	"crypto/sha512"
+	"encoding/binary"
+	"hash"
+	"math/big"
+)
+
+// Transcript is a Fiat-Shamir transcript. Messages are absorbed with a
+// label and length prefix into a running SHA-512 hash, so transcripts with
+// a different domain, label or message split never collide. Challenges
+// are derived from the current state and absorbed back into it.
+type Transcript struct {
+	h hash.Hash
+}
+
+// NewTranscript returns a transcript separated by the given domain,
+// which should identify the protocol and its version
+func NewTranscript(domain string) *Transcript {
+	t := &Transcript{}
+	t.h = sha512.New()
+	t.AppendMessage("domain", []byte(domain))
+	return t
+}
+
+// AppendMessage absorbs a labeled message into the transcript
+func (t *Transcript) AppendMessage(label string, msg []byte) {
+	binary.Write(t.h, binary.BigEndian, uint32(len(label)))
+	t.h.Write([]byte(label))
+	binary.Write(t.h, binary.BigEndian, uint64(len(msg)))
+	t.h.Write(msg)
+}
+
+// AppendPoint absorbs the compressed encoding of a point
+func (t *Transcript) AppendPoint(label string, P *Point) {
+	t.AppendMessage(label, P.MarshalCompressed())
+}
+
+// AppendScalar absorbs a non-negative integer
+func (t *Transcript) AppendScalar(label string, s *big.Int) {
+	t.AppendMessage(label, s.Bytes())
+}
+
+// ChallengeScalar derives a labeled challenge modulo the order of the curve.
+// The 512 bit digest is reduced modulo the order, which has negligible bias
+// for curves of up to 384 bits.
+func (t *Transcript) ChallengeScalar(label string, curve elliptic.Curve) *big.Int {
+	t.AppendMessage("challenge", []byte(label))
+	digest := t.h.Sum(nil)
+	t.AppendMessage("challenge output", digest)
+
+	c := new(big.Int).SetBytes(digest)
+	return c.Mod(c, curve.Params().N)
+}
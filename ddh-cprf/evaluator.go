@@ -0,0 +1,130 @@
+package ddhcprf
+
+import (
+	"crypto/elliptic"
+	"errors"
+	"math/big"
+	"sync"
+
+	"github.com/sachaservan/cprf/ddh-cprf/ec"
+)
+
+var (
+	ErrLengthMismatch = errors.New("input length does not match key length")
+)
+
+// Evaluator evaluates the CPRF under a fixed key and reuses
+// its scratch buffers across calls. Scratch state is kept in
+// a sync.Pool so an Evaluator is safe for concurrent use.
+//
+// The elliptic curve operations still allocate internally, so
+// unlike rocprf.Evaluator this only removes the allocations
+// made by the evaluation itself.
+type Evaluator struct {
+	pp     *PublicParameters
+	n      int
+	length int
+	zb     [][]*big.Int
+	pool   sync.Pool
+}
+
+// scratch state for a single evaluation
+type evalScratch struct {
+	tmp    big.Int
+	prod   big.Int
+	keys   []big.Int
+	keyFPs []*ec.Point
+	scalar []byte
+	hasher *dlHasher
+}
+
+// NewEvaluator returns an Evaluator bound to the master key
+func (msk *MasterKey) NewEvaluator(pp *PublicParameters) *Evaluator {
+	return newEvaluator(pp, msk.n, msk.length, msk.rows())
+}
+
+// NewEvaluator returns an Evaluator bound to the constrained key
+func (csk *ConstrainedKey) NewEvaluator(pp *PublicParameters) *Evaluator {
+	return newEvaluator(pp, csk.n, csk.length, csk.z1)
+}
+
+func newEvaluator(pp *PublicParameters, n int, length int, zb [][]*big.Int) *Evaluator {
+	e := &Evaluator{}
+	e.pp = pp
+	e.n = n
+	e.length = length
+	e.zb = zb
+	e.pool.New = func() interface{} {
+		s := &evalScratch{}
+		s.keys = make([]big.Int, n)
+		s.keyFPs = make([]*ec.Point, n)
+		for i := 0; i < n; i++ {
+			s.keyFPs[i] = &ec.Point{Curve: elliptic.P256()}
+		}
+		s.scalar = make([]byte, 32)
+		s.hasher = newDLHasher(pp)
+		return s
+	}
+	return e
+}
+
+// Eval evaluates the CPRF on x and returns a freshly allocated output
+func (e *Evaluator) Eval(x []*big.Int) (*ec.Point, error) {
+	dst := &ec.Point{}
+	if err := e.EvalInto(dst, x); err != nil {
+		return nil, err
+	}
+	return dst, nil
+}
+
+// EvalInto evaluates the CPRF on x and stores the output in dst
+func (e *Evaluator) EvalInto(dst *ec.Point, x []*big.Int) error {
+	if len(x) != e.length {
+		return ErrLengthMismatch
+	}
+
+	s := e.pool.Get().(*evalScratch)
+	defer e.pool.Put(s)
+
+	curve := elliptic.P256()
+	p := curve.Params().N
+
+	for i := 0; i < e.n; i++ {
+		acc := &s.keys[i]
+		acc.SetInt64(0)
+
+		for j := 0; j < e.length; j++ {
+			s.tmp.Mul(e.zb[i][j], x[j])
+			acc.Add(acc, &s.tmp).Mod(acc, p)
+		}
+
+		fp := s.keyFPs[i]
+		fp.X, fp.Y = curve.ScalarBaseMult(acc.FillBytes(s.scalar))
+	}
+
+	s.hasher.reset(e.pp)
+	for j := 0; j < e.length; j++ {
+		s.hasher.writeInt(x[j])
+	}
+	for i := 0; i < e.n; i++ {
+		s.hasher.Write(s.keyFPs[i].MarshalCompressed())
+	}
+	bits := s.hasher.sum()
+
+	// Recall: the input is always prefixed by 11
+	s.prod.SetInt64(1)
+	s.prod.Mul(&s.prod, &s.keys[0]).Mod(&s.prod, p)
+	s.prod.Mul(&s.prod, &s.keys[1]).Mod(&s.prod, p)
+
+	// Compute a_i^{x_i}
+	for i := 2; i < e.n; i++ {
+		if bits[i] {
+			s.prod.Mul(&s.prod, &s.keys[i]).Mod(&s.prod, p)
+		}
+	}
+
+	dst.Curve = curve
+	dst.X, dst.Y = curve.ScalarBaseMult(s.prod.FillBytes(s.scalar))
+
+	return nil
+}
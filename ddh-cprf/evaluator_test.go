@@ -0,0 +1,111 @@
+package ddhcprf
+
+import (
+	"crypto/elliptic"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/sachaservan/cprf/ddh-cprf/ec"
+)
+
+func TestEvaluatorMatchesEval(t *testing.T) {
+	p := elliptic.P256().Params().N
+	n := 128
+	length := 25
+
+	z, _ := generateRandomVector(length, p)
+	pp, msk, _ := KeyGen(n, length)
+	csk, _ := msk.Constrain(z)
+
+	mev := msk.NewEvaluator(pp)
+	cev := csk.NewEvaluator(pp)
+
+	dst := &ec.Point{}
+	for trial := 0; trial < 5; trial++ {
+		x, _ := generateRandomVector(length, p)
+
+		if err := mev.EvalInto(dst, x); err != nil {
+			t.Fatal(err)
+		}
+		if !ec.PointsEqual(dst, msk.Eval(pp, x)) {
+			t.Fatalf("Evaluator and Eval are not equal")
+		}
+
+		if err := cev.EvalInto(dst, x); err != nil {
+			t.Fatal(err)
+		}
+		if !ec.PointsEqual(dst, csk.CEval(pp, x)) {
+			t.Fatalf("Evaluator and CEval are not equal")
+		}
+	}
+
+	if _, err := mev.Eval(z[:length-1]); err != ErrLengthMismatch {
+		t.Fatalf("expected ErrLengthMismatch, got %v", err)
+	}
+}
+
+func TestEvaluatorConcurrent(t *testing.T) {
+	p := elliptic.P256().Params().N
+	n := 128
+	length := 10
+
+	pp, msk, _ := KeyGen(n, length)
+	ev := msk.NewEvaluator(pp)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 4)
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			dst := &ec.Point{}
+			for i := 0; i < 5; i++ {
+				x, _ := generateRandomVector(length, p)
+				if err := ev.EvalInto(dst, x); err != nil {
+					errs <- err
+					return
+				}
+				if !ec.PointsEqual(dst, msk.Eval(pp, x)) {
+					errs <- fmt.Errorf("concurrent Evaluator output differs from Eval")
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Fatal(err)
+	}
+}
+
+func BenchmarkEvalInto(b *testing.B) {
+	p := elliptic.P256().Params().N
+	n := 128
+
+	// Run the benchmark for different parameter sets
+	for _, params := range []struct{ length int }{
+		{10},
+		{50},
+		{100},
+		{500},
+		{1000},
+	} {
+		b.Run(fmt.Sprintf("length=%d", params.length), func(b *testing.B) {
+
+			pp, msk, _ := KeyGen(n, params.length)
+			x, _ := generateRandomVector(params.length, p)
+			ev := msk.NewEvaluator(pp)
+			dst := &ec.Point{}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				ev.EvalInto(dst, x)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package ddhcprf
+
+import (
+	"crypto/elliptic"
+	"errors"
+	"math/big"
+
+	"github.com/sachaservan/cprf/ddh-cprf/ec"
+)
+
+var (
+	ErrIndexOutOfRange = errors.New("coordinate index is out of range")
+)
+
+// IncrementalEval caches the n inner products between a key and an input
+// vector so that changing a single coordinate of the input costs O(n)
+// instead of recomputing all O(n*length) inner products.
+// Computing the output still hashes the whole input.
+type IncrementalEval struct {
+	pp   *PublicParameters
+	n    int
+	zb   [][]*big.Int
+	x    []*big.Int
+	keys []*big.Int // cached inner products <zb[i], x>
+}
+
+// NewIncrementalEval returns an IncrementalEval of the master key on x
+func (msk *MasterKey) NewIncrementalEval(pp *PublicParameters, x []*big.Int) (*IncrementalEval, error) {
+	return newIncrementalEval(pp, msk.n, msk.length, msk.rows(), x)
+}
+
+// NewIncrementalEval returns an IncrementalEval of the constrained key on x
+func (csk *ConstrainedKey) NewIncrementalEval(pp *PublicParameters, x []*big.Int) (*IncrementalEval, error) {
+	return newIncrementalEval(pp, csk.n, csk.length, csk.z1, x)
+}
+
+func newIncrementalEval(
+	pp *PublicParameters,
+	n int,
+	length int,
+	zb [][]*big.Int,
+	x []*big.Int) (*IncrementalEval, error) {
+
+	if len(x) != length {
+		return nil, ErrLengthMismatch
+	}
+
+	p := elliptic.P256().Params().N
+
+	ie := &IncrementalEval{}
+	ie.pp = pp
+	ie.n = n
+	ie.zb = zb
+	ie.x = make([]*big.Int, length)
+	ie.keys = make([]*big.Int, n)
+
+	for j := 0; j < length; j++ {
+		ie.x[j] = big.NewInt(0).Set(x[j])
+	}
+
+	tmp := big.NewInt(0)
+	for i := 0; i < n; i++ {
+		ie.keys[i] = big.NewInt(0)
+		for j := 0; j < length; j++ {
+			tmp.Mul(zb[i][j], x[j])
+			ie.keys[i].Add(ie.keys[i], tmp).Mod(ie.keys[i], p)
+		}
+	}
+
+	return ie, nil
+}
+
+// Update sets coordinate j of the input to value
+func (ie *IncrementalEval) Update(j int, value *big.Int) error {
+	if j < 0 || j >= len(ie.x) {
+		return ErrIndexOutOfRange
+	}
+
+	p := elliptic.P256().Params().N
+
+	// keys[i] += zb[i][j] * (value - x[j])
+	diff := big.NewInt(0).Sub(value, ie.x[j])
+	tmp := big.NewInt(0)
+	for i := 0; i < ie.n; i++ {
+		tmp.Mul(ie.zb[i][j], diff)
+		ie.keys[i].Add(ie.keys[i], tmp).Mod(ie.keys[i], p)
+	}
+
+	ie.x[j].Set(value)
+
+	return nil
+}
+
+// Output returns the CPRF output on the current input
+func (ie *IncrementalEval) Output() *ec.Point {
+	curve := elliptic.P256()
+
+	keyFPs := make([]*ec.Point, ie.n) // key fingerprint curve points
+	for i := 0; i < ie.n; i++ {
+		keyFPs[i] = ec.BaseScalarMult(curve, ie.keys[i])
+	}
+
+	return combineKeys(ie.pp, ie.n, ie.keys, keyFPs, ie.x)
+}
@@ -0,0 +1,53 @@
+package ddhcprf
+
+import (
+	"crypto/elliptic"
+	"math/big"
+	mrand "math/rand"
+	"testing"
+
+	"github.com/sachaservan/cprf/ddh-cprf/ec"
+)
+
+func TestIncrementalEvalMatchesEval(t *testing.T) {
+	p := elliptic.P256().Params().N
+	n := 128
+	length := 25
+
+	z, _ := generateRandomVector(length, p)
+	pp, msk, _ := KeyGen(n, length)
+	csk, _ := msk.Constrain(z)
+
+	x, _ := generateRandomVector(length, p)
+	mie, err := msk.NewIncrementalEval(pp, x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cie, err := csk.NewIncrementalEval(pp, x)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for trial := 0; trial < 5; trial++ {
+		j := mrand.Intn(length)
+		value, _ := generateRandomBigInt(p)
+		if trial == 0 {
+			value = big.NewInt(0)
+		}
+
+		x[j] = value
+		mie.Update(j, value)
+		cie.Update(j, value)
+
+		if !ec.PointsEqual(mie.Output(), msk.Eval(pp, x)) {
+			t.Fatalf("IncrementalEval and Eval are not equal after %d updates", trial+1)
+		}
+		if !ec.PointsEqual(cie.Output(), csk.CEval(pp, x)) {
+			t.Fatalf("IncrementalEval and CEval are not equal after %d updates", trial+1)
+		}
+	}
+
+	if err := mie.Update(-1, big.NewInt(1)); err != ErrIndexOutOfRange {
+		t.Fatalf("expected ErrIndexOutOfRange, got %v", err)
+	}
+}
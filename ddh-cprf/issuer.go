@@ -0,0 +1,201 @@
+package ddhcprf
+
+import (
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/sachaservan/cprf/ledger"
+)
+
+// The construction is proven secure only when a single constrained key is
+// issued per master key, but nothing stops a second call to Constrain.
+// An Issuer wraps a master key and a ledger: it records every constrained
+// key it issues and refuses to issue another one for the same master key
+// unless the caller explicitly overrides the check with a reason, which is
+// recorded as well. Keys constrained from msk directly bypass the ledger.
+//
+// Rotating through the Issuer records the rotation in the ledger, and an
+// issuance from the rotated key counts against every key it was rotated
+// from: constrained keys of earlier epochs are updated to the new key, so
+// they are issuances of it as well.
+
+var (
+	ErrAlreadyIssued  = errors.New("a constrained key has already been issued for this master key")
+	ErrOverrideReason = errors.New("an override requires a reason")
+)
+
+const (
+	keyIDDomain          = "ddh-cprf key id v1"
+	constraintHashDomain = "ddh-cprf constraint hash key v1"
+)
+
+// Issuer issues constrained keys from a master key and records them in a ledger
+type Issuer struct {
+	msk     *MasterKey
+	ledger  ledger.Ledger
+	keyID   string
+	hashKey []byte
+	mu      sync.Mutex
+}
+
+// KeyID returns the ID of the master key, a hash of the rows of z0 alone.
+// Copies with another schema ID or epoch, and the expansion of a compact
+// key, have the same ID; rotated keys have another.
+func (msk *MasterKey) KeyID() string {
+	return hex.EncodeToString(msk.keyMaterialDigest(keyIDDomain)[:16])
+}
+
+// keyMaterialDigest returns the SHA-256 hash of the domain and the rows of z0
+func (msk *MasterKey) keyMaterialDigest(domain string) []byte {
+	p := elliptic.P256().Params().N
+	elem := make([]byte, (p.BitLen()+7)/8)
+
+	hasher := sha256.New()
+	hasher.Write([]byte(domain))
+	hasher.Write(binary.AppendUvarint(nil, uint64(msk.n)))
+	hasher.Write(binary.AppendUvarint(nil, uint64(msk.length)))
+	for i := 0; i < msk.n; i++ {
+		for _, v := range msk.row(i) {
+			hasher.Write(big.NewInt(0).Mod(v, p).FillBytes(elem))
+		}
+	}
+	return hasher.Sum(nil)
+}
+
+// NewIssuer returns an issuer for the master key recording to the ledger
+func NewIssuer(msk *MasterKey, l ledger.Ledger) (*Issuer, error) {
+	iss := &Issuer{}
+	iss.ledger = l
+	iss.setKey(msk)
+
+	return iss, nil
+}
+
+func (iss *Issuer) setKey(msk *MasterKey) {
+	iss.msk = msk
+	iss.keyID = msk.KeyID()
+	iss.hashKey = msk.keyMaterialDigest(constraintHashDomain)
+}
+
+// KeyID returns the ID of the master key of the issuer
+func (iss *Issuer) KeyID() string {
+	iss.mu.Lock()
+	defer iss.mu.Unlock()
+
+	return iss.keyID
+}
+
+// MasterKey returns the current master key of the issuer
+func (iss *Issuer) MasterKey() *MasterKey {
+	iss.mu.Lock()
+	defer iss.mu.Unlock()
+
+	return iss.msk
+}
+
+// HashConstraint returns the hash of z recorded in the ledger
+// for keys issued from the current master key
+func (iss *Issuer) HashConstraint(z []*big.Int) string {
+	iss.mu.Lock()
+	defer iss.mu.Unlock()
+
+	return ledger.HashConstraint(iss.hashKey, z)
+}
+
+// Rotate rotates the master key of the issuer, records the rotation in
+// the ledger and returns the token that updates constrained keys. The
+// caller must store the new master key (see MasterKey) in place of the old.
+func (iss *Issuer) Rotate() (*UpdateToken, error) {
+	iss.mu.Lock()
+	defer iss.mu.Unlock()
+
+	next, token, err := iss.msk.Rotate()
+	if err != nil {
+		return nil, err
+	}
+
+	rec := ledger.Record{}
+	rec.KeyID = next.KeyID()
+	rec.RotatedFrom = iss.keyID
+	rec.Time = time.Now().UTC()
+
+	// the new key is only used once the rotation is recorded
+	if err := iss.ledger.Append(rec); err != nil {
+		return nil, err
+	}
+
+	iss.setKey(next)
+	return token, nil
+}
+
+// Constrain issues a constrained key for z, or returns ErrAlreadyIssued if
+// the ledger holds an issuance for the master key or a key it was rotated from
+func (iss *Issuer) Constrain(z []*big.Int) (*ConstrainedKey, error) {
+	return iss.issue(z, false, "")
+}
+
+// ConstrainOverride issues a constrained key for z even if keys have already
+// been issued for the master key, recording the override and its reason
+func (iss *Issuer) ConstrainOverride(z []*big.Int, reason string) (*ConstrainedKey, error) {
+	if reason == "" {
+		return nil, ErrOverrideReason
+	}
+	return iss.issue(z, true, reason)
+}
+
+// History returns the issuance and rotation records of the
+// master key and of every key it was rotated from
+func (iss *Issuer) History() ([]ledger.Record, error) {
+	iss.mu.Lock()
+	defer iss.mu.Unlock()
+
+	return ledger.History(iss.ledger, iss.keyID)
+}
+
+func (iss *Issuer) issue(z []*big.Int, override bool, reason string) (*ConstrainedKey, error) {
+	iss.mu.Lock()
+	defer iss.mu.Unlock()
+
+	if len(z) != iss.msk.length {
+		return nil, ErrLengthMismatch
+	}
+
+	records, err := ledger.History(iss.ledger, iss.keyID)
+	if err != nil {
+		return nil, err
+	}
+	issued := 0
+	for _, rec := range records {
+		if !rec.IsRotation() {
+			issued++
+		}
+	}
+	if issued > 0 && !override {
+		return nil, ErrAlreadyIssued
+	}
+
+	csk, err := iss.msk.Constrain(z)
+	if err != nil {
+		return nil, err
+	}
+
+	rec := ledger.Record{}
+	rec.KeyID = iss.keyID
+	rec.ConstraintHash = ledger.HashConstraint(iss.hashKey, z)
+	rec.Time = time.Now().UTC()
+	rec.Override = issued > 0
+	rec.Reason = reason
+
+	// the key is only released once the issuance is recorded
+	if err := iss.ledger.Append(rec); err != nil {
+		return nil, err
+	}
+
+	return csk, nil
+}
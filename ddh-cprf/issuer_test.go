@@ -0,0 +1,169 @@
+package ddhcprf
+
+import (
+	"crypto/elliptic"
+	"path/filepath"
+	"testing"
+
+	"github.com/sachaservan/cprf/ledger"
+)
+
+func TestIssuer(t *testing.T) {
+	p := elliptic.P256().Params().N
+	n := 16
+	length := 5
+
+	_, msk, _ := KeyGen(n, length)
+	l := ledger.NewMemory()
+	iss, err := NewIssuer(msk, l)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	z, _ := generateRandomVector(length, p)
+	if _, err := iss.Constrain(z); err != nil {
+		t.Fatal(err)
+	}
+
+	// a second issuance is refused, also for the same constraint
+	z2, _ := generateRandomVector(length, p)
+	if _, err := iss.Constrain(z2); err != ErrAlreadyIssued {
+		t.Fatalf("expected ErrAlreadyIssued, got %v", err)
+	}
+	if _, err := iss.Constrain(z); err != ErrAlreadyIssued {
+		t.Fatalf("expected ErrAlreadyIssued, got %v", err)
+	}
+
+	// another issuer on the same ledger sees the issuance
+	iss2, _ := NewIssuer(msk, l)
+	if _, err := iss2.Constrain(z2); err != ErrAlreadyIssued {
+		t.Fatalf("expected ErrAlreadyIssued from a second issuer, got %v", err)
+	}
+
+	if _, err := iss.ConstrainOverride(z2, ""); err != ErrOverrideReason {
+		t.Fatalf("expected ErrOverrideReason, got %v", err)
+	}
+	if _, err := iss.ConstrainOverride(z2, "holder lost the first key"); err != nil {
+		t.Fatal(err)
+	}
+
+	history, err := iss.History()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(history))
+	}
+	if history[0].KeyID != iss.KeyID() || history[0].ConstraintHash != iss.HashConstraint(z) || history[0].Override {
+		t.Fatalf("unexpected first record %v", history[0])
+	}
+	if history[1].ConstraintHash != iss.HashConstraint(z2) || !history[1].Override || history[1].Reason != "holder lost the first key" {
+		t.Fatalf("unexpected second record %v", history[1])
+	}
+
+	// keys of another master key are not affected
+	_, other, _ := KeyGen(n, length)
+	iss3, _ := NewIssuer(other, l)
+	if iss3.KeyID() == iss.KeyID() {
+		t.Fatalf("different keys have the same key ID")
+	}
+	if _, err := iss3.Constrain(z); err != nil {
+		t.Fatal(err)
+	}
+
+	// a rotated key is a new key unless the rotation is recorded
+	next, _, _ := msk.Rotate()
+	iss4, _ := NewIssuer(next, l)
+	if _, err := iss4.Constrain(z); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := iss3.Constrain(z[:length-1]); err != ErrLengthMismatch {
+		t.Fatalf("expected ErrLengthMismatch, got %v", err)
+	}
+}
+
+func TestIssuerRotate(t *testing.T) {
+	p := elliptic.P256().Params().N
+	n := 16
+	length := 5
+
+	_, msk, _ := KeyGen(n, length)
+	l := ledger.NewMemory()
+	iss, _ := NewIssuer(msk, l)
+	oldID := iss.KeyID()
+
+	z, _ := generateRandomVector(length, p)
+	csk, err := iss.Constrain(z)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := iss.Rotate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if iss.KeyID() == oldID || iss.KeyID() != iss.MasterKey().KeyID() {
+		t.Fatalf("issuer did not move to the rotated key")
+	}
+	if err := csk.ApplyUpdate(token); err != nil {
+		t.Fatal(err)
+	}
+
+	// the issuance of the old key counts against the rotated key,
+	// also for an issuer that starts from the stored rotated key
+	z2, _ := generateRandomVector(length, p)
+	if _, err := iss.Constrain(z2); err != ErrAlreadyIssued {
+		t.Fatalf("expected ErrAlreadyIssued after a rotation, got %v", err)
+	}
+	restarted, _ := NewIssuer(iss.MasterKey(), l)
+	if _, err := restarted.Constrain(z2); err != ErrAlreadyIssued {
+		t.Fatalf("expected ErrAlreadyIssued for a restarted issuer, got %v", err)
+	}
+
+	history, _ := iss.History()
+	if len(history) != 2 || history[0].KeyID != oldID || !history[1].IsRotation() || history[1].RotatedFrom != oldID {
+		t.Fatalf("unexpected history %v", history)
+	}
+
+	// the key ID depends on z0 alone
+	copied := &MasterKey{}
+	*copied = *iss.MasterKey()
+	copied.SetSchema("users/v2/0011223344556677")
+	copied.epoch += 5
+	if copied.KeyID() != iss.KeyID() {
+		t.Fatalf("key ID depends on the schema or epoch")
+	}
+	_, other, _ := KeyGen(n, length)
+	if other.KeyID() == iss.KeyID() {
+		t.Fatalf("different keys have the same key ID")
+	}
+}
+
+func TestIssuerFileLedger(t *testing.T) {
+	p := elliptic.P256().Params().N
+	path := filepath.Join(t.TempDir(), "ledger.jsonl")
+
+	_, msk, _ := KeyGenCompact(16, 5)
+	z, _ := generateRandomVector(5, p)
+
+	l, _ := ledger.OpenFile(path)
+	iss, _ := NewIssuer(msk, l)
+	if _, err := iss.Constrain(z); err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+
+	// the issuance survives a restart, and the key ID of
+	// a compact key is that of its decoded copy
+	data, _ := msk.MarshalBinary()
+	restored := &MasterKey{}
+	restored.UnmarshalBinary(data)
+
+	l, _ = ledger.OpenFile(path)
+	defer l.Close()
+	iss, _ = NewIssuer(restored, l)
+	if _, err := iss.Constrain(z); err != ErrAlreadyIssued {
+		t.Fatalf("expected ErrAlreadyIssued after a restart, got %v", err)
+	}
+}
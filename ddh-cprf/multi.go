@@ -0,0 +1,73 @@
+package ddhcprf
+
+import (
+	"crypto/elliptic"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+var (
+	ErrNoConstraints = errors.New("at least one constraint vector is required")
+)
+
+// ConstrainMulti outputs a constrained key for the conjunction
+// <z_1,x> = 0 AND ... AND <z_k,x> = 0, i.e., for the orthogonal
+// complement of the span of the z_j.
+//
+// Row i of the constrained key is z0_i - SUM_j Delta_{i,j} z_j for
+// independent random Delta_{i,j}. Security caveats:
+//   - The security proof of the construction only covers a single
+//     constraint vector; this variant is not covered by it.
+//   - Each row equals the single-vector constrained row for a folded
+//     constraint, so an input outside the span's complement still
+//     agrees on that row with probability 1/p.
+//   - When using more than length-1 independent vectors the complement
+//     is trivial and only the zero vector is authorized.
+func (msk *MasterKey) ConstrainMulti(zs [][]*big.Int) (*ConstrainedKey, error) {
+	if len(zs) == 0 {
+		return nil, ErrNoConstraints
+	}
+
+	// p is the order of the eliptic curve
+	p := elliptic.P256().Params().N
+	length := msk.length
+	n := msk.n
+
+	for j := 0; j < len(zs); j++ {
+		if len(zs[j]) != length {
+			return nil, ErrLengthMismatch
+		}
+	}
+
+	csk := &ConstrainedKey{}
+	csk.n = n
+	csk.length = length
+	csk.schema = msk.schema
+	csk.epoch = msk.epoch
+	csk.z1 = make([][]*big.Int, n)
+
+	// the constraint key is computed as z0 - SUM_j z_j*Delta_{i,j}
+	tmp := big.NewInt(0)
+	for i := 0; i < n; i++ {
+		z0i := msk.row(i)
+		csk.z1[i] = make([]*big.Int, length)
+		for k := 0; k < length; k++ {
+			csk.z1[i][k] = big.NewInt(0).Set(z0i[k])
+		}
+
+		for j := 0; j < len(zs); j++ {
+			deltaij, err := generateRandomBigInt(p)
+			if err != nil {
+				return nil, fmt.Errorf("failed to generate delta_(%d,%d) for constraint: %w", i, j, err)
+			}
+
+			for k := 0; k < length; k++ {
+				tmp.Mul(deltaij, zs[j][k])
+				csk.z1[i][k].Sub(csk.z1[i][k], tmp)
+			}
+		}
+	}
+
+	return csk, nil
+}
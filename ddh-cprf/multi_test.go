@@ -0,0 +1,43 @@
+package ddhcprf
+
+import (
+	"crypto/elliptic"
+	"math/big"
+	"testing"
+
+	"github.com/sachaservan/cprf/ddh-cprf/ec"
+	"github.com/sachaservan/cprf/linalg"
+)
+
+func TestConstrainMulti(t *testing.T) {
+	p := elliptic.P256().Params().N
+	field, _ := linalg.NewField(p)
+	n := 128
+	length := 10
+
+	pp, msk, _ := KeyGen(n, length)
+
+	zs := make(linalg.Matrix, 2)
+	for i := range zs {
+		zs[i], _ = field.RandomVector(length)
+	}
+
+	csk, err := msk.ConstrainMulti([][]*big.Int{zs[0], zs[1]})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	x, _ := field.SampleKernel(zs)
+	if !ec.PointsEqual(msk.Eval(pp, x), csk.CEval(pp, x)) {
+		t.Fatalf("Eval and CEval are not equal on an input satisfying all constraints")
+	}
+
+	y, _ := field.SampleOrthogonal(zs[0])
+	if ec.PointsEqual(msk.Eval(pp, y), csk.CEval(pp, y)) {
+		t.Fatalf("Eval and CEval are equal on an input violating a constraint")
+	}
+
+	if _, err := msk.ConstrainMulti(nil); err != ErrNoConstraints {
+		t.Fatalf("expected ErrNoConstraints, got %v", err)
+	}
+}
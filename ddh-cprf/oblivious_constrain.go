@@ -0,0 +1,215 @@
+package ddhcprf
+
+import (
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/sachaservan/cprf/paillier"
+)
+
+// Oblivious constraining lets a client obtain a key constrained to z
+// without the issuer learning z. Row i of the constrained key is
+// z0_i - Delta_i*z, which is affine in z, so the issuer can compute it on
+// Paillier encryptions of z:
+//
+//  1. The client sends its Paillier public key and Enc(z_j) for each j.
+//  2. The issuer returns Enc(w_ij) with w_ij = z0_ij + Delta_i(p - z_j) + p*rho_ij
+//     for random Delta_i and random rho_ij in [0, p*2^StatisticalSecurity).
+//  3. The client decrypts and sets z1_ij = w_ij mod p = z0_ij - Delta_i*z_j mod p.
+//
+// The integer w_ij is less than the Paillier modulus N, so no wraparound
+// occurs, and rho_ij statistically hides everything about it but its
+// residue mod p. The issuer only sees ciphertexts. Privacy of the master
+// key holds against semi-honest clients: a malicious client can encrypt
+// values outside [0, p), and proofs of plaintext range would be needed to
+// prevent this.
+
+var (
+	ErrPaillierModulusTooSmall = errors.New("paillier modulus is too small")
+	ErrInvalidConstrainMessage = errors.New("invalid oblivious constrain message")
+)
+
+// StatisticalSecurity is the statistical hiding parameter in bits
+const StatisticalSecurity = 128
+
+// ConstrainRequest is the client's message
+type ConstrainRequest struct {
+	PublicKey  *paillier.PublicKey
+	EncryptedZ []*big.Int
+}
+
+// ConstrainResponse is the issuer's message with one row
+// of encrypted key components per Naor-Reingold key element
+type ConstrainResponse struct {
+	EncryptedKey [][]*big.Int
+	Schema       string
+	Epoch        uint64
+}
+
+// ConstrainClient is the client side of an oblivious Constrain.
+// A client is used once: Request, then Finish.
+type ConstrainClient struct {
+	sk   *paillier.PrivateKey
+	n    int
+	z    []*big.Int
+	sent bool
+	done bool
+}
+
+// NewConstrainClient returns a client requesting a key constrained to z
+// sk: the client's Paillier key, with a modulus of at least paillier.MinKeySize
+// and at least 2*256 + StatisticalSecurity + 2 bits
+// n: number of elements in the Naor-Reingold PRF key
+func NewConstrainClient(sk *paillier.PrivateKey, n int, z []*big.Int) (*ConstrainClient, error) {
+	if err := checkPaillierModulus(&sk.PublicKey); err != nil {
+		return nil, err
+	}
+
+	p := elliptic.P256().Params().N
+
+	c := &ConstrainClient{}
+	c.sk = sk
+	c.n = n
+	c.z = make([]*big.Int, len(z))
+	for j := range z {
+		c.z[j] = big.NewInt(0).Mod(z[j], p)
+	}
+	return c, nil
+}
+
+// Request returns the request message with the encrypted constraint
+func (c *ConstrainClient) Request() (*ConstrainRequest, error) {
+	if c.sent {
+		return nil, ErrProtocolState
+	}
+
+	req := &ConstrainRequest{}
+	req.PublicKey = &c.sk.PublicKey
+	req.EncryptedZ = make([]*big.Int, len(c.z))
+	for j := range c.z {
+		var err error
+		req.EncryptedZ[j], err = c.sk.Encrypt(rand.Reader, c.z[j])
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt constraint component %d: %w", j, err)
+		}
+	}
+
+	c.sent = true
+	return req, nil
+}
+
+// Finish decrypts the response and returns the constrained key
+func (c *ConstrainClient) Finish(resp *ConstrainResponse) (*ConstrainedKey, error) {
+	if !c.sent || c.done {
+		return nil, ErrProtocolState
+	}
+	if resp == nil || len(resp.EncryptedKey) != c.n {
+		return nil, ErrInvalidConstrainMessage
+	}
+
+	p := elliptic.P256().Params().N
+
+	csk := &ConstrainedKey{}
+	csk.n = c.n
+	csk.length = len(c.z)
+	csk.schema = resp.Schema
+	csk.epoch = resp.Epoch
+	csk.z1 = make([][]*big.Int, c.n)
+	for i := range resp.EncryptedKey {
+		if len(resp.EncryptedKey[i]) != csk.length {
+			return nil, ErrInvalidConstrainMessage
+		}
+		csk.z1[i] = make([]*big.Int, csk.length)
+		for j := range resp.EncryptedKey[i] {
+			w, err := c.sk.Decrypt(resp.EncryptedKey[i][j])
+			if err != nil {
+				return nil, ErrInvalidConstrainMessage
+			}
+			csk.z1[i][j] = w.Mod(w, p)
+		}
+	}
+
+	c.done = true
+	return csk, nil
+}
+
+// RespondConstrain answers an oblivious Constrain request with
+// encryptions of the components of a constrained key
+func (msk *MasterKey) RespondConstrain(req *ConstrainRequest) (*ConstrainResponse, error) {
+	if req == nil || req.PublicKey == nil || req.PublicKey.N == nil {
+		return nil, ErrInvalidConstrainMessage
+	}
+	if len(req.EncryptedZ) != msk.length {
+		return nil, ErrLengthMismatch
+	}
+
+	// p is the order of the eliptic curve
+	p := elliptic.P256().Params().N
+	pk := req.PublicKey
+	if err := checkPaillierModulus(pk); err != nil {
+		return nil, err
+	}
+	for j := range req.EncryptedZ {
+		if err := pk.Validate(req.EncryptedZ[j]); err != nil {
+			return nil, ErrInvalidConstrainMessage
+		}
+	}
+
+	// rho_ij is sampled from [0, p*2^StatisticalSecurity)
+	rhoBound := big.NewInt(0).Lsh(p, StatisticalSecurity)
+
+	resp := &ConstrainResponse{}
+	resp.Schema = msk.schema
+	resp.Epoch = msk.epoch
+	resp.EncryptedKey = make([][]*big.Int, msk.n)
+	for i := 0; i < msk.n; i++ {
+		z0i := msk.row(i)
+
+		deltai, err := generateRandomBigInt(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate delta_%d for constraint: %w", i, err)
+		}
+		negDelta := big.NewInt(0).Neg(deltai)
+		deltaP := big.NewInt(0).Mul(deltai, p)
+
+		resp.EncryptedKey[i] = make([]*big.Int, msk.length)
+		for j := 0; j < msk.length; j++ {
+			rho, err := generateRandomBigInt(rhoBound)
+			if err != nil {
+				return nil, fmt.Errorf("failed to generate mask for component (%d,%d): %w", i, j, err)
+			}
+
+			// m = z0_ij + Delta_i*p + p*rho_ij
+			m := big.NewInt(0).Mul(p, rho)
+			m.Add(m, deltaP)
+			m.Add(m, big.NewInt(0).Mod(z0i[j], p))
+
+			// Enc(-Delta_i*z_j + m)
+			c := pk.MulPlaintext(req.EncryptedZ[j], negDelta)
+			c = pk.AddPlaintext(c, m)
+			resp.EncryptedKey[i][j], err = pk.Rerandomize(rand.Reader, c)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return resp, nil
+}
+
+// checkPaillierModulus checks that N has at least paillier.MinKeySize bits
+// and that N > p^2 * 2^(StatisticalSecurity+2), which bounds the plaintexts
+// w_ij = z0_ij + Delta_i(p - z_j) + p*rho_ij
+func checkPaillierModulus(pk *paillier.PublicKey) error {
+	if pk.N.BitLen() < paillier.MinKeySize {
+		return ErrPaillierModulusTooSmall
+	}
+	p := elliptic.P256().Params().N
+	if pk.N.BitLen() < 2*p.BitLen()+StatisticalSecurity+2 {
+		return ErrPaillierModulusTooSmall
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package ddhcprf
+
+import (
+	"crypto/elliptic"
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	"github.com/sachaservan/cprf/ddh-cprf/ec"
+	"github.com/sachaservan/cprf/linalg"
+	"github.com/sachaservan/cprf/paillier"
+)
+
+func TestObliviousConstrain(t *testing.T) {
+	p := elliptic.P256().Params().N
+	field, _ := linalg.NewField(p)
+	n := 128
+	length := 4
+
+	pp, msk, _ := KeyGenCompact(n, length)
+	msk.SetSchema("users/v1/0011223344556677")
+
+	sk, err := paillier.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	z, _ := field.RandomVector(length)
+	client, err := NewConstrainClient(sk, n, z)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the issuer only sees the request
+	req, err := client.Request()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := msk.RespondConstrain(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	csk, err := client.Finish(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if csk.Schema() != msk.Schema() {
+		t.Fatalf("constrained key has schema %q, expected %q", csk.Schema(), msk.Schema())
+	}
+
+	for trial := 0; trial < 3; trial++ {
+		x, _ := field.SampleOrthogonal(z)
+		if !ec.PointsEqual(msk.Eval(pp, x), csk.CEval(pp, x)) {
+			t.Fatalf("Eval and CEval are not equal on an authorized input")
+		}
+
+		y, _ := field.SampleNonOrthogonal(z)
+		if ec.PointsEqual(msk.Eval(pp, y), csk.CEval(pp, y)) {
+			t.Fatalf("Eval and CEval are equal on an unauthorized input")
+		}
+	}
+}
+
+func TestObliviousConstrainErrors(t *testing.T) {
+	n := 128
+	length := 2
+
+	_, msk, _ := KeyGen(n, length)
+	z := []*big.Int{big.NewInt(1), big.NewInt(2)}
+
+	sk, _ := paillier.GenerateKey(rand.Reader, 2048)
+	client, _ := NewConstrainClient(sk, n, z)
+	if _, err := client.Finish(&ConstrainResponse{}); err != ErrProtocolState {
+		t.Fatalf("expected ErrProtocolState before the request, got %v", err)
+	}
+
+	// a 1024 bit modulus is below paillier.MinKeySize
+	req, _ := client.Request()
+	smallReq := &ConstrainRequest{PublicKey: smallPaillierKey(t), EncryptedZ: req.EncryptedZ}
+	if _, err := msk.RespondConstrain(smallReq); err != ErrPaillierModulusTooSmall {
+		t.Fatalf("expected ErrPaillierModulusTooSmall, got %v", err)
+	}
+	short := &ConstrainRequest{PublicKey: req.PublicKey, EncryptedZ: req.EncryptedZ[1:]}
+	if _, err := msk.RespondConstrain(short); err != ErrLengthMismatch {
+		t.Fatalf("expected ErrLengthMismatch, got %v", err)
+	}
+
+	if _, err := client.Finish(&ConstrainResponse{EncryptedKey: [][]*big.Int{req.EncryptedZ}}); err != ErrInvalidConstrainMessage {
+		t.Fatalf("expected ErrInvalidConstrainMessage for a short response, got %v", err)
+	}
+}
+
+// smallPaillierKey returns a public key with a 1024 bit modulus,
+// which GenerateKey no longer produces
+func smallPaillierKey(t *testing.T) *paillier.PublicKey {
+	p, err := rand.Prime(rand.Reader, 512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, err := rand.Prime(rand.Reader, 512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return paillier.NewPublicKey(new(big.Int).Mul(p, q))
+}
@@ -0,0 +1,185 @@
+package ddhcprf
+
+import (
+	"crypto/elliptic"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// Rotation replaces every row z0_i by z0_i + r_i for fresh random r_i.
+// Since a constrained key has rows z1_i = z0_i - Delta_i z, its holder can
+// move it to the new master key by adding r_i, without learning z0 or
+// anything about z. The update token carrying the r_i must therefore be
+// sent over a private channel to constrained key holders only: together
+// with the old master key it gives the new one.
+//
+// Outputs under the new key are unrelated to outputs under the old one.
+// Evaluators created before an update keep evaluating the old key.
+
+var (
+	ErrEpochMismatch       = errors.New("update token is for another epoch")
+	ErrInvalidUpdateToken  = errors.New("invalid update token")
+	ErrUpdateTokenMismatch = errors.New("update token does not match the key")
+)
+
+const updateTokenVersion = 1
+
+// UpdateToken moves constrained keys from epoch From to epoch To = From+1
+// r: offsets added to the rows of every key
+type UpdateToken struct {
+	From uint64
+	To   uint64
+	r    [][]*big.Int
+}
+
+// Epoch returns the number of times the master key has been rotated
+func (msk *MasterKey) Epoch() uint64 {
+	return msk.epoch
+}
+
+// Epoch returns the epoch of the master key the key was constrained from
+func (csk *ConstrainedKey) Epoch() uint64 {
+	return csk.epoch
+}
+
+// Rotate returns the master key of the next epoch and the token that
+// updates constrained keys to it. msk is unchanged. The new key is
+// always expanded, since its rows no longer derive from a seed.
+func (msk *MasterKey) Rotate() (*MasterKey, *UpdateToken, error) {
+
+	// p is the order of the eliptic curve
+	p := elliptic.P256().Params().N
+
+	token := &UpdateToken{}
+	token.From = msk.epoch
+	token.To = msk.epoch + 1
+	token.r = make([][]*big.Int, msk.n)
+
+	next := &MasterKey{}
+	next.n = msk.n
+	next.length = msk.length
+	next.schema = msk.schema
+	next.epoch = token.To
+	next.z0 = make([][]*big.Int, msk.n)
+
+	var err error
+	for i := 0; i < msk.n; i++ {
+		z0i := msk.row(i)
+		token.r[i] = make([]*big.Int, msk.length)
+		next.z0[i] = make([]*big.Int, msk.length)
+		for j := 0; j < msk.length; j++ {
+			token.r[i][j], err = generateRandomBigInt(p)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to generate rotation offset (%d,%d): %w", i, j, err)
+			}
+			next.z0[i][j] = big.NewInt(0).Add(z0i[j], token.r[i][j])
+			next.z0[i][j].Mod(next.z0[i][j], p)
+		}
+	}
+
+	return next, token, nil
+}
+
+// ApplyUpdate moves the constrained key to the next epoch with the
+// token of the rotation of its master key
+func (csk *ConstrainedKey) ApplyUpdate(token *UpdateToken) error {
+	if token.From != csk.epoch {
+		return ErrEpochMismatch
+	}
+	if len(token.r) != csk.n {
+		return ErrUpdateTokenMismatch
+	}
+	for _, row := range token.r {
+		if len(row) != csk.length {
+			return ErrUpdateTokenMismatch
+		}
+	}
+
+	p := elliptic.P256().Params().N
+
+	for i := 0; i < csk.n; i++ {
+		for j := 0; j < csk.length; j++ {
+			csk.z1[i][j] = big.NewInt(0).Add(csk.z1[i][j], token.r[i][j])
+			csk.z1[i][j].Mod(csk.z1[i][j], p)
+		}
+	}
+	csk.epoch = token.To
+
+	return nil
+}
+
+// MarshalBinary encodes the token as its epochs, dimensions
+// and the offsets as fixed-width integers
+func (token *UpdateToken) MarshalBinary() ([]byte, error) {
+	p := elliptic.P256().Params().N
+	elemLen := (p.BitLen() + 7) / 8
+
+	length := 0
+	if len(token.r) > 0 {
+		length = len(token.r[0])
+	}
+
+	data := []byte{updateTokenVersion}
+	data = binary.AppendUvarint(data, token.From)
+	data = binary.AppendUvarint(data, token.To)
+	data = binary.AppendUvarint(data, uint64(len(token.r)))
+	data = binary.AppendUvarint(data, uint64(length))
+
+	elem := make([]byte, elemLen)
+	for _, row := range token.r {
+		for _, v := range row {
+			data = append(data, v.FillBytes(elem)...)
+		}
+	}
+
+	return data, nil
+}
+
+// UnmarshalBinary decodes a token encoded by MarshalBinary
+func (token *UpdateToken) UnmarshalBinary(data []byte) error {
+	p := elliptic.P256().Params().N
+	elemLen := (p.BitLen() + 7) / 8
+
+	if len(data) < 1 || data[0] != updateTokenVersion {
+		return ErrInvalidUpdateToken
+	}
+	data = data[1:]
+
+	fields := make([]uint64, 4)
+	for f := range fields {
+		v, read := binary.Uvarint(data)
+		if read <= 0 {
+			return ErrInvalidUpdateToken
+		}
+		fields[f] = v
+		data = data[read:]
+	}
+	from, to, n, length := fields[0], fields[1], fields[2], fields[3]
+	if to != from+1 || n == 0 || n > MaxN || length == 0 || length > MaxLength {
+		return ErrInvalidUpdateToken
+	}
+
+	if uint64(len(data)) != n*length*uint64(elemLen) {
+		return ErrInvalidUpdateToken
+	}
+
+	r := make([][]*big.Int, n)
+	for i := range r {
+		r[i] = make([]*big.Int, length)
+		for j := range r[i] {
+			r[i][j] = big.NewInt(0).SetBytes(data[:elemLen])
+			data = data[elemLen:]
+			if r[i][j].Cmp(p) >= 0 {
+				return ErrInvalidUpdateToken
+			}
+		}
+	}
+
+	token.From = from
+	token.To = to
+	token.r = r
+
+	return nil
+}
@@ -0,0 +1,123 @@
+package ddhcprf
+
+import (
+	"crypto/elliptic"
+	"encoding/binary"
+	"math/big"
+	"testing"
+
+	"github.com/sachaservan/cprf/ddh-cprf/ec"
+)
+
+func TestRotate(t *testing.T) {
+	p := elliptic.P256().Params().N
+	n := 16
+	length := 5
+
+	for _, compact := range []bool{false, true} {
+		var pp *PublicParameters
+		var msk *MasterKey
+		if compact {
+			pp, msk, _ = KeyGenCompact(n, length)
+		} else {
+			pp, msk, _ = KeyGen(n, length)
+		}
+
+		z, _ := generateRandomVector(length, p)
+		csk, _ := msk.Constrain(z)
+
+		// x = (z_1, -z_0, 0, ...) is orthogonal to z
+		x := make([]*big.Int, length)
+		for i := range x {
+			x[i] = big.NewInt(0)
+		}
+		x[0] = big.NewInt(0).Set(z[1])
+		x[1] = big.NewInt(0).Neg(z[0])
+		x[1].Mod(x[1], p)
+		y, _ := generateRandomVector(length, p)
+
+		oldX := msk.Eval(pp, x)
+		oldY := msk.Eval(pp, y)
+
+		next, token, err := msk.Rotate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if next.Epoch() != 1 || next.IsCompact() {
+			t.Fatalf("rotated key has epoch %d and compact %v", next.Epoch(), next.IsCompact())
+		}
+		if !ec.PointsEqual(msk.Eval(pp, x), oldX) {
+			t.Fatalf("Rotate changed the old key")
+		}
+		if ec.PointsEqual(next.Eval(pp, x), oldX) || ec.PointsEqual(next.Eval(pp, y), oldY) {
+			t.Fatalf("outputs of the rotated key match the old outputs")
+		}
+
+		data, err := token.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded := &UpdateToken{}
+		if err := decoded.UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := csk.ApplyUpdate(decoded); err != nil {
+			t.Fatal(err)
+		}
+		if csk.Epoch() != 1 {
+			t.Fatalf("updated key has epoch %d", csk.Epoch())
+		}
+		if !ec.PointsEqual(next.Eval(pp, x), csk.CEval(pp, x)) {
+			t.Fatalf("Eval and CEval are not equal on an authorized input after the update")
+		}
+		if ec.PointsEqual(next.Eval(pp, y), csk.CEval(pp, y)) {
+			t.Fatalf("Eval and CEval are equal on an unauthorized input after the update")
+		}
+		if ec.PointsEqual(csk.CEval(pp, x), oldX) {
+			t.Fatalf("updated constrained key matches the old output")
+		}
+
+		if err := csk.ApplyUpdate(token); err != ErrEpochMismatch {
+			t.Fatalf("expected ErrEpochMismatch, got %v", err)
+		}
+
+		// the epoch survives serialization
+		enc, _ := next.MarshalBinary()
+		res := &MasterKey{}
+		if err := res.UnmarshalBinary(enc); err != nil {
+			t.Fatal(err)
+		}
+		if res.Epoch() != 1 || !ec.PointsEqual(res.Eval(pp, x), next.Eval(pp, x)) {
+			t.Fatalf("decoded rotated key differs")
+		}
+	}
+}
+
+func TestUpdateTokenErrors(t *testing.T) {
+	p := elliptic.P256().Params().N
+
+	_, msk, _ := KeyGen(16, 5)
+	_, other, _ := KeyGen(8, 5)
+	z, _ := generateRandomVector(5, p)
+	csk, _ := other.Constrain(z)
+
+	_, token, _ := msk.Rotate()
+	if err := csk.ApplyUpdate(token); err != ErrUpdateTokenMismatch {
+		t.Fatalf("expected ErrUpdateTokenMismatch, got %v", err)
+	}
+
+	data, _ := token.MarshalBinary()
+	if err := (&UpdateToken{}).UnmarshalBinary(data[:len(data)-1]); err != ErrInvalidUpdateToken {
+		t.Fatalf("expected ErrInvalidUpdateToken for a truncated token, got %v", err)
+	}
+
+	// dimensions whose byte length is 2^64 with no values
+	overflow := binary.AppendUvarint([]byte{updateTokenVersion}, token.From)
+	overflow = binary.AppendUvarint(overflow, token.To)
+	overflow = binary.AppendUvarint(overflow, 1<<30)
+	overflow = binary.AppendUvarint(overflow, 1<<29)
+	if err := (&UpdateToken{}).UnmarshalBinary(overflow); err != ErrInvalidUpdateToken {
+		t.Fatalf("expected ErrInvalidUpdateToken for overflowing dimensions, got %v", err)
+	}
+}
@@ -0,0 +1,19 @@
+package ddhcprf
+
+// SetSchema records the ID of the schema input vectors are encoded
+// with (see package schema). Keys constrained from msk inherit it.
+func (msk *MasterKey) SetSchema(id string) {
+	msk.schema = id
+}
+
+// Schema returns the ID of the schema the key was built for,
+// or the empty string if none was recorded
+func (msk *MasterKey) Schema() string {
+	return msk.schema
+}
+
+// Schema returns the ID of the schema the key was built for,
+// or the empty string if none was recorded
+func (csk *ConstrainedKey) Schema() string {
+	return csk.schema
+}
@@ -0,0 +1,61 @@
+package ddhcprf
+
+import (
+	"crypto/elliptic"
+	"encoding/binary"
+	"math/big"
+	"testing"
+)
+
+func TestSchemaPropagates(t *testing.T) {
+	p := elliptic.P256().Params().N
+	n := 128
+	length := 10
+
+	_, msk, _ := KeyGen(n, length)
+	msk.SetSchema("users/v1/0011223344556677")
+
+	z, _ := generateRandomVector(length, p)
+	csk, _ := msk.Constrain(z)
+	if csk.Schema() != msk.Schema() {
+		t.Fatalf("constrained key has schema %q, expected %q", csk.Schema(), msk.Schema())
+	}
+
+	csk, _ = msk.ConstrainMulti([][]*big.Int{z, z})
+	if csk.Schema() != msk.Schema() {
+		t.Fatalf("multi-constrained key has schema %q, expected %q", csk.Schema(), msk.Schema())
+	}
+}
+
+func TestSchemaMarshal(t *testing.T) {
+	n := 128
+	length := 10
+
+	_, msk, _ := KeyGenCompact(n, length)
+	msk.SetSchema("users/v1/0011223344556677")
+
+	data, err := msk.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded := &MasterKey{}
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Schema() != msk.Schema() {
+		t.Fatalf("decoded key has schema %q, expected %q", decoded.Schema(), msk.Schema())
+	}
+
+	// version 1 encodings have no schema ID
+	v1 := []byte{1, keyEncodingSeeded}
+	v1 = binary.AppendUvarint(v1, uint64(n))
+	v1 = binary.AppendUvarint(v1, uint64(length))
+	v1 = append(v1, msk.seed...)
+	if err := decoded.UnmarshalBinary(v1); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Schema() != "" || !decoded.IsCompact() {
+		t.Fatalf("version 1 encoding decoded incorrectly")
+	}
+}
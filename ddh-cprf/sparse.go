@@ -0,0 +1,73 @@
+package ddhcprf
+
+import (
+	"crypto/elliptic"
+	"errors"
+	"math/big"
+
+	"github.com/sachaservan/cprf/ddh-cprf/ec"
+)
+
+var (
+	ErrInvalidSparseIndex = errors.New("sparse indices must be strictly increasing and less than the key length")
+)
+
+// SparseEntry is a coordinate of a sparse input vector
+// Index: position of the coordinate in the dense vector
+// Value: value of the coordinate
+type SparseEntry struct {
+	Index int
+	Value *big.Int
+}
+
+// EvalSparse evaluates the CPRF on the sparse vector x, given as
+// entries sorted by index; all omitted coordinates are zero.
+// The output equals Eval on the corresponding dense vector.
+func (msk *MasterKey) EvalSparse(pp *PublicParameters, x []SparseEntry) (*ec.Point, error) {
+	return commonEvalSparse(pp, msk.n, msk.length, msk.rows(), x)
+}
+
+// CEvalSparse is the constrained key analogue of EvalSparse
+func (csk *ConstrainedKey) CEvalSparse(pp *PublicParameters, x []SparseEntry) (*ec.Point, error) {
+	return commonEvalSparse(pp, csk.n, csk.length, csk.z1, x)
+}
+
+func commonEvalSparse(
+	pp *PublicParameters,
+	n int,
+	length int,
+	zb [][]*big.Int,
+	x []SparseEntry) (*ec.Point, error) {
+
+	curve := elliptic.P256()
+	p := elliptic.P256().Params().N
+
+	// zero coordinates encode to no bytes in hashDL, so hashing
+	// the nonzero values in index order matches the dense encoding
+	values := make([]*big.Int, len(x))
+	for i := 0; i < len(x); i++ {
+		index := x[i].Index
+		if index < 0 || index >= length || (i > 0 && index <= x[i-1].Index) {
+			return nil, ErrInvalidSparseIndex
+		}
+		values[i] = x[i].Value
+	}
+
+	keys := make([]*big.Int, n)
+	keyFPs := make([]*ec.Point, n) // key fingerprint curve points
+
+	tmp := big.NewInt(0)
+	for i := 0; i < n; i++ {
+		acc := big.NewInt(0)
+
+		for j := 0; j < len(x); j++ {
+			tmp.Mul(zb[i][x[j].Index], x[j].Value)
+			acc.Add(acc, tmp).Mod(acc, p)
+		}
+
+		keys[i] = acc
+		keyFPs[i] = ec.BaseScalarMult(curve, acc)
+	}
+
+	return combineKeys(pp, n, keys, keyFPs, values), nil
+}
@@ -0,0 +1,66 @@
+package ddhcprf
+
+import (
+	"crypto/elliptic"
+	"math/big"
+	mrand "math/rand"
+	"testing"
+
+	"github.com/sachaservan/cprf/ddh-cprf/ec"
+)
+
+// generateRandomSparse returns a random sparse vector with nnz
+// entries together with its dense representation
+func generateRandomSparse(length int, nnz int, max *big.Int) ([]SparseEntry, []*big.Int) {
+	dense := make([]*big.Int, length)
+	for i := 0; i < length; i++ {
+		dense[i] = big.NewInt(0)
+	}
+
+	for _, index := range mrand.Perm(length)[:nnz] {
+		dense[index], _ = generateRandomBigInt(max)
+	}
+
+	sparse := make([]SparseEntry, 0, nnz)
+	for i := 0; i < length; i++ {
+		if dense[i].Sign() != 0 {
+			sparse = append(sparse, SparseEntry{Index: i, Value: dense[i]})
+		}
+	}
+
+	return sparse, dense
+}
+
+func TestEvalSparseMatchesDense(t *testing.T) {
+	p := elliptic.P256().Params().N
+	n := 128
+	length := 200
+
+	z, _ := generateRandomVector(length, p)
+	pp, msk, _ := KeyGen(n, length)
+	csk, _ := msk.Constrain(z)
+
+	for trial := 0; trial < 3; trial++ {
+		sparse, dense := generateRandomSparse(length, 10, p)
+
+		eval, err := msk.EvalSparse(pp, sparse)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ec.PointsEqual(eval, msk.Eval(pp, dense)) {
+			t.Fatalf("EvalSparse and Eval are not equal")
+		}
+
+		ceval, err := csk.CEvalSparse(pp, sparse)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ec.PointsEqual(ceval, csk.CEval(pp, dense)) {
+			t.Fatalf("CEvalSparse and CEval are not equal")
+		}
+	}
+
+	if _, err := msk.EvalSparse(pp, []SparseEntry{{Index: length, Value: big.NewInt(1)}}); err != ErrInvalidSparseIndex {
+		t.Fatalf("expected ErrInvalidSparseIndex, got %v", err)
+	}
+}
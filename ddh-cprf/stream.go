@@ -0,0 +1,78 @@
+package ddhcprf
+
+import (
+	"crypto/elliptic"
+	"errors"
+	"io"
+	"math/big"
+
+	"github.com/sachaservan/cprf/ddh-cprf/ec"
+	"github.com/sachaservan/cprf/internal/coords"
+)
+
+var (
+	ErrTruncatedStream = coords.ErrTruncatedStream
+	ErrCoordinateCount = coords.ErrCoordinateCount
+	ErrInvalidWidth    = errors.New("coordinate width must be positive")
+)
+
+// EvalReader evaluates the CPRF on an input vector read from r.
+// The stream must contain exactly length coordinates, each encoded
+// as an unsigned big-endian integer of width bytes.
+// The output equals Eval on the same vector.
+//
+// The stream is read once: each coordinate updates the n inner
+// products and is absorbed into the DL hash, so memory use does
+// not depend on the length of the vector.
+func (msk *MasterKey) EvalReader(pp *PublicParameters, r io.Reader, width int) (*ec.Point, error) {
+	return commonEvalReader(pp, msk.n, msk.length, msk.rows(), r, width)
+}
+
+// CEvalReader is the constrained key analogue of EvalReader
+func (csk *ConstrainedKey) CEvalReader(pp *PublicParameters, r io.Reader, width int) (*ec.Point, error) {
+	return commonEvalReader(pp, csk.n, csk.length, csk.z1, r, width)
+}
+
+func commonEvalReader(
+	pp *PublicParameters,
+	n int,
+	length int,
+	zb [][]*big.Int,
+	r io.Reader,
+	width int) (*ec.Point, error) {
+
+	if width <= 0 {
+		return nil, ErrInvalidWidth
+	}
+
+	curve := elliptic.P256()
+	p := elliptic.P256().Params().N
+
+	keys := make([]*big.Int, n)
+	for i := 0; i < n; i++ {
+		keys[i] = big.NewInt(0)
+	}
+
+	h := newDLHasher(pp)
+
+	tmp := big.NewInt(0)
+	xj := big.NewInt(0)
+	err := coords.Read(r, width, length, func(j int, coord []byte) {
+		xj.SetBytes(coord)
+		for i := 0; i < n; i++ {
+			tmp.Mul(zb[i][j], xj)
+			keys[i].Add(keys[i], tmp).Mod(keys[i], p)
+		}
+		h.Write(coords.TrimLeadingZeros(coord))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// key fingerprints come after the input in the hash
+	for i := 0; i < n; i++ {
+		h.Write(ec.BaseScalarMult(curve, keys[i]).MarshalCompressed())
+	}
+
+	return naorReingold(n, keys, h.sum()[:n]), nil
+}
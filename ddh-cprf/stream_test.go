@@ -0,0 +1,76 @@
+package ddhcprf
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/sachaservan/cprf/ddh-cprf/ec"
+)
+
+// encodeVector encodes x as fixed-width big-endian coordinates
+func encodeVector(x []*big.Int, width int) []byte {
+	buf := make([]byte, 0, len(x)*width)
+	for i := 0; i < len(x); i++ {
+		buf = append(buf, x[i].FillBytes(make([]byte, width))...)
+	}
+	return buf
+}
+
+func TestEvalReaderMatchesEval(t *testing.T) {
+	p := elliptic.P256().Params().N
+	n := 128
+	width := 32
+
+	for _, length := range []int{1, 7, 25} {
+		z, _ := generateRandomVector(length, p)
+		pp, msk, _ := KeyGen(n, length)
+		csk, _ := msk.Constrain(z)
+
+		x, _ := generateRandomVector(length, p)
+		x[0] = big.NewInt(255)
+		data := encodeVector(x, width)
+
+		eval, err := msk.EvalReader(pp, bytes.NewReader(data), width)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ec.PointsEqual(eval, msk.Eval(pp, x)) {
+			t.Fatalf("EvalReader and Eval are not equal for length %d", length)
+		}
+
+		ceval, err := csk.CEvalReader(pp, bytes.NewReader(data), width)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ec.PointsEqual(ceval, csk.CEval(pp, x)) {
+			t.Fatalf("CEvalReader and CEval are not equal for length %d", length)
+		}
+	}
+}
+
+func TestEvalReaderErrors(t *testing.T) {
+	p := elliptic.P256().Params().N
+	n := 128
+	width := 32
+	length := 5
+
+	pp, msk, _ := KeyGen(n, length)
+	x, _ := generateRandomVector(length+1, p)
+	data := encodeVector(x, width)
+
+	for _, tc := range []struct {
+		data []byte
+		err  error
+	}{
+		{data[:length*width-1], ErrTruncatedStream},
+		{data[:(length-1)*width], ErrCoordinateCount},
+		{data, ErrCoordinateCount},
+	} {
+		if _, err := msk.EvalReader(pp, bytes.NewReader(tc.data), width); !errors.Is(err, tc.err) {
+			t.Fatalf("expected %v, got %v", tc.err, err)
+		}
+	}
+}
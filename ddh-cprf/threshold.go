@@ -0,0 +1,568 @@
+package ddhcprf
+
+import (
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/sachaservan/cprf/ddh-cprf/ec"
+)
+
+// A threshold key splits every row of z0 into additive shares
+// z0_i = SUM_k share_ki (mod p) held by N parties, so that no single host
+// ever holds z0. Each party computes its part a_ki of the Naor-Reingold keys
+// k_i = <z0_i,x> = SUM_k a_ki, masked with pseudorandom values that sum to
+// zero over all parties and are derived from seeds shared pairwise during
+// key generation. Evaluation is a protocol between the parties and a
+// combiner that opens only group elements:
+//
+//  1. Every party sends the fingerprints g^a_ki. Their products are the
+//     fingerprints g^k_i, from which the combiner hashes the selected rows.
+//  2. Starting from Y = g, for each selected row i in turn, every party
+//     sends Y^a_ki and the combiner sets Y to their product Y^k_i.
+//
+// The final Y is the output. Constrain is non-interactive: every party
+// sends share_i - Delta_ki z plus masks and the combiner adds them up.
+// The protocols are secure against a semi-honest combiner and parties;
+// a party exponentiates any element it is sent.
+
+var (
+	ErrPartyIndex          = errors.New("party index out of range")
+	ErrSetupIncomplete     = errors.New("pairwise seeds have not been received from all parties")
+	ErrDuplicateShare      = errors.New("duplicate message from party")
+	ErrMissingShares       = errors.New("a message from every party is required")
+	ErrParameterMismatch   = errors.New("messages were produced with different parameters")
+	ErrInvalidPairwiseSeed = errors.New("pairwise seed must be PairwiseSeedSize bytes")
+)
+
+// PairwiseSeedSize is the size in bytes of the seeds shared by pairs of parties
+const PairwiseSeedSize = 32
+
+// Party holds one share of a threshold master key
+// index: index of the party in 0, ..., parties-1
+// parties: number of parties
+// share: additive shares of the rows of z0
+// seeds: seeds shared with each other party (nil at the own index)
+type Party struct {
+	index   int
+	parties int
+	n       int
+	length  int
+	share   [][]*big.Int
+	seeds   [][]byte
+}
+
+// SeedMessage carries the seed a party shares with another party.
+// It must be sent over a private channel.
+type SeedMessage struct {
+	From int
+	To   int
+	Seed []byte
+}
+
+// KeyFingerprints is a party's first evaluation message: the
+// fingerprints g^a_ki of its masked parts of the keys
+type KeyFingerprints struct {
+	Index   int
+	Parties int
+	FPs     []*ec.Point
+}
+
+// ExponentRequest asks every party to raise Base to its part of key Row
+type ExponentRequest struct {
+	Row  int
+	Base *ec.Point
+}
+
+// ExponentResponse is a party's answer Base^a_ki to an ExponentRequest
+type ExponentResponse struct {
+	Index int
+	Row   int
+	Value *ec.Point
+}
+
+// PartialConstrainedKey is a party's masked part of the constrained key
+type PartialConstrainedKey struct {
+	Index   int
+	Parties int
+	Z1      [][]*big.Int
+}
+
+// EvalSession holds a party's masked parts of the keys for one input
+type EvalSession struct {
+	index int
+	parts []*big.Int
+}
+
+// EvalCombiner runs the evaluation protocol with the parties
+type EvalCombiner struct {
+	pp       *PublicParameters
+	n        int
+	parties  int
+	x        []*big.Int
+	keyFPs   []*ec.Point
+	received []bool
+	rows     []int // selected rows, set once all fingerprints are in
+	pos      int   // position of the current row in rows
+	acc      *ec.Point
+	y        *ec.Point
+}
+
+// NewParty samples the shares of party index of a threshold key and
+// the seeds it shares with the parties of higher index. The returned
+// messages must be delivered to those parties with Receive.
+// n: number of elements in the Naor-Reingold PRF key
+// length: length of the inner product
+func NewParty(n int, length int, index int, parties int) (*Party, []*SeedMessage, error) {
+	if parties < 1 || index < 0 || index >= parties {
+		return nil, nil, ErrPartyIndex
+	}
+
+	// p is the order of the eliptic curve
+	p := elliptic.P256().Params().N
+
+	party := &Party{}
+	party.index = index
+	party.parties = parties
+	party.n = n
+	party.length = length
+	party.share = make([][]*big.Int, n)
+	party.seeds = make([][]byte, parties)
+
+	var err error
+	for i := 0; i < n; i++ {
+		party.share[i] = make([]*big.Int, length)
+		for j := 0; j < length; j++ {
+			party.share[i][j], err = generateRandomBigInt(p)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to generate key share component (%d,%d): %w", i, j, err)
+			}
+		}
+	}
+
+	msgs := make([]*SeedMessage, 0, parties-index-1)
+	for j := index + 1; j < parties; j++ {
+		seed := make([]byte, PairwiseSeedSize)
+		if _, err := io.ReadFull(rand.Reader, seed); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate pairwise seed: %w", err)
+		}
+		party.seeds[j] = seed
+
+		msg := &SeedMessage{}
+		msg.From = index
+		msg.To = j
+		msg.Seed = append([]byte(nil), seed...)
+		msgs = append(msgs, msg)
+	}
+
+	return party, msgs, nil
+}
+
+// KeyGenThreshold runs the key generation of all parties in process
+// and delivers the seed messages between them
+// Outputs public parameters and the parties
+func KeyGenThreshold(n int, length int, parties int) (*PublicParameters, []*Party, error) {
+	res := make([]*Party, parties)
+	var msgs []*SeedMessage
+	for k := 0; k < parties; k++ {
+		party, out, err := NewParty(n, length, k, parties)
+		if err != nil {
+			return nil, nil, err
+		}
+		res[k] = party
+		msgs = append(msgs, out...)
+	}
+
+	for _, msg := range msgs {
+		if err := res[msg.To].Receive(msg); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return newPublicParameters(n, length), res, nil
+}
+
+// Index returns the index of the party
+func (party *Party) Index() int {
+	return party.index
+}
+
+// Receive stores the seed sent by a party of lower index
+func (party *Party) Receive(msg *SeedMessage) error {
+	if msg.To != party.index || msg.From < 0 || msg.From >= party.index {
+		return ErrPartyIndex
+	}
+	if len(msg.Seed) != PairwiseSeedSize {
+		return ErrInvalidPairwiseSeed
+	}
+	if party.seeds[msg.From] != nil {
+		return ErrDuplicateShare
+	}
+
+	party.seeds[msg.From] = append([]byte(nil), msg.Seed...)
+	return nil
+}
+
+// Ready returns true once the party shares a seed with every other party
+func (party *Party) Ready() bool {
+	for j, seed := range party.seeds {
+		if j != party.index && seed == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// NewEvalSession computes the party's masked parts a_ki = <share_i,x> + mask_i(x)
+// of the keys for x and returns the session and the party's fingerprints
+func (party *Party) NewEvalSession(x []*big.Int) (*EvalSession, *KeyFingerprints, error) {
+	if !party.Ready() {
+		return nil, nil, ErrSetupIncomplete
+	}
+	if len(x) != party.length {
+		return nil, nil, ErrLengthMismatch
+	}
+
+	curve := elliptic.P256()
+	p := curve.Params().N
+
+	masks := party.masks("eval", encodeMaskContext(x, p), party.n)
+
+	session := &EvalSession{}
+	session.index = party.index
+	session.parts = make([]*big.Int, party.n)
+
+	msg := &KeyFingerprints{}
+	msg.Index = party.index
+	msg.Parties = party.parties
+	msg.FPs = make([]*ec.Point, party.n)
+
+	tmp := big.NewInt(0)
+	for i := 0; i < party.n; i++ {
+		acc := big.NewInt(0).Set(masks[i])
+		for j := 0; j < party.length; j++ {
+			tmp.Mul(party.share[i][j], x[j])
+			acc.Add(acc, tmp).Mod(acc, p)
+		}
+		session.parts[i] = acc
+		msg.FPs[i] = ec.BaseScalarMult(curve, acc)
+	}
+
+	return session, msg, nil
+}
+
+// Exponentiate answers an exponentiation request of the combiner
+func (session *EvalSession) Exponentiate(req *ExponentRequest) (*ExponentResponse, error) {
+	if req.Row < 0 || req.Row >= len(session.parts) || !validPoint(req.Base) {
+		return nil, ErrInvalidMessage
+	}
+
+	resp := &ExponentResponse{}
+	resp.Index = session.index
+	resp.Row = req.Row
+	resp.Value = ec.PointScalarMult(elliptic.P256(), req.Base, session.parts[req.Row])
+
+	return resp, nil
+}
+
+// NewEvalCombiner returns a combiner for evaluating x with the given number of parties
+func NewEvalCombiner(pp *PublicParameters, n int, parties int, x []*big.Int) *EvalCombiner {
+	c := &EvalCombiner{}
+	c.pp = pp
+	c.n = n
+	c.parties = parties
+	c.x = x
+	c.keyFPs = make([]*ec.Point, n)
+	c.received = make([]bool, parties)
+	return c
+}
+
+// AddFingerprints adds the fingerprints of a party. Once every
+// party's fingerprints are in, the combiner selects the rows.
+func (c *EvalCombiner) AddFingerprints(msg *KeyFingerprints) error {
+	if c.rows != nil {
+		return ErrProtocolState
+	}
+	if msg.Parties != c.parties || len(msg.FPs) != c.n {
+		return ErrParameterMismatch
+	}
+	if msg.Index < 0 || msg.Index >= c.parties {
+		return ErrPartyIndex
+	}
+	if c.received[msg.Index] {
+		return ErrDuplicateShare
+	}
+	for _, fp := range msg.FPs {
+		if !validPoint(fp) {
+			return ErrInvalidMessage
+		}
+	}
+
+	curve := elliptic.P256()
+	for i, fp := range msg.FPs {
+		if c.keyFPs[i] == nil {
+			c.keyFPs[i] = fp
+		} else {
+			c.keyFPs[i] = ec.PointAdd(curve, c.keyFPs[i], fp)
+		}
+	}
+	c.received[msg.Index] = true
+
+	if !c.receivedAll() {
+		return nil
+	}
+
+	// rows 0 and 1 are always selected as the input is prefixed by 11
+	bits := hashDL(c.pp, c.x, c.keyFPs)[:c.n]
+	c.rows = []int{0, 1}
+	for i := 2; i < c.n; i++ {
+		if bits[i] {
+			c.rows = append(c.rows, i)
+		}
+	}
+	c.y = ec.BaseScalarMult(curve, big.NewInt(1))
+	c.resetRound()
+
+	return nil
+}
+
+// Next returns the exponentiation request of the current round,
+// or nil once the output is available
+func (c *EvalCombiner) Next() (*ExponentRequest, error) {
+	if c.rows == nil {
+		return nil, ErrProtocolState
+	}
+	if c.pos == len(c.rows) {
+		return nil, nil
+	}
+
+	req := &ExponentRequest{}
+	req.Row = c.rows[c.pos]
+	req.Base = c.y
+	return req, nil
+}
+
+// AddResponse adds a party's response to the current request.
+// Once every party has responded, the combiner moves to the next row.
+func (c *EvalCombiner) AddResponse(resp *ExponentResponse) error {
+	if c.rows == nil || c.pos == len(c.rows) || resp.Row != c.rows[c.pos] {
+		return ErrProtocolState
+	}
+	if resp.Index < 0 || resp.Index >= c.parties {
+		return ErrPartyIndex
+	}
+	if c.received[resp.Index] {
+		return ErrDuplicateShare
+	}
+	if !validPoint(resp.Value) {
+		return ErrInvalidMessage
+	}
+
+	if c.acc == nil {
+		c.acc = resp.Value
+	} else {
+		c.acc = ec.PointAdd(elliptic.P256(), c.acc, resp.Value)
+	}
+	c.received[resp.Index] = true
+
+	if c.receivedAll() {
+		c.y = c.acc
+		c.pos++
+		c.resetRound()
+	}
+
+	return nil
+}
+
+// Output returns Eval(x) once all rounds are complete
+func (c *EvalCombiner) Output() (*ec.Point, error) {
+	if c.rows == nil || c.pos != len(c.rows) {
+		return nil, ErrProtocolState
+	}
+	return c.y, nil
+}
+
+func (c *EvalCombiner) receivedAll() bool {
+	for _, ok := range c.received {
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *EvalCombiner) resetRound() {
+	c.acc = nil
+	for k := range c.received {
+		c.received[k] = false
+	}
+}
+
+// PartialConstrain returns the masked part share_i - Delta_ki z + mask_i of
+// every row of the constrained key for fresh random shares Delta_ki of Delta_i.
+// All parties must use the same session, which must not be reused for another key.
+func (party *Party) PartialConstrain(session []byte, z []*big.Int) (*PartialConstrainedKey, error) {
+	if !party.Ready() {
+		return nil, ErrSetupIncomplete
+	}
+	if len(z) != party.length {
+		return nil, ErrLengthMismatch
+	}
+
+	p := elliptic.P256().Params().N
+
+	context := append(binary.AppendUvarint(nil, uint64(len(session))), session...)
+	context = append(context, encodeMaskContext(z, p)...)
+	masks := party.masks("constrain", context, party.n*party.length)
+
+	res := &PartialConstrainedKey{}
+	res.Index = party.index
+	res.Parties = party.parties
+	res.Z1 = make([][]*big.Int, party.n)
+	for i := 0; i < party.n; i++ {
+		delta, err := generateRandomBigInt(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate delta_%d share for constraint: %w", i, err)
+		}
+
+		res.Z1[i] = make([]*big.Int, party.length)
+		for j := 0; j < party.length; j++ {
+			res.Z1[i][j] = big.NewInt(0).Mul(delta, z[j])
+			res.Z1[i][j].Sub(party.share[i][j], res.Z1[i][j])
+			res.Z1[i][j].Add(res.Z1[i][j], masks[i*party.length+j]).Mod(res.Z1[i][j], p)
+		}
+	}
+
+	return res, nil
+}
+
+// CombineConstrain combines the partial constrained keys of all parties
+// into the constrained key with rows z0_i - Delta_i z for Delta_i = SUM_k Delta_ki
+func CombineConstrain(partials []*PartialConstrainedKey) (*ConstrainedKey, error) {
+	if len(partials) == 0 {
+		return nil, ErrMissingShares
+	}
+
+	p := elliptic.P256().Params().N
+
+	n := len(partials[0].Z1)
+	if n == 0 {
+		return nil, ErrParameterMismatch
+	}
+	length := len(partials[0].Z1[0])
+
+	seen := make([]bool, len(partials))
+	for _, partial := range partials {
+		if partial.Parties != len(partials) {
+			return nil, ErrMissingShares
+		}
+		if partial.Index < 0 || partial.Index >= len(partials) {
+			return nil, ErrPartyIndex
+		}
+		if seen[partial.Index] {
+			return nil, ErrDuplicateShare
+		}
+		seen[partial.Index] = true
+
+		if len(partial.Z1) != n {
+			return nil, ErrParameterMismatch
+		}
+		for _, row := range partial.Z1 {
+			if len(row) != length {
+				return nil, ErrParameterMismatch
+			}
+		}
+	}
+
+	csk := &ConstrainedKey{}
+	csk.n = n
+	csk.length = length
+	csk.z1 = make([][]*big.Int, n)
+	for i := 0; i < n; i++ {
+		csk.z1[i] = make([]*big.Int, length)
+		for j := 0; j < length; j++ {
+			csk.z1[i][j] = big.NewInt(0)
+			for _, partial := range partials {
+				csk.z1[i][j].Add(csk.z1[i][j], partial.Z1[i][j]).Mod(csk.z1[i][j], p)
+			}
+		}
+	}
+
+	return csk, nil
+}
+
+// masks returns count masks mod p for the context that sum to zero over
+// all parties: the mask of party k is SUM_j +-PRF(seed_kj, context), with
+// a plus sign if k < j and a minus sign otherwise
+func (party *Party) masks(label string, context []byte, count int) []*big.Int {
+	p := elliptic.P256().Params().N
+
+	res := make([]*big.Int, count)
+	for e := range res {
+		res[e] = big.NewInt(0)
+	}
+
+	for j, seed := range party.seeds {
+		if j == party.index {
+			continue
+		}
+		for e, m := range expandMasks(seed, label, context, count, p) {
+			if party.index < j {
+				res[e].Add(res[e], m)
+			} else {
+				res[e].Sub(res[e], m)
+			}
+			res[e].Mod(res[e], p)
+		}
+	}
+
+	return res
+}
+
+// expandMasks derives count elements mod modulus from the seed with
+// HMAC-SHA256, using 128 extra bits per element so that the reduction
+// mod modulus is statistically close to uniform
+func expandMasks(seed []byte, label string, context []byte, count int, modulus *big.Int) []*big.Int {
+	blocks := (modulus.BitLen() + 128 + 255) / 256
+
+	mac := hmac.New(sha256.New, seed)
+	mac.Write([]byte(label))
+	mac.Write(binary.AppendUvarint(nil, uint64(len(context))))
+	mac.Write(context)
+	prefix := mac.Sum(nil)
+
+	res := make([]*big.Int, count)
+	counter := make([]byte, 8)
+	for e := 0; e < count; e++ {
+		buf := make([]byte, 0, blocks*sha256.Size)
+		for b := 0; b < blocks; b++ {
+			binary.BigEndian.PutUint64(counter, uint64(e*blocks+b))
+			mac := hmac.New(sha256.New, prefix)
+			mac.Write(counter)
+			buf = mac.Sum(buf)
+		}
+		res[e] = big.NewInt(0).SetBytes(buf)
+		res[e].Mod(res[e], modulus)
+	}
+
+	return res
+}
+
+// encodeMaskContext encodes a vector unambiguously after reducing each
+// coordinate mod p, so that inputs with the same residues get the same
+// masks and inputs with different residues (such as x and -x) do not
+func encodeMaskContext(v []*big.Int, p *big.Int) []byte {
+	data := binary.AppendUvarint(nil, uint64(len(v)))
+	for _, vi := range v {
+		b := big.NewInt(0).Mod(vi, p).Bytes()
+		data = binary.AppendUvarint(data, uint64(len(b)))
+		data = append(data, b...)
+	}
+	return data
+}
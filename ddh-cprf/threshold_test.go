@@ -0,0 +1,228 @@
+package ddhcprf
+
+import (
+	"crypto/elliptic"
+	"math/big"
+	"testing"
+
+	"github.com/sachaservan/cprf/ddh-cprf/ec"
+)
+
+// combinedMasterKey returns the master key the parties jointly hold
+func combinedMasterKey(parties []*Party) *MasterKey {
+	p := elliptic.P256().Params().N
+	n := parties[0].n
+	length := parties[0].length
+
+	msk := &MasterKey{}
+	msk.n = n
+	msk.length = length
+	msk.z0 = make([][]*big.Int, n)
+	for i := 0; i < n; i++ {
+		msk.z0[i] = make([]*big.Int, length)
+		for j := 0; j < length; j++ {
+			msk.z0[i][j] = big.NewInt(0)
+			for _, party := range parties {
+				msk.z0[i][j].Add(msk.z0[i][j], party.share[i][j]).Mod(msk.z0[i][j], p)
+			}
+		}
+	}
+	return msk
+}
+
+// thresholdEval runs the evaluation protocol between the parties and a combiner in process
+func thresholdEval(t *testing.T, pp *PublicParameters, parties []*Party, x []*big.Int) *ec.Point {
+	n := parties[0].n
+	c := NewEvalCombiner(pp, n, len(parties), x)
+
+	sessions := make([]*EvalSession, len(parties))
+	for k := len(parties) - 1; k >= 0; k-- {
+		session, msg, err := parties[k].NewEvalSession(x)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sessions[k] = session
+		if err := c.AddFingerprints(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for {
+		req, err := c.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if req == nil {
+			break
+		}
+		for _, session := range sessions {
+			resp, err := session.Exponentiate(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := c.AddResponse(resp); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	y, err := c.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return y
+}
+
+func TestThresholdEval(t *testing.T) {
+	p := elliptic.P256().Params().N
+	n := 16
+	length := 5
+
+	for _, numParties := range []int{1, 3} {
+		pp, parties, err := KeyGenThreshold(n, length, numParties)
+		if err != nil {
+			t.Fatal(err)
+		}
+		msk := combinedMasterKey(parties)
+
+		for trial := 0; trial < 3; trial++ {
+			x, _ := generateRandomVector(length, p)
+			if !ec.PointsEqual(thresholdEval(t, pp, parties, x), msk.Eval(pp, x)) {
+				t.Fatalf("threshold Eval and Eval are not equal for %d parties", numParties)
+			}
+		}
+	}
+}
+
+func TestThresholdMaskingNegatedInput(t *testing.T) {
+	p := elliptic.P256().Params().N
+	n := 16
+	length := 5
+
+	_, parties, _ := KeyGenThreshold(n, length, 3)
+	party := parties[1]
+
+	x, _ := generateRandomVector(length, p)
+	neg := make([]*big.Int, length)
+	shifted := make([]*big.Int, length)
+	for j := range x {
+		neg[j] = big.NewInt(0).Neg(x[j])
+		shifted[j] = big.NewInt(0).Add(x[j], p)
+	}
+
+	sx, _, _ := party.NewEvalSession(x)
+	sneg, _, _ := party.NewEvalSession(neg)
+	sshifted, _, _ := party.NewEvalSession(shifted)
+	masks := party.masks("eval", encodeMaskContext(x, p), n)
+
+	for i := 0; i < n; i++ {
+		// with the same mask on x and -x the sum of the parts would be
+		// twice the mask, which opens <share_i, x>
+		sum := big.NewInt(0).Add(sx.parts[i], sneg.parts[i])
+		sum.Mod(sum, p)
+		if sum.Cmp(big.NewInt(0).Mod(big.NewInt(0).Lsh(masks[i], 1), p)) == 0 {
+			t.Fatalf("parts %d of x and -x use the same mask", i)
+		}
+
+		// inputs with the same residues are the same input
+		if sshifted.parts[i].Cmp(sx.parts[i]) != 0 {
+			t.Fatalf("parts %d of x and x+p differ", i)
+		}
+	}
+}
+
+func TestThresholdConstrain(t *testing.T) {
+	p := elliptic.P256().Params().N
+	n := 16
+	length := 5
+
+	pp, parties, _ := KeyGenThreshold(n, length, 3)
+	msk := combinedMasterKey(parties)
+
+	z, _ := generateRandomVector(length, p)
+	partials := make([]*PartialConstrainedKey, len(parties))
+	for k, party := range parties {
+		var err error
+		partials[k], err = party.PartialConstrain([]byte("session 1"), z)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	csk, err := CombineConstrain(partials)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// x = (z_1, -z_0, 0, ...) is orthogonal to z
+	x := make([]*big.Int, length)
+	for i := range x {
+		x[i] = big.NewInt(0)
+	}
+	x[0] = big.NewInt(0).Set(z[1])
+	x[1] = big.NewInt(0).Neg(z[0])
+	x[1].Mod(x[1], p)
+
+	if !ec.PointsEqual(thresholdEval(t, pp, parties, x), csk.CEval(pp, x)) {
+		t.Fatalf("threshold Eval and CEval are not equal on an authorized input")
+	}
+
+	y, _ := generateRandomVector(length, p)
+	if ec.PointsEqual(thresholdEval(t, pp, parties, y), csk.CEval(pp, y)) {
+		t.Fatalf("threshold Eval and CEval are equal on an unauthorized input")
+	}
+	if !ec.PointsEqual(thresholdEval(t, pp, parties, y), msk.Eval(pp, y)) {
+		t.Fatalf("threshold Eval and Eval are not equal")
+	}
+}
+
+func TestThresholdCombinerErrors(t *testing.T) {
+	p := elliptic.P256().Params().N
+	n := 8
+	length := 4
+
+	pp, parties, _ := KeyGenThreshold(n, length, 2)
+	x, _ := generateRandomVector(length, p)
+	c := NewEvalCombiner(pp, n, len(parties), x)
+
+	if _, err := c.Next(); err != ErrProtocolState {
+		t.Fatalf("expected ErrProtocolState before the fingerprints, got %v", err)
+	}
+
+	session, msg, _ := parties[0].NewEvalSession(x)
+	if err := c.AddFingerprints(msg); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddFingerprints(msg); err != ErrDuplicateShare {
+		t.Fatalf("expected ErrDuplicateShare, got %v", err)
+	}
+	if _, err := c.Output(); err != ErrProtocolState {
+		t.Fatalf("expected ErrProtocolState before the last round, got %v", err)
+	}
+
+	_, msg, _ = parties[1].NewEvalSession(x)
+	c.AddFingerprints(msg)
+
+	req, _ := c.Next()
+	resp, _ := session.Exponentiate(req)
+	resp.Row++
+	if err := c.AddResponse(resp); err != ErrProtocolState {
+		t.Fatalf("expected ErrProtocolState for a response to another row, got %v", err)
+	}
+
+	req.Row = n
+	if _, err := session.Exponentiate(req); err != ErrInvalidMessage {
+		t.Fatalf("expected ErrInvalidMessage for a row out of range, got %v", err)
+	}
+
+	// parties that have not received all seeds
+	party, _, _ := NewParty(n, length, 1, 2)
+	if _, _, err := party.NewEvalSession(x); err != ErrSetupIncomplete {
+		t.Fatalf("expected ErrSetupIncomplete, got %v", err)
+	}
+
+	partial, _ := parties[0].PartialConstrain([]byte("session"), x)
+	if _, err := CombineConstrain([]*PartialConstrainedKey{partial}); err != ErrMissingShares {
+		t.Fatalf("expected ErrMissingShares, got %v", err)
+	}
+}
@@ -0,0 +1,296 @@
+package ddhcprf
+
+import (
+	"crypto/elliptic"
+	"encoding/binary"
+	"errors"
+	"math/big"
+
+	"github.com/sachaservan/cprf/ddh-cprf/ec"
+)
+
+var (
+	ErrInvalidProof         = errors.New("evaluation proof is invalid")
+	ErrInvalidCommitment    = errors.New("invalid key commitment")
+	ErrInvalidProofEncoding = errors.New("invalid evaluation proof encoding")
+)
+
+// KeyCommitment commits to a master key z0 with the group elements
+// C_ij = g^{z0_ij}. From the commitment anyone can compute the key
+// fingerprints g^{<z0_i,x>} = PROD_j C_ij^{x_j} of any input x.
+// The commitment determines the master key outputs, but computing
+// them from the fingerprints alone requires solving CDH.
+//
+// Publishing the commitment gives up constraint hiding: the holder of a
+// constrained key z1_i = z0_i - Delta_i z can compute g^{z0_ij - z1_ij} =
+// g^{Delta_i z_j} and test whether a guess of z is a multiple of the
+// vector of exponents. Only publish it when the constraints may be known.
+type KeyCommitment struct {
+	n      int
+	length int
+	points [][]*ec.Point
+}
+
+// EvalProof proves that an output is the evaluation of the committed
+// master key. The output g^{k_{s_1} ... k_{s_m}} is computed as a chain
+// P_1 = g^{k_{s_1}}, P_j = P_{j-1}^{k_{s_j}} over the keys selected by the
+// hash of the input, and each step carries a Chaum-Pedersen proof that
+// log_g K_{s_j} = log_{P_{j-1}} P_j for the key fingerprint K_{s_j}.
+// intermediates: the points P_2, ..., P_{m-1}
+// steps: the proofs for P_2, ..., P_m
+type EvalProof struct {
+	intermediates []*ec.Point
+	steps         []*ec.DLEQProof
+}
+
+// Commit returns the commitment to the master key
+func (msk *MasterKey) Commit() *KeyCommitment {
+
+	curve := elliptic.P256()
+
+	com := &KeyCommitment{}
+	com.n = msk.n
+	com.length = msk.length
+	com.points = make([][]*ec.Point, msk.n)
+	for i := 0; i < msk.n; i++ {
+		row := msk.row(i)
+		com.points[i] = make([]*ec.Point, msk.length)
+		for j := 0; j < msk.length; j++ {
+			com.points[i][j] = ec.BaseScalarMult(curve, row[j])
+		}
+	}
+
+	return com
+}
+
+// EvalWithProof evaluates the CPRF on x and returns the output together
+// with a proof that it is correct with respect to msk.Commit()
+func (msk *MasterKey) EvalWithProof(pp *PublicParameters, x []*big.Int) (*ec.Point, *EvalProof, error) {
+	if len(x) != msk.length {
+		return nil, nil, ErrLengthMismatch
+	}
+
+	curve := elliptic.P256()
+	n := msk.n
+
+	keys, keyFPs := innerProductKeys(n, msk.length, msk.rows(), x)
+
+	selected := selectKeys(n, hashDL(pp, x, keyFPs))
+
+	g := ec.BaseScalarMult(curve, big.NewInt(1))
+	t := evalTranscript(x)
+	proof := &EvalProof{}
+	prev := keyFPs[selected[0]]
+	for j := 1; j < len(selected); j++ {
+		k := keys[selected[j]]
+		next := ec.PointScalarMult(curve, prev, k)
+
+		step, err := ec.ProveDLEQ(t, g, keyFPs[selected[j]], prev, next, k)
+		if err != nil {
+			return nil, nil, err
+		}
+		proof.steps = append(proof.steps, step)
+		if j < len(selected)-1 {
+			proof.intermediates = append(proof.intermediates, next)
+		}
+		prev = next
+	}
+
+	return prev, proof, nil
+}
+
+// VerifyEval checks that y is the output of the master key committed
+// to by com on input x. It returns ErrInvalidProof if it is not.
+func VerifyEval(pp *PublicParameters, com *KeyCommitment, x []*big.Int, y *ec.Point, proof *EvalProof) error {
+	if com == nil || proof == nil || len(com.points) != com.n || com.n < 2 {
+		return ErrInvalidProof
+	}
+	if len(x) != com.length {
+		return ErrLengthMismatch
+	}
+	if !validPoint(y) {
+		return ErrInvalidProof
+	}
+
+	curve := elliptic.P256()
+	n := com.n
+
+	p := curve.Params().N
+
+	// PointScalarMult ignores the sign of the scalar
+	xp := make([]*big.Int, len(x))
+	for j := range x {
+		xp[j] = big.NewInt(0).Mod(x[j], p)
+	}
+
+	// key fingerprints g^{<z0_i,x>} from the commitment
+	keyFPs := make([]*ec.Point, n)
+	for i := 0; i < n; i++ {
+		if len(com.points[i]) != com.length {
+			return ErrInvalidProof
+		}
+		keyFPs[i] = &ec.Point{Curve: curve, X: big.NewInt(0), Y: big.NewInt(0)}
+		for j := 0; j < com.length; j++ {
+			term := ec.PointScalarMult(curve, com.points[i][j], xp[j])
+			keyFPs[i] = ec.PointAdd(curve, keyFPs[i], term)
+		}
+	}
+
+	selected := selectKeys(n, hashDL(pp, x, keyFPs))
+	if len(proof.steps) != len(selected)-1 || len(proof.intermediates) != len(selected)-2 {
+		return ErrInvalidProof
+	}
+
+	g := ec.BaseScalarMult(curve, big.NewInt(1))
+	t := evalTranscript(x)
+	prev := keyFPs[selected[0]]
+	for j := 1; j < len(selected); j++ {
+		next := y
+		if j < len(selected)-1 {
+			next = proof.intermediates[j-1]
+		}
+
+		if ec.VerifyDLEQ(t, g, keyFPs[selected[j]], prev, next, proof.steps[j-1]) != nil {
+			return ErrInvalidProof
+		}
+		prev = next
+	}
+
+	return nil
+}
+
+// selectKeys returns the indices of the Naor-Reingold keys multiplied
+// into the output: 0, 1 and every i >= 2 with bits[i] set
+func selectKeys(n int, bits []bool) []int {
+	selected := []int{0, 1}
+	for i := 2; i < n; i++ {
+		if bits[i] {
+			selected = append(selected, i)
+		}
+	}
+	return selected
+}
+
+// evalTranscript returns the transcript for the proof of
+// an evaluation on x, to which each step is appended in turn
+func evalTranscript(x []*big.Int) *ec.Transcript {
+	p := elliptic.P256().Params().N
+
+	t := ec.NewTranscript("ddh-cprf eval proof v1")
+	for j := range x {
+		t.AppendScalar("x", big.NewInt(0).Mod(x[j], p))
+	}
+	return t
+}
+
+// MarshalBinary encodes the commitment as its dimensions
+// followed by the compressed points
+func (com *KeyCommitment) MarshalBinary() ([]byte, error) {
+	data := binary.AppendUvarint(nil, uint64(com.n))
+	data = binary.AppendUvarint(data, uint64(com.length))
+	for i := 0; i < com.n; i++ {
+		for j := 0; j < com.length; j++ {
+			data = append(data, com.points[i][j].MarshalCompressed()...)
+		}
+	}
+	return data, nil
+}
+
+// UnmarshalBinary decodes a commitment encoded by MarshalBinary
+func (com *KeyCommitment) UnmarshalBinary(data []byte) error {
+
+	curve := elliptic.P256()
+	pointLen := (curve.Params().BitSize+7)/8 + 1
+
+	n, read := binary.Uvarint(data)
+	if read <= 0 {
+		return ErrInvalidCommitment
+	}
+	data = data[read:]
+
+	length, read := binary.Uvarint(data)
+	if read <= 0 {
+		return ErrInvalidCommitment
+	}
+	data = data[read:]
+
+	if n < 2 || n > MaxN || length == 0 || length > MaxLength || uint64(len(data)) != n*length*uint64(pointLen) {
+		return ErrInvalidCommitment
+	}
+
+	points := make([][]*ec.Point, n)
+	for i := range points {
+		points[i] = make([]*ec.Point, length)
+		for j := range points[i] {
+			points[i][j] = &ec.Point{}
+			if err := points[i][j].Unmarshal(curve, data[:pointLen]); err != nil {
+				return ErrInvalidCommitment
+			}
+			data = data[pointLen:]
+		}
+	}
+
+	com.n = int(n)
+	com.length = int(length)
+	com.points = points
+
+	return nil
+}
+
+// MarshalBinary encodes the proof as the number of steps followed by
+// the compressed intermediate points and the encoded step proofs
+func (proof *EvalProof) MarshalBinary() ([]byte, error) {
+	data := binary.AppendUvarint(nil, uint64(len(proof.steps)))
+	for _, P := range proof.intermediates {
+		data = append(data, P.MarshalCompressed()...)
+	}
+	for _, step := range proof.steps {
+		stepData, err := step.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, stepData...)
+	}
+	return data, nil
+}
+
+// UnmarshalBinary decodes a proof encoded by MarshalBinary
+func (proof *EvalProof) UnmarshalBinary(data []byte) error {
+
+	curve := elliptic.P256()
+	pointLen := (curve.Params().BitSize+7)/8 + 1
+
+	// a proof has one step per key but the first, so at most MaxN-1
+	numSteps, read := binary.Uvarint(data)
+	if read <= 0 || numSteps == 0 || numSteps > MaxN-1 {
+		return ErrInvalidProofEncoding
+	}
+	data = data[read:]
+
+	if uint64(len(data)) != (numSteps-1)*uint64(pointLen)+numSteps*2*ec.ScalarSize {
+		return ErrInvalidProofEncoding
+	}
+
+	intermediates := make([]*ec.Point, numSteps-1)
+	for i := range intermediates {
+		intermediates[i] = &ec.Point{}
+		if err := intermediates[i].Unmarshal(curve, data[:pointLen]); err != nil {
+			return ErrInvalidProofEncoding
+		}
+		data = data[pointLen:]
+	}
+
+	steps := make([]*ec.DLEQProof, numSteps)
+	for i := range steps {
+		steps[i] = &ec.DLEQProof{}
+		if err := steps[i].UnmarshalBinary(data[:2*ec.ScalarSize]); err != nil {
+			return ErrInvalidProofEncoding
+		}
+		data = data[2*ec.ScalarSize:]
+	}
+
+	proof.intermediates = intermediates
+	proof.steps = steps
+
+	return nil
+}
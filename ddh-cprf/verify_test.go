@@ -0,0 +1,209 @@
+package ddhcprf
+
+import (
+	"crypto/elliptic"
+	"encoding/binary"
+	"math/big"
+	"testing"
+
+	"github.com/sachaservan/cprf/ddh-cprf/ec"
+)
+
+func TestEvalWithProof(t *testing.T) {
+	p := elliptic.P256().Params().N
+	n := 128
+	length := 5
+
+	pp, msk, _ := KeyGen(n, length)
+	com := msk.Commit()
+
+	for trial := 0; trial < 3; trial++ {
+		x, _ := generateRandomVector(length, p)
+
+		y, proof, err := msk.EvalWithProof(pp, x)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ec.PointsEqual(y, msk.Eval(pp, x)) {
+			t.Fatalf("EvalWithProof and Eval are not equal")
+		}
+		if err := VerifyEval(pp, com, x, y, proof); err != nil {
+			t.Fatalf("valid proof rejected: %v", err)
+		}
+	}
+}
+
+func TestVerifyEvalTampered(t *testing.T) {
+	p := elliptic.P256().Params().N
+	curve := elliptic.P256()
+	n := 128
+	length := 5
+
+	pp, msk, _ := KeyGen(n, length)
+	com := msk.Commit()
+	_, other, _ := KeyGen(n, length)
+
+	x, _ := generateRandomVector(length, p)
+	y, proof, _ := msk.EvalWithProof(pp, x)
+
+	g := ec.BaseScalarMult(curve, big.NewInt(1))
+
+	// tampered output
+	if err := VerifyEval(pp, com, x, ec.PointAdd(curve, y, g), proof); err != ErrInvalidProof {
+		t.Fatalf("expected ErrInvalidProof for a tampered output, got %v", err)
+	}
+
+	// output of another key
+	if err := VerifyEval(pp, com, x, other.Eval(pp, x), proof); err != ErrInvalidProof {
+		t.Fatalf("expected ErrInvalidProof for the output of another key, got %v", err)
+	}
+
+	// another input
+	x2, _ := generateRandomVector(length, p)
+	if err := VerifyEval(pp, com, x2, y, proof); err != ErrInvalidProof {
+		t.Fatalf("expected ErrInvalidProof for another input, got %v", err)
+	}
+
+	// another commitment
+	if err := VerifyEval(pp, other.Commit(), x, y, proof); err != ErrInvalidProof {
+		t.Fatalf("expected ErrInvalidProof for another commitment, got %v", err)
+	}
+
+	// tampered intermediate point
+	saved := proof.intermediates[0]
+	proof.intermediates[0] = ec.PointAdd(curve, saved, g)
+	if err := VerifyEval(pp, com, x, y, proof); err != ErrInvalidProof {
+		t.Fatalf("expected ErrInvalidProof for a tampered intermediate, got %v", err)
+	}
+	proof.intermediates[0] = saved
+
+	// tampered response
+	s := proof.steps[1].S
+	proof.steps[1].S = big.NewInt(0).Add(s, big.NewInt(1))
+	if err := VerifyEval(pp, com, x, y, proof); err != ErrInvalidProof {
+		t.Fatalf("expected ErrInvalidProof for a tampered response, got %v", err)
+	}
+	proof.steps[1].S = s
+
+	// steps in another order
+	proof.steps[1], proof.steps[2] = proof.steps[2], proof.steps[1]
+	if err := VerifyEval(pp, com, x, y, proof); err != ErrInvalidProof {
+		t.Fatalf("expected ErrInvalidProof for reordered steps, got %v", err)
+	}
+	proof.steps[1], proof.steps[2] = proof.steps[2], proof.steps[1]
+
+	// truncated proof
+	truncated := &EvalProof{intermediates: proof.intermediates[1:], steps: proof.steps[1:]}
+	if err := VerifyEval(pp, com, x, y, truncated); err != ErrInvalidProof {
+		t.Fatalf("expected ErrInvalidProof for a truncated proof, got %v", err)
+	}
+
+	if err := VerifyEval(pp, com, x, y, proof); err != nil {
+		t.Fatalf("restored proof rejected: %v", err)
+	}
+
+	// missing commitment or proof
+	if err := VerifyEval(pp, nil, x, y, proof); err != ErrInvalidProof {
+		t.Fatalf("expected ErrInvalidProof for a nil commitment, got %v", err)
+	}
+	if err := VerifyEval(pp, com, x, y, nil); err != ErrInvalidProof {
+		t.Fatalf("expected ErrInvalidProof for a nil proof, got %v", err)
+	}
+}
+
+func TestVerifyEvalNegativeInput(t *testing.T) {
+	p := elliptic.P256().Params().N
+	n := 16
+	length := 5
+
+	pp, msk, _ := KeyGen(n, length)
+	com := msk.Commit()
+
+	x, _ := generateRandomVector(length, p)
+	x[2] = big.NewInt(-7)
+
+	y, proof, err := msk.EvalWithProof(pp, x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ec.PointsEqual(y, msk.Eval(pp, x)) {
+		t.Fatalf("EvalWithProof and Eval are not equal")
+	}
+	if err := VerifyEval(pp, com, x, y, proof); err != nil {
+		t.Fatalf("valid proof for a negative coordinate rejected: %v", err)
+	}
+}
+
+func TestKeyCommitmentMarshal(t *testing.T) {
+	p := elliptic.P256().Params().N
+	n := 128
+	length := 3
+
+	pp, msk, _ := KeyGenCompact(n, length)
+	com := msk.Commit()
+
+	data, err := com.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded := &KeyCommitment{}
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+
+	x, _ := generateRandomVector(length, p)
+	y, proof, _ := msk.EvalWithProof(pp, x)
+	if err := VerifyEval(pp, decoded, x, y, proof); err != nil {
+		t.Fatalf("proof rejected under decoded commitment: %v", err)
+	}
+
+	if err := decoded.UnmarshalBinary(data[:len(data)-1]); err != ErrInvalidCommitment {
+		t.Fatalf("expected ErrInvalidCommitment, got %v", err)
+	}
+
+	// dimensions whose byte length wraps to 0 mod 2^64 with no points
+	overflow := binary.AppendUvarint(nil, 1<<63)
+	overflow = binary.AppendUvarint(overflow, 2)
+	if err := decoded.UnmarshalBinary(overflow); err != ErrInvalidCommitment {
+		t.Fatalf("expected ErrInvalidCommitment for overflowing dimensions, got %v", err)
+	}
+}
+
+func TestEvalProofMarshal(t *testing.T) {
+	p := elliptic.P256().Params().N
+	n := 128
+	length := 3
+
+	pp, msk, _ := KeyGen(n, length)
+	com := msk.Commit()
+
+	x, _ := generateRandomVector(length, p)
+	y, proof, _ := msk.EvalWithProof(pp, x)
+
+	data, err := proof.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded := &EvalProof{}
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyEval(pp, com, x, y, decoded); err != nil {
+		t.Fatalf("decoded proof rejected: %v", err)
+	}
+
+	if err := decoded.UnmarshalBinary(data[:len(data)-1]); err != ErrInvalidProofEncoding {
+		t.Fatalf("expected ErrInvalidProofEncoding, got %v", err)
+	}
+
+	// a step count whose byte length 97*numSteps - 33 wraps to 0 mod 2^64
+	wrap := big.NewInt(0).Lsh(big.NewInt(1), 64)
+	numSteps := big.NewInt(0).ModInverse(big.NewInt(97), wrap)
+	numSteps.Mul(numSteps, big.NewInt(33)).Mod(numSteps, wrap)
+	overflow := binary.AppendUvarint(nil, numSteps.Uint64())
+	if err := decoded.UnmarshalBinary(overflow); err != ErrInvalidProofEncoding {
+		t.Fatalf("expected ErrInvalidProofEncoding for an overflowing step count, got %v", err)
+	}
+}
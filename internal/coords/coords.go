@@ -0,0 +1,54 @@
+// Package coords reads input vectors encoded as streams of
+// fixed-width big-endian coordinates, shared by the CPRF packages.
+package coords
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var (
+	ErrTruncatedStream = errors.New("stream ended in the middle of a coordinate")
+	ErrCoordinateCount = errors.New("number of coordinates in stream does not match key length")
+)
+
+// Read reads exactly length coordinates of width bytes from r
+// and calls fn on each of them. The slice passed to fn is only
+// valid for the duration of the call.
+func Read(r io.Reader, width int, length int, fn func(j int, coord []byte)) error {
+	br := bufio.NewReader(r)
+	coord := make([]byte, width)
+
+	for j := 0; j < length; j++ {
+		_, err := io.ReadFull(br, coord)
+		switch {
+		case err == io.EOF:
+			return fmt.Errorf("%w: got %d, expected %d", ErrCoordinateCount, j, length)
+		case err == io.ErrUnexpectedEOF:
+			return fmt.Errorf("%w: coordinate %d", ErrTruncatedStream, j)
+		case err != nil:
+			return fmt.Errorf("failed to read coordinate %d: %w", j, err)
+		}
+		fn(j, coord)
+	}
+
+	// the stream must end after the last coordinate
+	if _, err := br.ReadByte(); err == nil {
+		return fmt.Errorf("%w: stream has more than %d coordinates", ErrCoordinateCount, length)
+	} else if err != io.EOF {
+		return fmt.Errorf("failed to read end of stream: %w", err)
+	}
+
+	return nil
+}
+
+// TrimLeadingZeros returns the minimal big-endian encoding of the
+// unsigned integer b, i.e., the bytes of new(big.Int).SetBytes(b).Bytes()
+func TrimLeadingZeros(b []byte) []byte {
+	for len(b) > 0 && b[0] == 0 {
+		b = b[1:]
+	}
+	return b
+}
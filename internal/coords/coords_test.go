@@ -0,0 +1,40 @@
+package coords
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestRead(t *testing.T) {
+	data := []byte{0, 1, 0, 0, 2, 3}
+
+	var got [][]byte
+	err := Read(bytes.NewReader(data), 2, 3, func(j int, coord []byte) {
+		got = append(got, TrimLeadingZeros(append([]byte(nil), coord...)))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]byte{{1}, {}, {2, 3}}
+	for j := range expected {
+		if !bytes.Equal(got[j], expected[j]) {
+			t.Fatalf("coordinate %d is %v, expected %v", j, got[j], expected[j])
+		}
+	}
+
+	for _, tc := range []struct {
+		data   []byte
+		length int
+		err    error
+	}{
+		{data[:5], 3, ErrTruncatedStream},
+		{data[:4], 3, ErrCoordinateCount},
+		{data, 2, ErrCoordinateCount},
+	} {
+		err := Read(bytes.NewReader(tc.data), 2, tc.length, func(int, []byte) {})
+		if !errors.Is(err, tc.err) {
+			t.Fatalf("expected %v, got %v", tc.err, err)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+// Package ledger records the constrained keys issued from master keys.
+//
+// The CPRF constructions are proven secure only when a single constrained
+// key is issued per master key. The issuers in ro-cprf and ddh-cprf consult
+// a Ledger before every issuance and append a Record after it, which also
+// gives auditors the issuance history of every key. Rotating a master key
+// appends a Record linking the new key ID to the old one, so the history
+// carries over to the rotated key (see History).
+//
+// Records identify the master key by a key ID, a hash of the key, and the
+// constraint by an HMAC of z under a secret key derived from the master
+// key. Readers of the ledger cannot test guesses of z, however few values
+// z may take; the issuer can recompute the HMAC to find a constraint.
+package ledger
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+)
+
+var (
+	ErrCorruptLedger = errors.New("ledger file is corrupt")
+)
+
+// Record is an entry of the ledger, either an issuance or a rotation
+// KeyID: ID of the master key the constrained key was issued from,
+// or of the new master key of a rotation
+// ConstraintHash: HMAC of the constraint vector z (see HashConstraint)
+// Time: time of issuance or rotation
+// Override: true if the key was issued despite earlier issuances
+// Reason: justification given for an override
+// RotatedFrom: ID of the master key that was rotated to KeyID,
+// empty for issuances
+type Record struct {
+	KeyID          string    `json:"key_id"`
+	ConstraintHash string    `json:"constraint_hash,omitempty"`
+	Time           time.Time `json:"time"`
+	Override       bool      `json:"override,omitempty"`
+	Reason         string    `json:"reason,omitempty"`
+	RotatedFrom    string    `json:"rotated_from,omitempty"`
+}
+
+// IsRotation reports whether the record is a rotation rather than an issuance
+func (rec Record) IsRotation() bool {
+	return rec.RotatedFrom != ""
+}
+
+// Ledger stores issuance records. Implementations must be safe
+// for concurrent use.
+type Ledger interface {
+	// Append adds a record to the ledger
+	Append(rec Record) error
+	// Records returns the records of the given key in the order they were appended
+	Records(keyID string) ([]Record, error)
+	// All returns every record in the order they were appended
+	All() ([]Record, error)
+}
+
+// HashConstraint returns the hex encoded HMAC-SHA256 under key of
+// the constraint vectors, in the order they are given. The key must be
+// secret, or the hash of a low-entropy constraint can be inverted by
+// trying every candidate.
+func HashConstraint(key []byte, zs ...[]*big.Int) string {
+	hasher := hmac.New(sha256.New, key)
+	hasher.Write([]byte("cprf constraint hash v2"))
+	hasher.Write(binary.AppendUvarint(nil, uint64(len(zs))))
+	for _, z := range zs {
+		hasher.Write(binary.AppendUvarint(nil, uint64(len(z))))
+		for _, zi := range z {
+			b := zi.Bytes()
+			hasher.Write([]byte{byte(zi.Sign() + 1)})
+			hasher.Write(binary.AppendUvarint(nil, uint64(len(b))))
+			hasher.Write(b)
+		}
+	}
+	return hex.EncodeToString(hasher.Sum(nil))
+}
+
+// History returns the records of the given key and of every key it was
+// rotated from, in the order they were appended
+func History(l Ledger, keyID string) ([]Record, error) {
+	all, err := l.All()
+	if err != nil {
+		return nil, err
+	}
+
+	parents := make(map[string]string)
+	for _, rec := range all {
+		if rec.IsRotation() {
+			parents[rec.KeyID] = rec.RotatedFrom
+		}
+	}
+
+	lineage := make(map[string]bool)
+	for id := keyID; id != "" && !lineage[id]; id = parents[id] {
+		lineage[id] = true
+	}
+
+	var res []Record
+	for _, rec := range all {
+		if lineage[rec.KeyID] {
+			res = append(res, rec)
+		}
+	}
+	return res, nil
+}
+
+// Memory is a Ledger held in memory
+type Memory struct {
+	mu      sync.Mutex
+	records []Record
+}
+
+// NewMemory returns an empty in-memory ledger
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+// Append adds a record to the ledger
+func (l *Memory) Append(rec Record) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.records = append(l.records, rec)
+	return nil
+}
+
+// Records returns the records of the given key
+func (l *Memory) Records(keyID string) ([]Record, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return filterRecords(l.records, keyID), nil
+}
+
+// All returns every record
+func (l *Memory) All() ([]Record, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return append([]Record(nil), l.records...), nil
+}
+
+// File is a Ledger stored in a file with one JSON record per line.
+// Records are appended and synced to disk before Append returns.
+// A ledger file must not be opened by more than one process at a time.
+type File struct {
+	mu      sync.Mutex
+	file    *os.File
+	records []Record
+}
+
+// OpenFile opens the ledger stored at path, creating it if it does not exist
+func OpenFile(path string) (*File, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ledger: %w", err)
+	}
+
+	l := &File{}
+	l.file = file
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 4096), 1<<20)
+	for scanner.Scan() {
+		var rec Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			file.Close()
+			return nil, ErrCorruptLedger
+		}
+		l.records = append(l.records, rec)
+	}
+	if err := scanner.Err(); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to read ledger: %w", err)
+	}
+
+	return l, nil
+}
+
+// Append adds a record to the ledger file
+func (l *File) Append(rec Record) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, err := l.file.Write(line); err != nil {
+		return fmt.Errorf("failed to append to ledger: %w", err)
+	}
+	if err := l.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync ledger: %w", err)
+	}
+
+	l.records = append(l.records, rec)
+	return nil
+}
+
+// Records returns the records of the given key
+func (l *File) Records(keyID string) ([]Record, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return filterRecords(l.records, keyID), nil
+}
+
+// All returns every record
+func (l *File) All() ([]Record, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return append([]Record(nil), l.records...), nil
+}
+
+// Close closes the ledger file
+func (l *File) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.file.Close()
+}
+
+func filterRecords(records []Record, keyID string) []Record {
+	var res []Record
+	for _, rec := range records {
+		if rec.KeyID == keyID {
+			res = append(res, rec)
+		}
+	}
+	return res
+}
@@ -0,0 +1,157 @@
+package ledger
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func testLedger(t *testing.T, l Ledger) {
+	now := time.Now().UTC().Truncate(time.Second)
+	records := []Record{
+		{KeyID: "a", ConstraintHash: "h1", Time: now},
+		{KeyID: "b", ConstraintHash: "h2", Time: now},
+		{KeyID: "a", ConstraintHash: "h3", Time: now, Override: true, Reason: "re-issued after loss"},
+	}
+	for _, rec := range records {
+		if err := l.Append(rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	res, err := l.Records("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 2 || res[0] != records[0] || res[1] != records[2] {
+		t.Fatalf("unexpected records for key a: %v", res)
+	}
+
+	res, _ = l.Records("c")
+	if len(res) != 0 {
+		t.Fatalf("unexpected records for key c: %v", res)
+	}
+
+	res, _ = l.All()
+	if len(res) != len(records) {
+		t.Fatalf("expected %d records, got %d", len(records), len(res))
+	}
+	for k := range res {
+		if !res[k].Time.Equal(records[k].Time) {
+			t.Fatalf("record %d has time %v", k, res[k].Time)
+		}
+		res[k].Time = records[k].Time
+		if res[k] != records[k] {
+			t.Fatalf("record %d is %v, expected %v", k, res[k], records[k])
+		}
+	}
+}
+
+func TestMemory(t *testing.T) {
+	testLedger(t, NewMemory())
+}
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.jsonl")
+
+	l, err := OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testLedger(t, l)
+	l.Close()
+
+	// records persist across opens
+	l, err = OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	res, _ := l.All()
+	if len(res) != 3 || res[2].Reason != "re-issued after loss" {
+		t.Fatalf("records were not persisted: %v", res)
+	}
+}
+
+func TestFileCorrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.jsonl")
+	os.WriteFile(path, []byte("{\"key_id\":\"a\"}\nnot json\n"), 0600)
+
+	if _, err := OpenFile(path); err != ErrCorruptLedger {
+		t.Fatalf("expected ErrCorruptLedger, got %v", err)
+	}
+}
+
+func TestHashConstraint(t *testing.T) {
+	key := []byte("issuer secret")
+	z := []*big.Int{big.NewInt(1), big.NewInt(2)}
+
+	if HashConstraint(key, z) != HashConstraint(key, []*big.Int{big.NewInt(1), big.NewInt(2)}) {
+		t.Fatalf("hash is not deterministic")
+	}
+	for _, other := range [][]*big.Int{
+		{big.NewInt(2), big.NewInt(1)},
+		{big.NewInt(1), big.NewInt(-2)},
+		{big.NewInt(1), big.NewInt(2), big.NewInt(0)},
+	} {
+		if HashConstraint(key, z) == HashConstraint(key, other) {
+			t.Fatalf("different constraints have the same hash")
+		}
+	}
+	if HashConstraint(key, z, z) == HashConstraint(key, z) {
+		t.Fatalf("a repeated constraint has the same hash")
+	}
+
+	// without the key the hash cannot be recomputed
+	if HashConstraint([]byte("another secret"), z) == HashConstraint(key, z) {
+		t.Fatalf("hashes under different keys are equal")
+	}
+}
+
+func TestHistory(t *testing.T) {
+	now := time.Now().UTC()
+	l := NewMemory()
+	records := []Record{
+		{KeyID: "a", ConstraintHash: "h1", Time: now},
+		{KeyID: "x", ConstraintHash: "h2", Time: now},
+		{KeyID: "b", RotatedFrom: "a", Time: now},
+		{KeyID: "b", ConstraintHash: "h3", Time: now, Override: true, Reason: "lost"},
+		{KeyID: "c", RotatedFrom: "b", Time: now},
+		{KeyID: "y", RotatedFrom: "x", Time: now},
+	}
+	for _, rec := range records {
+		l.Append(rec)
+	}
+
+	res, err := History(l, "c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Record{records[0], records[2], records[3], records[4]}
+	if len(res) != len(expected) {
+		t.Fatalf("expected %d records, got %v", len(expected), res)
+	}
+	for k := range res {
+		if res[k] != expected[k] {
+			t.Fatalf("record %d is %v, expected %v", k, res[k], expected[k])
+		}
+	}
+	if !res[1].IsRotation() || res[2].IsRotation() {
+		t.Fatalf("rotation records are not told apart from issuances")
+	}
+
+	// earlier keys do not see later ones
+	res, _ = History(l, "a")
+	if len(res) != 1 || res[0] != records[0] {
+		t.Fatalf("unexpected history of key a: %v", res)
+	}
+
+	// a cycle of rotations terminates
+	l.Append(Record{KeyID: "a", RotatedFrom: "c", Time: now})
+	if _, err := History(l, "c"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,188 @@
+// Package linalg implements vectors and matrices over Z_q for a prime q,
+// for building and testing inner-product constraints.
+package linalg
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+var (
+	ErrNotPrime          = errors.New("modulus must be prime")
+	ErrDimensionMismatch = errors.New("dimensions do not match")
+	ErrZeroVector        = errors.New("vector must be nonzero")
+)
+
+// Field is the field of integers modulo a prime q
+type Field struct {
+	q *big.Int
+}
+
+// Vector is a vector over a field
+type Vector []*big.Int
+
+// NewField returns the field of integers modulo q
+// q: prime modulus, e.g., the RO modulus or the P-256 group order
+func NewField(q *big.Int) (*Field, error) {
+	if q.Sign() <= 0 || !q.ProbablyPrime(20) {
+		return nil, ErrNotPrime
+	}
+
+	f := &Field{}
+	f.q = big.NewInt(0).Set(q)
+
+	return f, nil
+}
+
+// Modulus returns the modulus q of the field
+func (f *Field) Modulus() *big.Int {
+	return big.NewInt(0).Set(f.q)
+}
+
+// Elem returns v reduced into [0, q)
+func (f *Field) Elem(v *big.Int) *big.Int {
+	return big.NewInt(0).Mod(v, f.q)
+}
+
+// Inverse returns the multiplicative inverse of a mod q
+func (f *Field) Inverse(a *big.Int) (*big.Int, error) {
+	inv := big.NewInt(0).ModInverse(f.Elem(a), f.q)
+	if inv == nil {
+		return nil, fmt.Errorf("%v has no inverse mod q", a)
+	}
+	return inv, nil
+}
+
+// RandomElement samples a uniform element of the field
+func (f *Field) RandomElement() (*big.Int, error) {
+	v, err := rand.Int(rand.Reader, f.q)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate random field element: %w", err)
+	}
+	return v, nil
+}
+
+// NewVector returns the zero vector of the given length
+func (f *Field) NewVector(length int) Vector {
+	v := make(Vector, length)
+	for i := 0; i < length; i++ {
+		v[i] = big.NewInt(0)
+	}
+	return v
+}
+
+// RandomVector samples a uniform vector of the given length
+func (f *Field) RandomVector(length int) (Vector, error) {
+	v := make(Vector, length)
+	for i := 0; i < length; i++ {
+		var err error
+		v[i], err = f.RandomElement()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+// Reduce returns a copy of v with every coordinate reduced into [0, q)
+func (f *Field) Reduce(v Vector) Vector {
+	res := make(Vector, len(v))
+	for i := 0; i < len(v); i++ {
+		res[i] = f.Elem(v[i])
+	}
+	return res
+}
+
+// IsZero returns true if every coordinate of v is zero mod q
+func (f *Field) IsZero(v Vector) bool {
+	tmp := big.NewInt(0)
+	for i := 0; i < len(v); i++ {
+		if tmp.Mod(v[i], f.q).Sign() != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Dot returns the inner product <a, b> mod q
+func (f *Field) Dot(a, b Vector) (*big.Int, error) {
+	if len(a) != len(b) {
+		return nil, ErrDimensionMismatch
+	}
+
+	tmp := big.NewInt(0)
+	res := big.NewInt(0)
+	for i := 0; i < len(a); i++ {
+		tmp.Mul(a[i], b[i])
+		res.Add(res, tmp).Mod(res, f.q)
+	}
+
+	return res, nil
+}
+
+// Add returns a + b mod q
+func (f *Field) Add(a, b Vector) (Vector, error) {
+	if len(a) != len(b) {
+		return nil, ErrDimensionMismatch
+	}
+
+	res := make(Vector, len(a))
+	for i := 0; i < len(a); i++ {
+		res[i] = big.NewInt(0).Add(a[i], b[i])
+		res[i].Mod(res[i], f.q)
+	}
+
+	return res, nil
+}
+
+// Sub returns a - b mod q
+func (f *Field) Sub(a, b Vector) (Vector, error) {
+	if len(a) != len(b) {
+		return nil, ErrDimensionMismatch
+	}
+
+	res := make(Vector, len(a))
+	for i := 0; i < len(a); i++ {
+		res[i] = big.NewInt(0).Sub(a[i], b[i])
+		res[i].Mod(res[i], f.q)
+	}
+
+	return res, nil
+}
+
+// Scale returns c*a mod q
+func (f *Field) Scale(c *big.Int, a Vector) Vector {
+	res := make(Vector, len(a))
+	for i := 0; i < len(a); i++ {
+		res[i] = big.NewInt(0).Mul(c, a[i])
+		res[i].Mod(res[i], f.q)
+	}
+	return res
+}
+
+// SampleOrthogonal samples a uniform x from the orthogonal complement
+// of z, i.e., such that <z, x> = 0 mod q
+func (f *Field) SampleOrthogonal(z Vector) (Vector, error) {
+	return f.SampleKernel(Matrix{z})
+}
+
+// SampleNonOrthogonal samples a uniform x such that <z, x> != 0 mod q
+func (f *Field) SampleNonOrthogonal(z Vector) (Vector, error) {
+	if f.IsZero(z) {
+		return nil, ErrZeroVector
+	}
+
+	for {
+		x, err := f.RandomVector(len(z))
+		if err != nil {
+			return nil, err
+		}
+
+		ip, _ := f.Dot(z, x)
+		if ip.Sign() != 0 {
+			return x, nil
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package linalg
+
+import (
+	"crypto/elliptic"
+	"math/big"
+	"testing"
+)
+
+func testFields(t testing.TB) []*Field {
+	roModulus, _ := big.NewInt(0).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFF61", 16)
+	fields := make([]*Field, 0)
+	for _, q := range []*big.Int{roModulus, elliptic.P256().Params().N, big.NewInt(7)} {
+		f, err := NewField(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+func TestNewFieldRejectsComposite(t *testing.T) {
+	if _, err := NewField(big.NewInt(15)); err != ErrNotPrime {
+		t.Fatalf("expected ErrNotPrime, got %v", err)
+	}
+}
+
+func TestVectorArithmetic(t *testing.T) {
+	f, _ := NewField(big.NewInt(7))
+
+	a := Vector{big.NewInt(1), big.NewInt(5), big.NewInt(6)}
+	b := Vector{big.NewInt(3), big.NewInt(4), big.NewInt(-1)}
+
+	dot, _ := f.Dot(a, b) // 3 + 20 - 6 = 17 = 3 mod 7
+	if dot.Int64() != 3 {
+		t.Fatalf("unexpected inner product %v", dot)
+	}
+
+	sum, _ := f.Add(a, b)
+	diff, _ := f.Sub(sum, b)
+	if !f.IsZero(mustSub(t, f, diff, a)) {
+		t.Fatalf("(a + b) - b != a")
+	}
+
+	if f.Scale(big.NewInt(2), a)[1].Int64() != 3 {
+		t.Fatalf("unexpected scaled vector")
+	}
+
+	if _, err := f.Dot(a, b[:2]); err != ErrDimensionMismatch {
+		t.Fatalf("expected ErrDimensionMismatch, got %v", err)
+	}
+}
+
+func TestSampleOrthogonal(t *testing.T) {
+	for _, f := range testFields(t) {
+		for trial := 0; trial < 10; trial++ {
+			z, _ := f.RandomVector(20)
+
+			x, err := f.SampleOrthogonal(z)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ip, _ := f.Dot(z, x); ip.Sign() != 0 {
+				t.Fatalf("sampled vector is not orthogonal")
+			}
+
+			y, err := f.SampleNonOrthogonal(z)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ip, _ := f.Dot(z, y); ip.Sign() == 0 {
+				t.Fatalf("sampled vector is orthogonal")
+			}
+		}
+	}
+}
+
+func TestSampleNonOrthogonalZero(t *testing.T) {
+	f, _ := NewField(big.NewInt(7))
+	if _, err := f.SampleNonOrthogonal(f.NewVector(3)); err != ErrZeroVector {
+		t.Fatalf("expected ErrZeroVector, got %v", err)
+	}
+}
+
+func mustSub(t *testing.T, f *Field, a, b Vector) Vector {
+	res, err := f.Sub(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
@@ -0,0 +1,209 @@
+package linalg
+
+import (
+	"errors"
+	"math/big"
+)
+
+var (
+	ErrEmptyMatrix   = errors.New("matrix must have at least one row")
+	ErrRaggedMatrix  = errors.New("matrix rows must have the same length")
+	ErrNoSolution    = errors.New("linear system has no solution")
+	ErrTrivialKernel = errors.New("kernel is trivial")
+)
+
+// Matrix is a matrix over a field, stored as a slice of rows
+type Matrix []Vector
+
+// Cols returns the number of columns of m
+func (m Matrix) Cols() int {
+	if len(m) == 0 {
+		return 0
+	}
+	return len(m[0])
+}
+
+func (f *Field) check(m Matrix) error {
+	if len(m) == 0 {
+		return ErrEmptyMatrix
+	}
+	for i := 1; i < len(m); i++ {
+		if len(m[i]) != len(m[0]) {
+			return ErrRaggedMatrix
+		}
+	}
+	return nil
+}
+
+// MulVec returns the matrix-vector product m*v mod q
+func (f *Field) MulVec(m Matrix, v Vector) (Vector, error) {
+	if err := f.check(m); err != nil {
+		return nil, err
+	}
+	if m.Cols() != len(v) {
+		return nil, ErrDimensionMismatch
+	}
+
+	res := make(Vector, len(m))
+	for i := 0; i < len(m); i++ {
+		res[i], _ = f.Dot(m[i], v)
+	}
+
+	return res, nil
+}
+
+// RowReduce computes the reduced row echelon form of m by Gaussian
+// elimination. It returns the nonzero rows of the echelon form and the
+// pivot column of each of them. The input matrix is not modified.
+func (f *Field) RowReduce(m Matrix) (Matrix, []int, error) {
+	if err := f.check(m); err != nil {
+		return nil, nil, err
+	}
+
+	rows := make(Matrix, len(m))
+	for i := 0; i < len(m); i++ {
+		rows[i] = f.Reduce(m[i])
+	}
+
+	cols := m.Cols()
+	pivots := make([]int, 0)
+	tmp := big.NewInt(0)
+
+	r := 0 // next pivot row
+	for c := 0; c < cols && r < len(rows); c++ {
+
+		// find a row with a nonzero entry in column c
+		pivot := -1
+		for i := r; i < len(rows); i++ {
+			if rows[i][c].Sign() != 0 {
+				pivot = i
+				break
+			}
+		}
+		if pivot < 0 {
+			continue
+		}
+		rows[r], rows[pivot] = rows[pivot], rows[r]
+
+		// normalize the pivot to one
+		inv, _ := f.Inverse(rows[r][c])
+		rows[r] = f.Scale(inv, rows[r])
+
+		// eliminate column c from every other row
+		for i := 0; i < len(rows); i++ {
+			if i == r || rows[i][c].Sign() == 0 {
+				continue
+			}
+			factor := big.NewInt(0).Set(rows[i][c])
+			for j := c; j < cols; j++ {
+				tmp.Mul(factor, rows[r][j])
+				rows[i][j].Sub(rows[i][j], tmp).Mod(rows[i][j], f.q)
+			}
+		}
+
+		pivots = append(pivots, c)
+		r++
+	}
+
+	return rows[:r], pivots, nil
+}
+
+// Rank returns the rank of m
+func (f *Field) Rank(m Matrix) (int, error) {
+	_, pivots, err := f.RowReduce(m)
+	if err != nil {
+		return 0, err
+	}
+	return len(pivots), nil
+}
+
+// Kernel returns a basis of the kernel {x : m*x = 0} of m.
+// The basis is empty if the kernel is trivial.
+func (f *Field) Kernel(m Matrix) (Matrix, error) {
+	echelon, pivots, err := f.RowReduce(m)
+	if err != nil {
+		return nil, err
+	}
+
+	cols := m.Cols()
+	isPivot := make([]bool, cols)
+	for _, c := range pivots {
+		isPivot[c] = true
+	}
+
+	// one basis vector per free column: set the free variable to one
+	// and solve for the pivot variables
+	basis := make(Matrix, 0, cols-len(pivots))
+	for free := 0; free < cols; free++ {
+		if isPivot[free] {
+			continue
+		}
+
+		v := f.NewVector(cols)
+		v[free].SetInt64(1)
+		for i, c := range pivots {
+			v[c].Neg(echelon[i][free]).Mod(v[c], f.q)
+		}
+
+		basis = append(basis, v)
+	}
+
+	return basis, nil
+}
+
+// SampleKernel samples a uniform vector from the kernel of m
+func (f *Field) SampleKernel(m Matrix) (Vector, error) {
+	basis, err := f.Kernel(m)
+	if err != nil {
+		return nil, err
+	}
+	if len(basis) == 0 {
+		return nil, ErrTrivialKernel
+	}
+
+	// a uniform combination of a basis is uniform over the span
+	res := f.NewVector(m.Cols())
+	for i := 0; i < len(basis); i++ {
+		c, err := f.RandomElement()
+		if err != nil {
+			return nil, err
+		}
+		res, _ = f.Add(res, f.Scale(c, basis[i]))
+	}
+
+	return res, nil
+}
+
+// Solve returns a solution x of m*x = b; any solution of the system
+// is obtained by adding an element of the kernel of m.
+func (f *Field) Solve(m Matrix, b Vector) (Vector, error) {
+	if err := f.check(m); err != nil {
+		return nil, err
+	}
+	if len(b) != len(m) {
+		return nil, ErrDimensionMismatch
+	}
+
+	// row reduce the augmented matrix [m | b]
+	cols := m.Cols()
+	augmented := make(Matrix, len(m))
+	for i := 0; i < len(m); i++ {
+		augmented[i] = append(append(Vector{}, m[i]...), b[i])
+	}
+
+	echelon, pivots, err := f.RowReduce(augmented)
+	if err != nil {
+		return nil, err
+	}
+
+	x := f.NewVector(cols)
+	for i, c := range pivots {
+		if c == cols {
+			// pivot in the last column means 0 = 1
+			return nil, ErrNoSolution
+		}
+		x[c].Set(echelon[i][cols])
+	}
+
+	return x, nil
+}
@@ -0,0 +1,120 @@
+package linalg
+
+import (
+	"math/big"
+	"testing"
+)
+
+func vec(values ...int64) Vector {
+	v := make(Vector, len(values))
+	for i := 0; i < len(values); i++ {
+		v[i] = big.NewInt(values[i])
+	}
+	return v
+}
+
+func TestRowReduce(t *testing.T) {
+	f, _ := NewField(big.NewInt(7))
+
+	// third row is the sum of the first two
+	m := Matrix{
+		vec(1, 2, 3, 4),
+		vec(2, 0, 1, 1),
+		vec(3, 2, 4, 5),
+	}
+
+	echelon, pivots, err := f.RowReduce(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(echelon) != 2 || len(pivots) != 2 || pivots[0] != 0 || pivots[1] != 1 {
+		t.Fatalf("unexpected echelon form %v with pivots %v", echelon, pivots)
+	}
+
+	// the input must not be modified
+	if m[0][1].Int64() != 2 {
+		t.Fatalf("RowReduce modified its input")
+	}
+
+	if _, _, err := f.RowReduce(Matrix{}); err != ErrEmptyMatrix {
+		t.Fatalf("expected ErrEmptyMatrix, got %v", err)
+	}
+	if _, _, err := f.RowReduce(Matrix{vec(1, 2), vec(1)}); err != ErrRaggedMatrix {
+		t.Fatalf("expected ErrRaggedMatrix, got %v", err)
+	}
+}
+
+func TestKernel(t *testing.T) {
+	for _, f := range testFields(t) {
+		for _, dims := range []struct{ rows, cols int }{
+			{1, 5},
+			{3, 10},
+			{4, 4},
+			{6, 4},
+		} {
+			m := make(Matrix, dims.rows)
+			for i := 0; i < dims.rows; i++ {
+				m[i], _ = f.RandomVector(dims.cols)
+			}
+
+			rank, _ := f.Rank(m)
+			basis, err := f.Kernel(m)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(basis) != dims.cols-rank {
+				t.Fatalf("kernel has dimension %d, expected %d", len(basis), dims.cols-rank)
+			}
+
+			for _, v := range basis {
+				mv, _ := f.MulVec(m, v)
+				if !f.IsZero(mv) {
+					t.Fatalf("kernel basis vector is not in the kernel")
+				}
+			}
+
+			if len(basis) > 0 {
+				basisRank, _ := f.Rank(basis)
+				if basisRank != len(basis) {
+					t.Fatalf("kernel basis is not linearly independent")
+				}
+
+				x, err := f.SampleKernel(m)
+				if err != nil {
+					t.Fatal(err)
+				}
+				mx, _ := f.MulVec(m, x)
+				if !f.IsZero(mx) {
+					t.Fatalf("sampled vector is not in the kernel")
+				}
+			} else if _, err := f.SampleKernel(m); err != ErrTrivialKernel {
+				t.Fatalf("expected ErrTrivialKernel, got %v", err)
+			}
+		}
+	}
+}
+
+func TestSolve(t *testing.T) {
+	for _, f := range testFields(t) {
+		m := make(Matrix, 3)
+		for i := 0; i < 3; i++ {
+			m[i], _ = f.RandomVector(5)
+		}
+		b, _ := f.RandomVector(3)
+
+		x, err := f.Solve(m, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mx, _ := f.MulVec(m, x)
+		if !f.IsZero(mustSub(t, f, mx, b)) {
+			t.Fatalf("m*x != b")
+		}
+	}
+
+	// inconsistent system: x + y = 1 and x + y = 2
+	f, _ := NewField(big.NewInt(7))
+	if _, err := f.Solve(Matrix{vec(1, 1), vec(1, 1)}, vec(1, 2)); err != ErrNoSolution {
+		t.Fatalf("expected ErrNoSolution, got %v", err)
+	}
+}
@@ -0,0 +1,187 @@
+// Package paillier implements the Paillier additively homomorphic
+// encryption scheme with generator g = N+1 using only the standard library.
+//
+// Ciphertexts are integers in Z*_{N^2}. Encryptions of m1 and m2 multiply
+// to an encryption of m1 + m2 mod N, and raising an encryption of m to the
+// power k gives an encryption of k*m mod N.
+package paillier
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+)
+
+var (
+	ErrKeySize           = errors.New("paillier key size is too small")
+	ErrMessageOutOfRange = errors.New("plaintext is out of range")
+	ErrInvalidCiphertext = errors.New("invalid ciphertext")
+)
+
+// MinKeySize is the smallest modulus size in bits accepted by GenerateKey.
+// Moduli below 2048 bits can be factored with feasible effort, which
+// reveals every plaintext.
+const MinKeySize = 2048
+
+// PublicKey is a Paillier public key with modulus N.
+// A PublicKey{N: N} literal is valid; NewPublicKey
+// also precomputes N^2.
+type PublicKey struct {
+	N  *big.Int
+	n2 *big.Int // N^2, if precomputed
+}
+
+// PrivateKey is a Paillier private key
+// lambda: phi(N) = (p-1)(q-1)
+// mu: lambda^-1 mod N
+type PrivateKey struct {
+	PublicKey
+	lambda *big.Int
+	mu     *big.Int
+}
+
+// NewPublicKey returns the public key with modulus N
+func NewPublicKey(N *big.Int) *PublicKey {
+	pk := &PublicKey{}
+	pk.N = new(big.Int).Set(N)
+	pk.n2 = new(big.Int).Mul(N, N)
+	return pk
+}
+
+// GenerateKey generates a private key with a modulus of the given size
+func GenerateKey(random io.Reader, bits int) (*PrivateKey, error) {
+	if bits < MinKeySize {
+		return nil, ErrKeySize
+	}
+
+	one := big.NewInt(1)
+	for {
+		p, err := randPrime(random, bits/2)
+		if err != nil {
+			return nil, err
+		}
+		q, err := randPrime(random, bits-bits/2)
+		if err != nil {
+			return nil, err
+		}
+		if p.Cmp(q) == 0 {
+			continue
+		}
+
+		N := new(big.Int).Mul(p, q)
+		phi := new(big.Int).Mul(new(big.Int).Sub(p, one), new(big.Int).Sub(q, one))
+
+		// g = N+1 requires gcd(N, phi(N)) = 1
+		mu := new(big.Int).ModInverse(phi, N)
+		if mu == nil {
+			continue
+		}
+
+		sk := &PrivateKey{}
+		sk.PublicKey = *NewPublicKey(N)
+		sk.lambda = phi
+		sk.mu = mu
+		return sk, nil
+	}
+}
+
+func randPrime(random io.Reader, bits int) (*big.Int, error) {
+	p, err := rand.Prime(random, bits)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate paillier prime: %w", err)
+	}
+	return p, nil
+}
+
+// Encrypt encrypts m in [0, N) as (1 + mN) r^N mod N^2 for a random r in Z*_N
+func (pk *PublicKey) Encrypt(random io.Reader, m *big.Int) (*big.Int, error) {
+	if m.Sign() < 0 || m.Cmp(pk.N) >= 0 {
+		return nil, ErrMessageOutOfRange
+	}
+
+	r, err := pk.randomUnit(random)
+	if err != nil {
+		return nil, err
+	}
+
+	c := new(big.Int).Exp(r, pk.N, pk.nSquared())
+	return pk.AddPlaintext(c, m), nil
+}
+
+// Decrypt returns the plaintext L(c^lambda mod N^2) * mu mod N
+// with L(u) = (u - 1) / N
+func (sk *PrivateKey) Decrypt(c *big.Int) (*big.Int, error) {
+	if err := sk.Validate(c); err != nil {
+		return nil, err
+	}
+
+	u := new(big.Int).Exp(c, sk.lambda, sk.nSquared())
+	u.Sub(u, big.NewInt(1)).Div(u, sk.N)
+	return u.Mul(u, sk.mu).Mod(u, sk.N), nil
+}
+
+// Validate checks that c is in Z*_{N^2}
+func (pk *PublicKey) Validate(c *big.Int) error {
+	if c == nil || c.Sign() <= 0 || c.Cmp(pk.nSquared()) >= 0 {
+		return ErrInvalidCiphertext
+	}
+	if new(big.Int).GCD(nil, nil, c, pk.N).Cmp(big.NewInt(1)) != 0 {
+		return ErrInvalidCiphertext
+	}
+	return nil
+}
+
+// Add returns an encryption of the sum of the plaintexts of c1 and c2
+func (pk *PublicKey) Add(c1 *big.Int, c2 *big.Int) *big.Int {
+	c := new(big.Int).Mul(c1, c2)
+	return c.Mod(c, pk.nSquared())
+}
+
+// AddPlaintext returns an encryption of the plaintext of c plus m mod N
+func (pk *PublicKey) AddPlaintext(c *big.Int, m *big.Int) *big.Int {
+	// (1 + N)^m = 1 + mN mod N^2
+	g := new(big.Int).Mod(m, pk.N)
+	g.Mul(g, pk.N).Add(g, big.NewInt(1))
+	return g.Mul(g, c).Mod(g, pk.nSquared())
+}
+
+// MulPlaintext returns an encryption of the plaintext of c times k mod N
+func (pk *PublicKey) MulPlaintext(c *big.Int, k *big.Int) *big.Int {
+	e := new(big.Int).Mod(k, pk.N)
+	return e.Exp(c, e, pk.nSquared())
+}
+
+// Rerandomize returns a fresh encryption of the plaintext of c
+func (pk *PublicKey) Rerandomize(random io.Reader, c *big.Int) (*big.Int, error) {
+	r, err := pk.randomUnit(random)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Exp(r, pk.N, pk.nSquared())
+	return pk.Add(c, r), nil
+}
+
+// nSquared returns N^2
+func (pk *PublicKey) nSquared() *big.Int {
+	if pk.n2 != nil {
+		return pk.n2
+	}
+	return new(big.Int).Mul(pk.N, pk.N)
+}
+
+// randomUnit returns a uniformly random element of Z*_N
+func (pk *PublicKey) randomUnit(random io.Reader) (*big.Int, error) {
+	one := big.NewInt(1)
+	for {
+		r, err := rand.Int(random, pk.N)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate paillier randomness: %w", err)
+		}
+		if r.Sign() > 0 && new(big.Int).GCD(nil, nil, r, pk.N).Cmp(one) == 0 {
+			return r, nil
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package paillier
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	sk, err := GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sk.N.BitLen() != 2048 {
+		t.Fatalf("modulus has %d bits", sk.N.BitLen())
+	}
+
+	max := new(big.Int).Sub(sk.N, big.NewInt(1))
+	for _, m := range []*big.Int{big.NewInt(0), big.NewInt(1), big.NewInt(123456789), max} {
+		c, err := sk.Encrypt(rand.Reader, m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d, err := sk.Decrypt(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d.Cmp(m) != 0 {
+			t.Fatalf("decrypted %v, expected %v", d, m)
+		}
+	}
+
+	// encryption is randomized
+	c1, _ := sk.Encrypt(rand.Reader, big.NewInt(5))
+	c2, _ := sk.Encrypt(rand.Reader, big.NewInt(5))
+	if c1.Cmp(c2) == 0 {
+		t.Fatalf("encryptions of the same plaintext are equal")
+	}
+}
+
+func TestHomomorphism(t *testing.T) {
+	sk, _ := GenerateKey(rand.Reader, 2048)
+	pk := &sk.PublicKey
+
+	a, _ := rand.Int(rand.Reader, sk.N)
+	b, _ := rand.Int(rand.Reader, sk.N)
+	k, _ := rand.Int(rand.Reader, sk.N)
+
+	ca, _ := pk.Encrypt(rand.Reader, a)
+	cb, _ := pk.Encrypt(rand.Reader, b)
+
+	check := func(name string, c *big.Int, expected *big.Int) {
+		d, err := sk.Decrypt(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d.Cmp(new(big.Int).Mod(expected, sk.N)) != 0 {
+			t.Fatalf("%s: decrypted %v, expected %v", name, d, expected)
+		}
+	}
+
+	check("Add", pk.Add(ca, cb), new(big.Int).Add(a, b))
+	check("AddPlaintext", pk.AddPlaintext(ca, b), new(big.Int).Add(a, b))
+	check("MulPlaintext", pk.MulPlaintext(ca, k), new(big.Int).Mul(a, k))
+	check("MulPlaintext negative", pk.MulPlaintext(ca, big.NewInt(-1)), new(big.Int).Neg(a))
+
+	r, _ := pk.Rerandomize(rand.Reader, ca)
+	if r.Cmp(ca) == 0 {
+		t.Fatalf("rerandomized ciphertext is unchanged")
+	}
+	check("Rerandomize", r, a)
+
+	// a public key rebuilt from the modulus is equivalent
+	pk2 := NewPublicKey(sk.N)
+	c, _ := pk2.Encrypt(rand.Reader, a)
+	check("NewPublicKey", c, a)
+}
+
+func TestInvalidInputs(t *testing.T) {
+	sk, _ := GenerateKey(rand.Reader, 2048)
+
+	if _, err := GenerateKey(rand.Reader, 1024); err != ErrKeySize {
+		t.Fatalf("expected ErrKeySize, got %v", err)
+	}
+	for _, m := range []*big.Int{big.NewInt(-1), sk.N} {
+		if _, err := sk.Encrypt(rand.Reader, m); err != ErrMessageOutOfRange {
+			t.Fatalf("expected ErrMessageOutOfRange for %v, got %v", m, err)
+		}
+	}
+
+	n2 := new(big.Int).Mul(sk.N, sk.N)
+	for _, c := range []*big.Int{nil, big.NewInt(0), n2, new(big.Int).Set(sk.N)} {
+		if _, err := sk.Decrypt(c); err != ErrInvalidCiphertext {
+			t.Fatalf("expected ErrInvalidCiphertext for %v, got %v", c, err)
+		}
+	}
+}
+
+func BenchmarkEncrypt(b *testing.B) {
+	sk, _ := GenerateKey(rand.Reader, 2048)
+	m := big.NewInt(42)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sk.Encrypt(rand.Reader, m)
+	}
+}
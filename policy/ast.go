@@ -0,0 +1,286 @@
+// Package policy parses text policies over the attributes of a schema,
+// such as
+//
+//	dept == "eng" AND (region IN ("eu", "us") OR level >= 4)
+//
+// and compiles them into constraint vectors for MasterKey.Constrain.
+//
+// Policies are built from comparisons of an attribute with literals
+// (==, !=, <, <=, >, >=, IN and NOT IN) combined with AND, OR, NOT and
+// parentheses. Keywords are case-insensitive. String literals are double
+// quoted with Go escapes, integers are decimal and booleans are true or
+// false.
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/sachaservan/cprf/schema"
+)
+
+var (
+	ErrSyntax      = errors.New("syntax error")
+	ErrType        = errors.New("type error")
+	ErrUnsupported = errors.New("policy cannot be expressed as an inner-product constraint")
+	ErrTooLarge    = errors.New("policy requires vectors longer than MaxLength")
+)
+
+// Op is a comparison operator
+type Op int
+
+const (
+	Eq Op = iota
+	Ne
+	Lt
+	Le
+	Gt
+	Ge
+	In
+	NotIn
+)
+
+var opStrings = map[Op]string{
+	Eq:    "==",
+	Ne:    "!=",
+	Lt:    "<",
+	Le:    "<=",
+	Gt:    ">",
+	Ge:    ">=",
+	In:    "IN",
+	NotIn: "NOT IN",
+}
+
+func (op Op) String() string {
+	if s, ok := opStrings[op]; ok {
+		return s
+	}
+	return fmt.Sprintf("Op(%d)", int(op))
+}
+
+// negate returns the operator of the negated comparison
+func (op Op) negate() Op {
+	switch op {
+	case Eq:
+		return Ne
+	case Ne:
+		return Eq
+	case Lt:
+		return Ge
+	case Le:
+		return Gt
+	case Gt:
+		return Le
+	case Ge:
+		return Lt
+	case In:
+		return NotIn
+	}
+	return In
+}
+
+// Expr is a policy expression: *And, *Or, *Not or *Comparison
+type Expr interface {
+	String() string
+
+	// Evaluate returns the value of the expression on a record
+	Evaluate(record schema.Record) (bool, error)
+}
+
+// And is the conjunction of two expressions
+type And struct {
+	Left  Expr
+	Right Expr
+}
+
+// Or is the disjunction of two expressions
+type Or struct {
+	Left  Expr
+	Right Expr
+}
+
+// Not is the negation of an expression
+type Not struct {
+	X Expr
+}
+
+// Comparison compares an attribute with literal values: a single value
+// for ==, !=, <, <=, > and >= and one or more values for IN and NOT IN.
+// Values are strings, int64s or bools.
+type Comparison struct {
+	Attribute string
+	Op        Op
+	Values    []interface{}
+}
+
+func (e *And) String() string {
+	return "(" + e.Left.String() + " AND " + e.Right.String() + ")"
+}
+
+func (e *Or) String() string {
+	return "(" + e.Left.String() + " OR " + e.Right.String() + ")"
+}
+
+func (e *Not) String() string {
+	return "NOT " + e.X.String()
+}
+
+func (e *Comparison) String() string {
+	values := make([]string, len(e.Values))
+	for i, v := range e.Values {
+		values[i] = literalString(v)
+	}
+
+	if e.Op == In || e.Op == NotIn {
+		return e.Attribute + " " + e.Op.String() + " (" + strings.Join(values, ", ") + ")"
+	}
+	return e.Attribute + " " + e.Op.String() + " " + strings.Join(values, ", ")
+}
+
+func (e *And) Evaluate(record schema.Record) (bool, error) {
+	left, err := e.Left.Evaluate(record)
+	if err != nil {
+		return false, err
+	}
+	right, err := e.Right.Evaluate(record)
+	if err != nil {
+		return false, err
+	}
+	return left && right, nil
+}
+
+func (e *Or) Evaluate(record schema.Record) (bool, error) {
+	left, err := e.Left.Evaluate(record)
+	if err != nil {
+		return false, err
+	}
+	right, err := e.Right.Evaluate(record)
+	if err != nil {
+		return false, err
+	}
+	return left || right, nil
+}
+
+func (e *Not) Evaluate(record schema.Record) (bool, error) {
+	x, err := e.X.Evaluate(record)
+	if err != nil {
+		return false, err
+	}
+	return !x, nil
+}
+
+func (e *Comparison) Evaluate(record schema.Record) (bool, error) {
+	v, ok := record[e.Attribute]
+	if !ok {
+		return false, fmt.Errorf("%w: %s", schema.ErrMissingAttribute, e.Attribute)
+	}
+	return compare(e.Op, v, e.Values)
+}
+
+// compare returns the value of the comparison v op values
+func compare(op Op, v interface{}, values []interface{}) (bool, error) {
+	if len(values) == 0 || (op != In && op != NotIn && len(values) != 1) {
+		return false, fmt.Errorf("%w: %s with %d values", ErrType, op, len(values))
+	}
+
+	switch op {
+	case Eq, Ne, In, NotIn:
+		found := false
+		for _, w := range values {
+			eq, err := equal(v, w)
+			if err != nil {
+				return false, err
+			}
+			found = found || eq
+		}
+		return found == (op == Eq || op == In), nil
+	}
+
+	a, aok := toInt64(v)
+	b, bok := toInt64(values[0])
+	if !aok || !bok {
+		return false, fmt.Errorf("%w: %s compares %T and %T", ErrType, op, v, values[0])
+	}
+
+	switch op {
+	case Lt:
+		return a < b, nil
+	case Le:
+		return a <= b, nil
+	case Gt:
+		return a > b, nil
+	case Ge:
+		return a >= b, nil
+	}
+
+	return false, fmt.Errorf("%w: unknown operator %d", ErrType, int(op))
+}
+
+// equal returns true if v and w are equal values of the same type
+func equal(v interface{}, w interface{}) (bool, error) {
+	if a, ok := toInt64(v); ok {
+		if b, ok := toInt64(w); ok {
+			return a == b, nil
+		}
+	}
+	switch a := v.(type) {
+	case string:
+		if b, ok := w.(string); ok {
+			return a == b, nil
+		}
+	case bool:
+		if b, ok := w.(bool); ok {
+			return a == b, nil
+		}
+	}
+	return false, fmt.Errorf("%w: cannot compare %T and %T", ErrType, v, w)
+}
+
+func toInt64(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), true
+	case int64:
+		return n, true
+	}
+	return 0, false
+}
+
+func literalString(v interface{}) string {
+	switch l := v.(type) {
+	case string:
+		return strconv.Quote(l)
+	case bool:
+		return strconv.FormatBool(l)
+	}
+	if n, ok := toInt64(v); ok {
+		return strconv.FormatInt(n, 10)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// nnf returns the negation normal form of e (or of NOT e if negate is
+// set), in which only comparisons are negated by flipping their operator
+func nnf(e Expr, negate bool) Expr {
+	switch e := e.(type) {
+	case *Not:
+		return nnf(e.X, !negate)
+	case *And:
+		if negate {
+			return &Or{Left: nnf(e.Left, true), Right: nnf(e.Right, true)}
+		}
+		return &And{Left: nnf(e.Left, false), Right: nnf(e.Right, false)}
+	case *Or:
+		if negate {
+			return &And{Left: nnf(e.Left, true), Right: nnf(e.Right, true)}
+		}
+		return &Or{Left: nnf(e.Left, false), Right: nnf(e.Right, false)}
+	case *Comparison:
+		if negate {
+			return &Comparison{Attribute: e.Attribute, Op: e.Op.negate(), Values: e.Values}
+		}
+	}
+	return e
+}
@@ -0,0 +1,336 @@
+package policy
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/sachaservan/cprf/predicates"
+	"github.com/sachaservan/cprf/schema"
+)
+
+// MaxLength is the largest vector length a compiled policy may require
+const MaxLength = 1 << 20
+
+// Constraint is a compiled policy. The key length is Length() and inputs
+// are encoded with Input, which tensors the schema encoding of a record
+// with itself Degree times.
+// Z: constraint vector for MasterKey.Constrain
+// Degree: tensor degree of the inputs
+// Exact: whether the constrained key authorizes exactly the records
+// satisfying the policy (up to collisions of hashed strings); otherwise
+// other records are authorized with probability at most ErrorProbability
+type Constraint struct {
+	Z      []*big.Int
+	Degree int
+	Exact  bool
+	folds  int
+	schema *schema.Schema
+	q      *big.Int
+}
+
+// form is a constraint vector on inputs of the given tensor degree;
+// the policy holds iff the inner product is zero. If bound is not nil
+// the inner product is an integer in [0, bound] on every record.
+type form struct {
+	z     []*big.Int
+	k     int
+	bound *big.Int
+}
+
+// Compile parses the policy and compiles it for records of schema s.
+//
+// Comparisons on enum, int and bool attributes compile to 0/1 mismatch
+// indicators over their one-hot slots, so they can be negated. Comparisons
+// on string attributes compile to set membership polynomials and only
+// == and IN (with at most Degree values) are supported, also after
+// pushing negations down to the comparisons.
+//
+// A conjunction of mismatch counts is their sum, which is exact as long as
+// the sum cannot reach q; otherwise the conjuncts are folded with random
+// coefficients (see predicates.FoldConstraints), which is not exact. A
+// disjunction is the tensor product of its operands (see
+// predicates.OrConstraint), which adds their degrees; operands of lower
+// degree are lifted using the constant slot of the schema.
+// q: prime inner product modulus
+func Compile(s *schema.Schema, policy string, q *big.Int) (*Constraint, error) {
+	e, err := Parse(policy)
+	if err != nil {
+		return nil, err
+	}
+	return CompileExpr(s, e, q)
+}
+
+// CompileExpr compiles a parsed policy for records of schema s (see Compile)
+func CompileExpr(s *schema.Schema, e Expr, q *big.Int) (*Constraint, error) {
+	if !q.ProbablyPrime(20) {
+		return nil, predicates.ErrModulusNotPrime
+	}
+
+	c := &Constraint{}
+	c.schema = s
+	c.q = q
+
+	f, err := c.compile(nnf(e, false))
+	if err != nil {
+		return nil, err
+	}
+
+	c.Z = f.z
+	c.Degree = f.k
+	c.Exact = c.folds == 0
+
+	return c, nil
+}
+
+// Length returns the length of the constraint and input vectors
+// (and hence the key length for KeyGen)
+func (c *Constraint) Length() int {
+	return len(c.Z)
+}
+
+// Schema returns the ID of the schema the policy was compiled for
+func (c *Constraint) Schema() string {
+	return c.schema.ID()
+}
+
+// ErrorProbability returns an upper bound on the probability that a
+// record not satisfying the policy is authorized, which is zero for
+// exact constraints and the number of folds divided by q otherwise
+func (c *Constraint) ErrorProbability() *big.Rat {
+	return new(big.Rat).SetFrac(big.NewInt(int64(c.folds)), c.q)
+}
+
+// Input returns the input vector for a record
+func (c *Constraint) Input(record schema.Record) ([]*big.Int, error) {
+	x, err := c.schema.Encode(record, c.q)
+	if err != nil {
+		return nil, err
+	}
+	return predicates.TensorInput(x, c.Degree, c.q), nil
+}
+
+func (c *Constraint) compile(e Expr) (*form, error) {
+	switch e := e.(type) {
+	case *And:
+		left, right, err := c.operands(e.Left, e.Right)
+		if err != nil {
+			return nil, err
+		}
+		return c.and(left, right)
+
+	case *Or:
+		left, right, err := c.operands(e.Left, e.Right)
+		if err != nil {
+			return nil, err
+		}
+		return c.or(left, right)
+
+	case *Comparison:
+		return c.comparison(e)
+	}
+
+	return nil, fmt.Errorf("%w: unexpected expression %v", ErrUnsupported, e)
+}
+
+func (c *Constraint) operands(left Expr, right Expr) (*form, *form, error) {
+	l, err := c.compile(left)
+	if err != nil {
+		return nil, nil, err
+	}
+	r, err := c.compile(right)
+	if err != nil {
+		return nil, nil, err
+	}
+	return l, r, nil
+}
+
+func (c *Constraint) and(a *form, b *form) (*form, error) {
+	k := a.k
+	if b.k > k {
+		k = b.k
+	}
+
+	za, err := c.lift(a, k)
+	if err != nil {
+		return nil, err
+	}
+	zb, err := c.lift(b, k)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &form{k: k}
+	if a.bound != nil && b.bound != nil {
+		bound := big.NewInt(0).Add(a.bound, b.bound)
+		if bound.Cmp(c.q) < 0 {
+			f.bound = bound
+			f.z = make([]*big.Int, len(za))
+			for i := range za {
+				f.z[i] = big.NewInt(0).Add(za[i], zb[i])
+				f.z[i].Mod(f.z[i], c.q)
+			}
+			return f, nil
+		}
+	}
+
+	f.z, err = predicates.FoldConstraints(c.q, za, zb)
+	if err != nil {
+		return nil, err
+	}
+	c.folds++
+
+	return f, nil
+}
+
+func (c *Constraint) or(a *form, b *form) (*form, error) {
+	if err := c.checkLength(a.k + b.k); err != nil {
+		return nil, err
+	}
+
+	// operands may have different degrees, so this is the
+	// tensor product of predicates.OrConstraint without lifting
+	z, err := predicates.Tensor(c.q, a.z, b.z)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &form{z: z, k: a.k + b.k}
+	if a.bound != nil && b.bound != nil {
+		bound := big.NewInt(0).Mul(a.bound, b.bound)
+		if bound.Cmp(c.q) < 0 {
+			f.bound = bound
+		}
+	}
+
+	return f, nil
+}
+
+// lift returns z ⊗ e ⊗ ... ⊗ e for the constant slot unit vector e,
+// which has the same inner product with inputs of degree k
+func (c *Constraint) lift(f *form, k int) ([]*big.Int, error) {
+	if f.k == k {
+		return f.z, nil
+	}
+	if err := c.checkLength(k); err != nil {
+		return nil, err
+	}
+
+	e := c.zero(c.schema.Length())
+	e[c.schema.ConstantSlot()].SetInt64(1)
+
+	vs := [][]*big.Int{f.z}
+	for i := f.k; i < k; i++ {
+		vs = append(vs, e)
+	}
+
+	return predicates.Tensor(c.q, vs...)
+}
+
+func (c *Constraint) checkLength(k int) error {
+	length := 1
+	for i := 0; i < k; i++ {
+		length *= c.schema.Length()
+		if length > MaxLength {
+			return fmt.Errorf("%w: degree %d on %d slots", ErrTooLarge, k, c.schema.Length())
+		}
+	}
+	return nil
+}
+
+func (c *Constraint) comparison(e *Comparison) (*form, error) {
+	a, offset, err := c.schema.Lookup(e.Attribute)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range e.Values {
+		if err := checkLiteral(a, v); err != nil {
+			return nil, err
+		}
+	}
+
+	z := c.zero(c.schema.Length())
+	f := &form{z: z, k: 1}
+
+	if a.Kind == schema.String {
+		if e.Op != Eq && e.Op != In {
+			return nil, fmt.Errorf("%w: %s on string attribute %s", ErrUnsupported, e.Op, a.Name)
+		}
+		if len(e.Values) > a.Degree {
+			return nil, fmt.Errorf("%w: %d values for string attribute %s of degree %d", ErrUnsupported, len(e.Values), a.Name, a.Degree)
+		}
+
+		set := make([]*big.Int, len(e.Values))
+		for i, v := range e.Values {
+			set[i] = schema.HashToField(v.(string), c.q)
+		}
+		coeffs, err := predicates.SetConstraint(set, a.Degree, c.q)
+		if err != nil {
+			return nil, err
+		}
+
+		// the string slots hold (h, ..., h^Degree) and the
+		// constant term goes to the constant slot
+		z[c.schema.ConstantSlot()].Set(coeffs[0])
+		for j := 1; j <= a.Degree; j++ {
+			z[offset+j-1].Set(coeffs[j])
+		}
+		return f, nil
+	}
+
+	// one-hot attributes: count the slots of values not satisfying the comparison
+	for j := 0; j < a.Width(); j++ {
+		ok, err := compare(e.Op, slotValue(a, j), e.Values)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			z[offset+j].SetInt64(1)
+		}
+	}
+	f.bound = big.NewInt(1)
+
+	return f, nil
+}
+
+func (c *Constraint) zero(length int) []*big.Int {
+	z := make([]*big.Int, length)
+	for i := range z {
+		z[i] = big.NewInt(0)
+	}
+	return z
+}
+
+// checkLiteral checks that v is a valid literal for attribute a
+func checkLiteral(a *schema.Attribute, v interface{}) error {
+	switch a.Kind {
+	case schema.Enum:
+		if _, err := a.Index(v); err != nil {
+			return fmt.Errorf("%w: %v", ErrType, err)
+		}
+	case schema.Int:
+		if _, ok := toInt64(v); !ok {
+			return fmt.Errorf("%w: %s expects an integer, got %s", ErrType, a.Name, literalString(v))
+		}
+	case schema.String:
+		if _, ok := v.(string); !ok {
+			return fmt.Errorf("%w: %s expects a string, got %s", ErrType, a.Name, literalString(v))
+		}
+	case schema.Bool:
+		if _, ok := v.(bool); !ok {
+			return fmt.Errorf("%w: %s expects a bool, got %s", ErrType, a.Name, literalString(v))
+		}
+	}
+	return nil
+}
+
+// slotValue returns the value encoded by slot j of a one-hot attribute
+func slotValue(a *schema.Attribute, j int) interface{} {
+	switch a.Kind {
+	case schema.Enum:
+		return a.Values[j]
+	case schema.Int:
+		return a.Min + int64(j)
+	}
+	return j == 1
+}
@@ -0,0 +1,151 @@
+package policy
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/sachaservan/cprf/predicates"
+	rocprf "github.com/sachaservan/cprf/ro-cprf"
+	"github.com/sachaservan/cprf/schema"
+)
+
+var testModulus, _ = big.NewInt(0).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFF61", 16)
+
+func testSchema(t *testing.T) *schema.Schema {
+	s, err := schema.New("employees", 1,
+		schema.EnumAttribute("dept", "eng", "sales", "ops"),
+		schema.IntAttribute("level", 1, 4),
+		schema.StringAttribute("region", 2),
+		schema.BoolAttribute("contractor"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// allRecords returns every record of the test schema,
+// with regions drawn from a small set
+func allRecords() []schema.Record {
+	records := make([]schema.Record, 0)
+	for _, dept := range []string{"eng", "sales", "ops"} {
+		for level := 1; level <= 4; level++ {
+			for _, region := range []string{"eu", "us", "ap"} {
+				for _, contractor := range []bool{false, true} {
+					records = append(records, schema.Record{
+						"dept":       dept,
+						"level":      level,
+						"region":     region,
+						"contractor": contractor,
+					})
+				}
+			}
+		}
+	}
+	return records
+}
+
+// checkTruthTable checks that the constrained key for the policy
+// authorizes exactly the records satisfying it
+func checkTruthTable(t *testing.T, s *schema.Schema, policy string) *Constraint {
+	c, err := Compile(s, policy, testModulus)
+	if err != nil {
+		t.Fatalf("%s: %v", policy, err)
+	}
+	e, _ := Parse(policy)
+
+	msk, _ := rocprf.KeyGen(testModulus, c.Length())
+	msk.SetSchema(c.Schema())
+	csk, err := msk.Constrain(c.Z)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Check(csk.Schema()); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, record := range allRecords() {
+		expected, err := e.Evaluate(record)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		x, err := c.Input(record)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Equal(msk.Eval(x), csk.CEval(x)) != expected {
+			t.Fatalf("%s: authorization of %v does not match the policy", policy, record)
+		}
+	}
+
+	return c
+}
+
+func TestCompileTruthTable(t *testing.T) {
+	s := testSchema(t)
+
+	for _, tc := range []struct {
+		policy string
+		degree int
+		exact  bool
+	}{
+		{`dept == "eng"`, 1, true},
+		{`dept != "eng"`, 1, true},
+		{`level >= 3`, 1, true},
+		{`level < 2 OR level > 3`, 2, true},
+		{`NOT (level <= 2 AND dept IN ("eng", "ops"))`, 2, true},
+		{`contractor == false AND level NOT IN (1, 4)`, 1, true},
+		{`region == "eu"`, 1, true},
+		{`region IN ("eu", "us")`, 1, true},
+		{`dept == "eng" AND region IN ("eu","us")`, 1, false},
+		{`NOT (region NOT IN ("ap") OR contractor == true)`, 1, false},
+		{`(dept == "eng" AND level >= 3) OR region == "ap"`, 2, true},
+		{`(dept == "sales" OR contractor == true) AND (level == 1 OR region == "us")`, 2, false},
+		{`dept == "ops" OR level == 4 OR contractor == true`, 3, true},
+	} {
+		c := checkTruthTable(t, s, tc.policy)
+		if c.Degree != tc.degree || c.Exact != tc.exact {
+			t.Fatalf("%s: got degree %d and exact %v, expected %d and %v", tc.policy, c.Degree, c.Exact, tc.degree, tc.exact)
+		}
+		if c.Length() != predicates.TensorLength(s.Length(), c.Degree) {
+			t.Fatalf("%s: unexpected length %d", tc.policy, c.Length())
+		}
+		if (c.ErrorProbability().Sign() == 0) != c.Exact {
+			t.Fatalf("%s: error probability %v for exact %v", tc.policy, c.ErrorProbability(), c.Exact)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	s := testSchema(t)
+
+	for _, tc := range []struct {
+		policy   string
+		expected error
+	}{
+		{`region != "eu"`, ErrUnsupported},
+		{`NOT region IN ("eu")`, ErrUnsupported},
+		{`NOT (dept == "eng" AND region == "eu")`, ErrUnsupported},
+		{`region IN ("eu", "us", "ap")`, ErrUnsupported},
+		{`region < "eu"`, ErrUnsupported},
+		{`dept < "eng"`, ErrType},
+		{`dept == "hr"`, ErrType},
+		{`level == "3"`, ErrType},
+		{`contractor == 1`, ErrType},
+		{`region == true`, ErrType},
+		{`team == "a"`, schema.ErrUnknownAttribute},
+		{`dept == "eng" OR dept == "eng" OR dept == "eng" OR dept == "eng" OR dept == "eng" OR dept == "eng"`, ErrTooLarge},
+		{`dept == `, ErrSyntax},
+	} {
+		if _, err := Compile(s, tc.policy, testModulus); !errors.Is(err, tc.expected) {
+			t.Fatalf("%s: expected %v, got %v", tc.policy, tc.expected, err)
+		}
+	}
+
+	if _, err := Compile(s, `dept == "eng"`, big.NewInt(1000)); err == nil {
+		t.Fatalf("expected an error for a modulus that is not prime")
+	}
+}
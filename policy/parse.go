@@ -0,0 +1,309 @@
+package policy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenInt
+	tokenOp
+	tokenLParen
+	tokenRParen
+	tokenComma
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenIn
+	tokenTrue
+	tokenFalse
+)
+
+var keywords = map[string]tokenKind{
+	"AND":   tokenAnd,
+	"OR":    tokenOr,
+	"NOT":   tokenNot,
+	"IN":    tokenIn,
+	"TRUE":  tokenTrue,
+	"FALSE": tokenFalse,
+}
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// lex splits the policy into tokens
+func lex(src string) ([]token, error) {
+	tokens := make([]token, 0)
+
+	for i := 0; i < len(src); {
+		c := rune(src[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+
+		case c == '(':
+			tokens = append(tokens, token{tokenLParen, "(", i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{tokenRParen, ")", i})
+			i++
+		case c == ',':
+			tokens = append(tokens, token{tokenComma, ",", i})
+			i++
+
+		case c == '=' || c == '!' || c == '<' || c == '>':
+			op := src[i : i+1]
+			if i+1 < len(src) && src[i+1] == '=' {
+				op = src[i : i+2]
+			}
+			if op == "=" || op == "!" {
+				return nil, fmt.Errorf("%w: unexpected %q at offset %d", ErrSyntax, op, i)
+			}
+			tokens = append(tokens, token{tokenOp, op, i})
+			i += len(op)
+
+		case c == '"':
+			j := i + 1
+			for j < len(src) && src[j] != '"' {
+				if src[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(src) {
+				return nil, fmt.Errorf("%w: unterminated string at offset %d", ErrSyntax, i)
+			}
+			s, err := strconv.Unquote(src[i : j+1])
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid string at offset %d", ErrSyntax, i)
+			}
+			tokens = append(tokens, token{tokenString, s, i})
+			i = j + 1
+
+		case c == '-' || unicode.IsDigit(c):
+			j := i + 1
+			for j < len(src) && unicode.IsDigit(rune(src[j])) {
+				j++
+			}
+			tokens = append(tokens, token{tokenInt, src[i:j], i})
+			i = j
+
+		case c == '_' || unicode.IsLetter(c):
+			j := i + 1
+			for j < len(src) && (src[j] == '_' || src[j] == '.' || unicode.IsLetter(rune(src[j])) || unicode.IsDigit(rune(src[j]))) {
+				j++
+			}
+			word := src[i:j]
+			kind, ok := keywords[strings.ToUpper(word)]
+			if !ok {
+				kind = tokenIdent
+			}
+			tokens = append(tokens, token{kind, word, i})
+			i = j
+
+		default:
+			return nil, fmt.Errorf("%w: unexpected %q at offset %d", ErrSyntax, c, i)
+		}
+	}
+
+	return append(tokens, token{tokenEOF, "", len(src)}), nil
+}
+
+// parser is a recursive descent parser for the grammar
+//
+//	expr       = and { OR and }
+//	and        = unary { AND unary }
+//	unary      = NOT unary | "(" expr ")" | comparison
+//	comparison = ident op literal | ident [ NOT ] IN "(" literal { "," literal } ")"
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// Parse parses a policy
+func Parse(src string) (Expr, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	e, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, p.unexpected(t)
+	}
+
+	return e, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) expect(kind tokenKind) (token, error) {
+	t := p.next()
+	if t.kind != kind {
+		return t, p.unexpected(t)
+	}
+	return t, nil
+}
+
+func (p *parser) unexpected(t token) error {
+	if t.kind == tokenEOF {
+		return fmt.Errorf("%w: unexpected end of policy", ErrSyntax)
+	}
+	return fmt.Errorf("%w: unexpected %q at offset %d", ErrSyntax, t.text, t.pos)
+}
+
+func (p *parser) expr() (Expr, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokenOr {
+		p.next()
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = &Or{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) and() (Expr, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokenAnd {
+		p.next()
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = &And{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) unary() (Expr, error) {
+	switch p.peek().kind {
+	case tokenNot:
+		p.next()
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &Not{X: x}, nil
+
+	case tokenLParen:
+		p.next()
+		e, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokenRParen); err != nil {
+			return nil, err
+		}
+		return e, nil
+	}
+
+	return p.comparison()
+}
+
+func (p *parser) comparison() (Expr, error) {
+	attr, err := p.expect(tokenIdent)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Comparison{Attribute: attr.text}
+
+	t := p.next()
+	switch t.kind {
+	case tokenOp:
+		for op, s := range opStrings {
+			if s == t.text {
+				c.Op = op
+			}
+		}
+		v, err := p.literal()
+		if err != nil {
+			return nil, err
+		}
+		c.Values = []interface{}{v}
+		return c, nil
+
+	case tokenNot:
+		if _, err := p.expect(tokenIn); err != nil {
+			return nil, err
+		}
+		c.Op = NotIn
+
+	case tokenIn:
+		c.Op = In
+
+	default:
+		return nil, p.unexpected(t)
+	}
+
+	if _, err := p.expect(tokenLParen); err != nil {
+		return nil, err
+	}
+	for {
+		v, err := p.literal()
+		if err != nil {
+			return nil, err
+		}
+		c.Values = append(c.Values, v)
+
+		t := p.next()
+		if t.kind == tokenRParen {
+			return c, nil
+		}
+		if t.kind != tokenComma {
+			return nil, p.unexpected(t)
+		}
+	}
+}
+
+func (p *parser) literal() (interface{}, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenString:
+		return t.text, nil
+	case tokenTrue:
+		return true, nil
+	case tokenFalse:
+		return false, nil
+	case tokenInt:
+		n, err := strconv.ParseInt(t.text, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid integer %q at offset %d", ErrSyntax, t.text, t.pos)
+		}
+		return n, nil
+	}
+	return nil, p.unexpected(t)
+}
@@ -0,0 +1,61 @@
+package policy
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	for _, tc := range []struct {
+		src      string
+		expected string
+	}{
+		{`dept == "eng"`, `dept == "eng"`},
+		{`dept == "eng" AND region IN ("eu","us")`, `(dept == "eng" AND region IN ("eu", "us"))`},
+		{`a == 1 OR b == 2 AND c == 3`, `(a == 1 OR (b == 2 AND c == 3))`},
+		{`(a == 1 OR b == 2) AND c == 3`, `((a == 1 OR b == 2) AND c == 3)`},
+		{`not a != -1 and b not in (true, false)`, `(NOT a != -1 AND b NOT IN (true, false))`},
+		{`level>=3 OR level<1`, `(level >= 3 OR level < 1)`},
+		{`name == "a \"quoted\" value"`, `name == "a \"quoted\" value"`},
+	} {
+		e, err := Parse(tc.src)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.src, err)
+		}
+		if e.String() != tc.expected {
+			t.Fatalf("%s: parsed as %s, expected %s", tc.src, e.String(), tc.expected)
+		}
+
+		// the string representation parses to the same expression
+		again, err := Parse(e.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if again.String() != e.String() {
+			t.Fatalf("%s does not round-trip", e.String())
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, src := range []string{
+		``,
+		`dept`,
+		`dept = "eng"`,
+		`dept == `,
+		`dept == "eng`,
+		`dept == "eng" AND`,
+		`(dept == "eng"`,
+		`dept == "eng")`,
+		`dept IN ()`,
+		`dept IN ("a" "b")`,
+		`dept NOT == "a"`,
+		`dept == eng`,
+		`dept == 99999999999999999999`,
+		`dept == "eng" # comment`,
+	} {
+		if _, err := Parse(src); !errors.Is(err, ErrSyntax) {
+			t.Fatalf("%q: expected ErrSyntax, got %v", src, err)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package predicates
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+)
+
+// FoldConstraints returns a constraint vector z = SUM_j r_j z_j for uniformly
+// random coefficients r_j, approximating the predicate
+// <z_1,x> = 0 AND ... AND <z_k,x> = 0 on vectors of the original length.
+//
+// Every input satisfying all constraints satisfies <z,x> = 0. An input that
+// violates at least one constraint also satisfies it with probability exactly
+// 1/q over the choice of the coefficients (see FoldErrorProbability), provided
+// the input is chosen independently of them. The coefficients are not
+// returned and should not be revealed to the holder of the constrained key.
+// q: prime inner product modulus
+// zs: constraint vectors of the same length
+func FoldConstraints(q *big.Int, zs ...[]*big.Int) ([]*big.Int, error) {
+	if !q.ProbablyPrime(20) {
+		return nil, ErrModulusNotPrime
+	}
+	if len(zs) == 0 {
+		return nil, ErrNoConstraints
+	}
+
+	length := len(zs[0])
+	z := make([]*big.Int, length)
+	for i := 0; i < length; i++ {
+		z[i] = big.NewInt(0)
+	}
+
+	tmp := big.NewInt(0)
+	for j, zj := range zs {
+		if len(zj) != length {
+			return nil, ErrLengthMismatch
+		}
+
+		r, err := rand.Int(rand.Reader, q)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate folding coefficient %d: %w", j, err)
+		}
+
+		for i := 0; i < length; i++ {
+			tmp.Mul(r, zj[i])
+			z[i].Add(z[i], tmp).Mod(z[i], q)
+		}
+	}
+
+	return z, nil
+}
+
+// FoldErrorProbability returns the probability 1/q that an input violating
+// some constraint satisfies a constraint built by FoldConstraints
+func FoldErrorProbability(q *big.Int) *big.Rat {
+	return new(big.Rat).SetFrac(big.NewInt(1), q)
+}
@@ -0,0 +1,79 @@
+package predicates
+
+import (
+	"crypto/elliptic"
+	"math/big"
+	"testing"
+
+	ddhcprf "github.com/sachaservan/cprf/ddh-cprf"
+	"github.com/sachaservan/cprf/linalg"
+	rocprf "github.com/sachaservan/cprf/ro-cprf"
+)
+
+func TestFoldConstraintsRO(t *testing.T) {
+	field, _ := linalg.NewField(roModulus)
+	length := 10
+
+	zs := make(linalg.Matrix, 3)
+	for i := range zs {
+		zs[i], _ = field.RandomVector(length)
+	}
+
+	z, err := FoldConstraints(roModulus, zs[0], zs[1], zs[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msk, _ := rocprf.KeyGen(roModulus, length)
+	csk, _ := msk.Constrain(z)
+
+	for trial := 0; trial < 10; trial++ {
+
+		// inputs in the intersection of the orthogonal complements
+		x, _ := field.SampleKernel(zs)
+		if !authorizedRO(msk, csk, x) {
+			t.Fatalf("input satisfying all constraints is not authorized")
+		}
+
+		// inputs satisfying only the first two constraints
+		y, _ := field.SampleKernel(zs[:2])
+		if authorizedRO(msk, csk, y) {
+			t.Fatalf("input violating a constraint is authorized")
+		}
+	}
+}
+
+func TestFoldConstraintsDDH(t *testing.T) {
+	p := elliptic.P256().Params().N
+	field, _ := linalg.NewField(p)
+	length := 10
+
+	zs := make(linalg.Matrix, 2)
+	for i := range zs {
+		zs[i], _ = field.RandomVector(length)
+	}
+
+	z, err := FoldConstraints(p, zs[0], zs[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pp, msk, _ := ddhcprf.KeyGen(128, length)
+	csk, _ := msk.Constrain(z)
+
+	x, _ := field.SampleKernel(zs)
+	if !authorizedDDH(pp, msk, csk, x) {
+		t.Fatalf("input satisfying all constraints is not authorized")
+	}
+
+	y, _ := field.SampleKernel(zs[1:])
+	if authorizedDDH(pp, msk, csk, y) {
+		t.Fatalf("input violating a constraint is authorized")
+	}
+}
+
+func TestFoldErrorProbability(t *testing.T) {
+	if FoldErrorProbability(big.NewInt(7)).Cmp(big.NewRat(1, 7)) != 0 {
+		t.Fatalf("unexpected false-positive probability")
+	}
+}
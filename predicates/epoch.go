@@ -0,0 +1,92 @@
+package predicates
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// Epoch-scoped keys append SetLength(degree) coordinates to the input
+// vector holding the powers (1, e, ..., e^degree) of the current epoch e,
+// and extend the caller's constraint with a set membership constraint on
+// them (see SetConstraint). A constrained key then only agrees with the
+// master key on inputs encoded for an epoch in its window.
+
+// EpochLength returns the key length for inputs of the given
+// length scoped to windows of up to degree epochs
+func EpochLength(length int, degree int) int {
+	return length + SetLength(degree)
+}
+
+// EpochOf returns the number of the epoch of the given period containing
+// t, counting from the Unix epoch. The time must be between 1970 and 2262.
+func EpochOf(t time.Time, period time.Duration) (uint64, error) {
+	if period <= 0 {
+		return 0, fmt.Errorf("%w: epoch period %v", ErrOutOfRange, period)
+	}
+
+	ns := t.UnixNano()
+	if ns < 0 || t.Year() >= 2262 {
+		return 0, fmt.Errorf("%w: time %v", ErrOutOfRange, t)
+	}
+
+	return uint64(ns) / uint64(period), nil
+}
+
+// EpochInput returns the input vector (x, 1, e, ..., e^degree) for x during epoch e
+func EpochInput(x []*big.Int, epoch uint64, degree int, q *big.Int) []*big.Int {
+	e := big.NewInt(0).SetUint64(epoch)
+
+	res := make([]*big.Int, 0, EpochLength(len(x), degree))
+	res = append(res, x...)
+	return append(res, SetInput(e, degree, q)...)
+}
+
+// EpochConstraint returns the constraint vector for the predicate
+// <z,x> = 0 AND e in epochs on inputs encoded by EpochInput.
+//
+// If z is nil the constraint only restricts the epoch and is exact.
+// Otherwise the two constraints are combined with FoldConstraints, so an
+// input violating <z,x> = 0 during a valid epoch (or vice versa) is
+// authorized with probability FoldErrorProbability(q).
+// z: constraint vector on the inputs x, or nil
+// length: length of the inputs x
+// epochs: epochs of the window, at most degree of them and all less than q
+// degree: maximum number of epochs in a window
+// q: prime inner product modulus
+func EpochConstraint(z []*big.Int, length int, epochs []uint64, degree int, q *big.Int) ([]*big.Int, error) {
+	if z != nil && len(z) != length {
+		return nil, ErrLengthMismatch
+	}
+
+	set := make([]*big.Int, len(epochs))
+	for i, epoch := range epochs {
+		set[i] = big.NewInt(0).SetUint64(epoch)
+		if set[i].Cmp(q) >= 0 {
+			return nil, fmt.Errorf("%w: epoch %d is not less than the modulus", ErrOutOfRange, epoch)
+		}
+	}
+
+	coeffs, err := SetConstraint(set, degree, q)
+	if err != nil {
+		return nil, err
+	}
+
+	ze := make([]*big.Int, 0, EpochLength(length, degree))
+	for i := 0; i < length; i++ {
+		ze = append(ze, big.NewInt(0))
+	}
+	ze = append(ze, coeffs...)
+
+	if z == nil {
+		return ze, nil
+	}
+
+	zx := make([]*big.Int, 0, len(ze))
+	zx = append(zx, z...)
+	for i := 0; i < SetLength(degree); i++ {
+		zx = append(zx, big.NewInt(0))
+	}
+
+	return FoldConstraints(q, zx, ze)
+}
@@ -0,0 +1,107 @@
+package predicates
+
+import (
+	"crypto/elliptic"
+	"math/big"
+	"testing"
+	"time"
+
+	ddhcprf "github.com/sachaservan/cprf/ddh-cprf"
+	rocprf "github.com/sachaservan/cprf/ro-cprf"
+)
+
+func TestEpochOf(t *testing.T) {
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	e, err := EpochOf(start, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next, _ := EpochOf(start.Add(time.Hour-time.Nanosecond), time.Hour); next != e {
+		t.Fatalf("epoch changed before the end of the period")
+	}
+	if next, _ := EpochOf(start.Add(time.Hour), time.Hour); next != e+1 {
+		t.Fatalf("epoch did not change at the end of the period")
+	}
+
+	if _, err := EpochOf(start, 0); err == nil {
+		t.Fatalf("expected an error for a zero period")
+	}
+	if _, err := EpochOf(time.Date(1969, 1, 1, 0, 0, 0, 0, time.UTC), time.Hour); err == nil {
+		t.Fatalf("expected an error for a time before the Unix epoch")
+	}
+}
+
+func TestEpochWindowRO(t *testing.T) {
+	period := 15 * time.Minute
+	degree := 4
+	start := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	// key valid from 9:00 to 10:00
+	first, _ := EpochOf(start, period)
+	window := []uint64{first, first + 1, first + 2, first + 3}
+
+	pattern := "1*0"
+	z, _ := BitPatternConstraint(pattern, roModulus)
+	length := BitPatternLength(len(pattern))
+
+	for _, zc := range [][]*big.Int{nil, z} {
+		ze, err := EpochConstraint(zc, length, window, degree, roModulus)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		msk, _ := rocprf.KeyGen(roModulus, EpochLength(length, degree))
+		csk, _ := msk.Constrain(ze)
+
+		// simulated clock from 8:00 to 11:00 in steps of 5 minutes
+		for now := start.Add(-time.Hour); now.Before(start.Add(2 * time.Hour)); now = now.Add(5 * time.Minute) {
+			epoch, _ := EpochOf(now, period)
+			inWindow := !now.Before(start) && now.Before(start.Add(time.Hour))
+
+			for _, s := range allStrings("01", len(pattern)) {
+				x, _ := BitInput(s)
+				authorized := authorizedRO(msk, csk, EpochInput(x, epoch, degree, roModulus))
+
+				expected := inWindow && (zc == nil || matches(pattern, s))
+				if authorized != expected {
+					t.Fatalf("at %v: authorization of %s is %v, expected %v", now.Format(time.Kitchen), s, authorized, expected)
+				}
+			}
+		}
+	}
+}
+
+func TestEpochConstraintErrors(t *testing.T) {
+	if _, err := EpochConstraint(nil, 3, []uint64{1, 2, 3}, 2, roModulus); err == nil {
+		t.Fatalf("expected an error for more epochs than the degree")
+	}
+	if _, err := EpochConstraint([]*big.Int{big.NewInt(1)}, 3, []uint64{1}, 2, roModulus); err == nil {
+		t.Fatalf("expected an error for a constraint of the wrong length")
+	}
+	if _, err := EpochConstraint(nil, 3, []uint64{11}, 2, big.NewInt(11)); err == nil {
+		t.Fatalf("expected an error for an epoch not less than the modulus")
+	}
+}
+
+func TestEpochWindowDDH(t *testing.T) {
+	p := elliptic.P256().Params().N
+	degree := 2
+	length := 3
+
+	ze, err := EpochConstraint(nil, length, []uint64{100, 101}, degree, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pp, msk, _ := ddhcprf.KeyGen(128, EpochLength(length, degree))
+	csk, _ := msk.Constrain(ze)
+
+	x, _ := BitInput("01")
+	for epoch := uint64(99); epoch <= 102; epoch++ {
+		expected := epoch == 100 || epoch == 101
+		if authorizedDDH(pp, msk, csk, EpochInput(x, epoch, degree, p)) != expected {
+			t.Fatalf("authorization in epoch %d does not match the window", epoch)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package predicates
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+var (
+	ErrOutOfRange = errors.New("parameter is out of range")
+)
+
+// The predicates below are over bit strings encoded with BitInput, i.e.,
+// x = (s_1, ..., s_l, 1), and have key length BitPatternLength(l).
+// The set-valued variants take the product of one linear form per allowed
+// value (see OrConstraint) and have key length TensorLength(l+1, m) for m
+// allowed values, on inputs TensorInput(BitInput(s), m, q).
+
+// HammingDistanceConstraint returns the constraint vector z such that
+// <z, BitInput(s)> = 0 iff the Hamming distance between s and w is exactly d.
+//
+// The inner product is dist(s, w) - d, where the distance is the linear form
+// SUM_{w_i = 0} s_i + SUM_{w_i = 1} (1 - s_i). It lies in [-l, l], so it is
+// zero mod q only if it is zero, which requires q > l.
+// w: reference bit string
+// d: distance, between 0 and len(w)
+// q: inner product modulus
+func HammingDistanceConstraint(w string, d int, q *big.Int) ([]*big.Int, error) {
+	if d < 0 || d > len(w) {
+		return nil, fmt.Errorf("%w: distance %d for strings of length %d", ErrOutOfRange, d, len(w))
+	}
+	if err := checkModulus(q, len(w)); err != nil {
+		return nil, err
+	}
+
+	z, err := distanceForm(w)
+	if err != nil {
+		return nil, err
+	}
+
+	return shiftForm(z, d, q), nil
+}
+
+// MatchCountConstraint returns the constraint vector z such that
+// <z, BitInput(s)> = 0 iff s and w agree in exactly k positions,
+// i.e., the Hamming distance between s and w is len(w) - k.
+func MatchCountConstraint(w string, k int, q *big.Int) ([]*big.Int, error) {
+	return HammingDistanceConstraint(w, len(w)-k, q)
+}
+
+// CountConstraint returns the constraint vector z such that
+// <z, BitInput(s)> = 0 iff exactly k of the bits of s at the given
+// positions are set ("exactly k of these attributes").
+// l: length of the bit strings
+// positions: distinct positions in [0, l); nil means all positions
+// k: number of set bits, between 0 and the number of positions
+// q: inner product modulus
+func CountConstraint(l int, positions []int, k int, q *big.Int) ([]*big.Int, error) {
+	z, err := countForm(l, positions)
+	if err != nil {
+		return nil, err
+	}
+
+	count := len(positions)
+	if positions == nil {
+		count = l
+	}
+	if k < 0 || k > count {
+		return nil, fmt.Errorf("%w: count %d of %d positions", ErrOutOfRange, k, count)
+	}
+	if err := checkModulus(q, l); err != nil {
+		return nil, err
+	}
+
+	return shiftForm(z, k, q), nil
+}
+
+// HammingDistanceSetConstraint returns the constraint vector z such that
+// <z, TensorInput(BitInput(s), len(ds), q)> = 0 iff the Hamming distance
+// between s and w is one of ds. Requires a prime q > len(w).
+func HammingDistanceSetConstraint(w string, ds []int, q *big.Int) ([]*big.Int, error) {
+	z, err := distanceForm(w)
+	if err != nil {
+		return nil, err
+	}
+	return formSetConstraint(z, ds, len(w), q)
+}
+
+// CountSetConstraint returns the constraint vector z such that
+// <z, TensorInput(BitInput(s), len(ks), q)> = 0 iff the number of set bits
+// of s at the given positions is one of ks. Requires a prime q > l.
+func CountSetConstraint(l int, positions []int, ks []int, q *big.Int) ([]*big.Int, error) {
+	z, err := countForm(l, positions)
+	if err != nil {
+		return nil, err
+	}
+
+	count := len(positions)
+	if positions == nil {
+		count = l
+	}
+	return formSetConstraint(z, ks, count, q)
+}
+
+// distanceForm returns the linear form computing dist(s, w) on BitInput(s)
+func distanceForm(w string) ([]*big.Int, error) {
+	l := len(w)
+	z := make([]*big.Int, l+1)
+	ones := 0
+	for i := 0; i < l; i++ {
+		switch w[i] {
+		case '0':
+			z[i] = big.NewInt(1)
+		case '1':
+			z[i] = big.NewInt(-1)
+			ones++
+		default:
+			return nil, fmt.Errorf("%w: unexpected symbol %q at position %d", ErrInvalidInput, w[i], i)
+		}
+	}
+	z[l] = big.NewInt(int64(ones))
+
+	return z, nil
+}
+
+// countForm returns the linear form computing the number of
+// set bits at the given positions on BitInput(s)
+func countForm(l int, positions []int) ([]*big.Int, error) {
+	z := make([]*big.Int, l+1)
+	for i := 0; i <= l; i++ {
+		z[i] = big.NewInt(0)
+	}
+
+	if positions == nil {
+		for i := 0; i < l; i++ {
+			z[i].SetInt64(1)
+		}
+		return z, nil
+	}
+
+	for _, i := range positions {
+		if i < 0 || i >= l || z[i].Sign() != 0 {
+			return nil, fmt.Errorf("%w: position %d", ErrOutOfRange, i)
+		}
+		z[i].SetInt64(1)
+	}
+
+	return z, nil
+}
+
+// shiftForm returns the linear form minus the constant v, reduced mod q.
+// The constant is subtracted from the last coordinate which is one on
+// every input.
+func shiftForm(form []*big.Int, v int, q *big.Int) []*big.Int {
+	z := make([]*big.Int, len(form))
+	for i := 0; i < len(form); i++ {
+		z[i] = big.NewInt(0).Mod(form[i], q)
+	}
+
+	last := len(form) - 1
+	z[last].Sub(z[last], big.NewInt(int64(v))).Mod(z[last], q)
+
+	return z
+}
+
+// formSetConstraint returns the tensor product of the linear form
+// shifted by each of the values, which vanishes iff the form takes
+// one of the values. The form takes values in [0, bound].
+func formSetConstraint(form []*big.Int, values []int, bound int, q *big.Int) ([]*big.Int, error) {
+	if len(values) == 0 {
+		return nil, ErrNoConstraints
+	}
+	if err := checkModulus(q, bound); err != nil {
+		return nil, err
+	}
+
+	zs := make([][]*big.Int, len(values))
+	for j, v := range values {
+		if v < 0 || v > bound {
+			return nil, fmt.Errorf("%w: value %d not in [0, %d]", ErrOutOfRange, v, bound)
+		}
+		zs[j] = shiftForm(form, v, q)
+	}
+
+	return OrConstraint(q, zs...)
+}
@@ -0,0 +1,145 @@
+package predicates
+
+import (
+	"math/big"
+	"testing"
+
+	rocprf "github.com/sachaservan/cprf/ro-cprf"
+)
+
+// distance returns the Hamming distance between two bit strings
+func distance(a, b string) int {
+	d := 0
+	for i := range a {
+		if a[i] != b[i] {
+			d++
+		}
+	}
+	return d
+}
+
+// countSet returns the number of set bits of s at the given positions
+func countSet(s string, positions []int) int {
+	c := 0
+	for _, i := range positions {
+		if s[i] == '1' {
+			c++
+		}
+	}
+	return c
+}
+
+func contains(values []int, v int) bool {
+	for _, u := range values {
+		if u == v {
+			return true
+		}
+	}
+	return false
+}
+
+// checkExhaustive checks that exactly the bit strings of length l satisfying
+// the predicate are authorized by a key constrained on z, where inputs are
+// the k-fold tensor power of BitInput(s)
+func checkExhaustive(t *testing.T, z []*big.Int, l int, k int, predicate func(s string) bool) {
+	msk, _ := rocprf.KeyGen(roModulus, TensorLength(BitPatternLength(l), k))
+	csk, _ := msk.Constrain(z)
+
+	for _, s := range allStrings("01", l) {
+		x, _ := BitInput(s)
+		if authorizedRO(msk, csk, TensorInput(x, k, roModulus)) != predicate(s) {
+			t.Fatalf("authorization of %s does not match the predicate", s)
+		}
+	}
+}
+
+func TestHammingDistanceRO(t *testing.T) {
+	w := "10110"
+	for d := 0; d <= len(w); d++ {
+		z, err := HammingDistanceConstraint(w, d, roModulus)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkExhaustive(t, z, len(w), 1, func(s string) bool {
+			return distance(s, w) == d
+		})
+	}
+}
+
+func TestMatchCountRO(t *testing.T) {
+	w := "0110"
+	for k := 0; k <= len(w); k++ {
+		z, err := MatchCountConstraint(w, k, roModulus)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkExhaustive(t, z, len(w), 1, func(s string) bool {
+			return len(w)-distance(s, w) == k
+		})
+	}
+}
+
+func TestCountRO(t *testing.T) {
+	l := 5
+	positions := []int{0, 2, 3}
+	for k := 0; k <= len(positions); k++ {
+		z, err := CountConstraint(l, positions, k, roModulus)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkExhaustive(t, z, l, 1, func(s string) bool {
+			return countSet(s, positions) == k
+		})
+	}
+
+	all := []int{0, 1, 2, 3, 4}
+	z, _ := CountConstraint(l, nil, 2, roModulus)
+	checkExhaustive(t, z, l, 1, func(s string) bool {
+		return countSet(s, all) == 2
+	})
+}
+
+func TestHammingDistanceSetRO(t *testing.T) {
+	w := "1100"
+	for _, ds := range [][]int{{1, 3}, {0, 2, 4}} {
+		z, err := HammingDistanceSetConstraint(w, ds, roModulus)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkExhaustive(t, z, len(w), len(ds), func(s string) bool {
+			return contains(ds, distance(s, w))
+		})
+	}
+}
+
+func TestCountSetRO(t *testing.T) {
+	l := 4
+	positions := []int{1, 2, 3}
+	ks := []int{0, 3}
+
+	z, err := CountSetConstraint(l, positions, ks, roModulus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkExhaustive(t, z, l, len(ks), func(s string) bool {
+		return contains(ks, countSet(s, positions))
+	})
+}
+
+func TestHammingErrors(t *testing.T) {
+	if _, err := HammingDistanceConstraint("101", 4, roModulus); err == nil {
+		t.Fatalf("expected an error for a distance larger than the length")
+	}
+	if _, err := CountConstraint(4, []int{1, 1}, 1, roModulus); err == nil {
+		t.Fatalf("expected an error for repeated positions")
+	}
+	if _, err := CountConstraint(4, []int{4}, 1, roModulus); err == nil {
+		t.Fatalf("expected an error for an out of range position")
+	}
+	if _, err := CountSetConstraint(4, nil, []int{1, 5}, roModulus); err == nil {
+		t.Fatalf("expected an error for an out of range count")
+	}
+	if _, err := HammingDistanceSetConstraint("10", nil, roModulus); err != ErrNoConstraints {
+		t.Fatalf("expected ErrNoConstraints, got %v", err)
+	}
+}
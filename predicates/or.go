@@ -0,0 +1,80 @@
+package predicates
+
+import (
+	"errors"
+	"math/big"
+)
+
+var (
+	ErrNoConstraints  = errors.New("at least one constraint vector is required")
+	ErrLengthMismatch = errors.New("vectors must have the same length")
+)
+
+// TensorLength returns the length of the k-fold tensor power of
+// vectors of the given length, i.e., length^k. This is the key
+// length required for an OR of k constraints on such vectors.
+func TensorLength(length int, k int) int {
+	res := 1
+	for i := 0; i < k; i++ {
+		res *= length
+	}
+	return res
+}
+
+// Tensor returns the tensor (Kronecker) product v_1 ⊗ ... ⊗ v_k mod q,
+// indexed so that the last vector varies fastest
+func Tensor(q *big.Int, vs ...[]*big.Int) ([]*big.Int, error) {
+	if len(vs) == 0 {
+		return nil, ErrNoConstraints
+	}
+
+	res := []*big.Int{big.NewInt(1)}
+	for _, v := range vs {
+		next := make([]*big.Int, 0, len(res)*len(v))
+		for _, a := range res {
+			for _, b := range v {
+				c := big.NewInt(0).Mul(a, b)
+				next = append(next, c.Mod(c, q))
+			}
+		}
+		res = next
+	}
+
+	return res, nil
+}
+
+// OrConstraint returns the constraint vector z = z_1 ⊗ ... ⊗ z_k for
+// the predicate <z_1,x> = 0 OR ... OR <z_k,x> = 0 on TensorInput(x, k).
+//
+// Since <z_1 ⊗ ... ⊗ z_k, x ⊗ ... ⊗ x> = PROD_i <z_i,x>, and q is prime,
+// the inner product is zero iff one of the factors is zero.
+// The key length is TensorLength(len(x), k).
+// q: prime inner product modulus
+// zs: constraint vectors of the same length
+func OrConstraint(q *big.Int, zs ...[]*big.Int) ([]*big.Int, error) {
+	if !q.ProbablyPrime(20) {
+		return nil, ErrModulusNotPrime
+	}
+	if len(zs) == 0 {
+		return nil, ErrNoConstraints
+	}
+	for i := 1; i < len(zs); i++ {
+		if len(zs[i]) != len(zs[0]) {
+			return nil, ErrLengthMismatch
+		}
+	}
+
+	return Tensor(q, zs...)
+}
+
+// TensorInput returns the input vector x ⊗ ... ⊗ x (k times) mod q
+// matching a constraint built by OrConstraint from k vectors
+func TensorInput(x []*big.Int, k int, q *big.Int) []*big.Int {
+	xs := make([][]*big.Int, k)
+	for i := 0; i < k; i++ {
+		xs[i] = x
+	}
+
+	res, _ := Tensor(q, xs...)
+	return res
+}
@@ -0,0 +1,119 @@
+package predicates
+
+import (
+	"crypto/elliptic"
+	"math/big"
+	"testing"
+
+	ddhcprf "github.com/sachaservan/cprf/ddh-cprf"
+	"github.com/sachaservan/cprf/linalg"
+	rocprf "github.com/sachaservan/cprf/ro-cprf"
+)
+
+func TestTensorInnerProduct(t *testing.T) {
+	field, _ := linalg.NewField(roModulus)
+
+	z1, _ := field.RandomVector(4)
+	z2, _ := field.RandomVector(4)
+	z3, _ := field.RandomVector(4)
+	x, _ := field.RandomVector(4)
+
+	z, _ := OrConstraint(roModulus, z1, z2, z3)
+	if len(z) != TensorLength(4, 3) {
+		t.Fatalf("tensor has length %d, expected %d", len(z), TensorLength(4, 3))
+	}
+
+	ip, _ := field.Dot(z, TensorInput(x, 3, roModulus))
+
+	expected := big.NewInt(1)
+	for _, zi := range [][]*big.Int{z1, z2, z3} {
+		d, _ := field.Dot(zi, x)
+		expected.Mul(expected, d).Mod(expected, roModulus)
+	}
+
+	if ip.Cmp(expected) != 0 {
+		t.Fatalf("inner product of tensors is not the product of inner products")
+	}
+}
+
+func TestOrConstraintRO(t *testing.T) {
+	field, _ := linalg.NewField(roModulus)
+
+	for _, params := range []struct{ length, k int }{
+		{5, 2},
+		{4, 3},
+	} {
+		zs := make([][]*big.Int, params.k)
+		for i := range zs {
+			zs[i], _ = field.RandomVector(params.length)
+		}
+
+		z, err := OrConstraint(roModulus, zs...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		msk, _ := rocprf.KeyGen(roModulus, TensorLength(params.length, params.k))
+		csk, _ := msk.Constrain(z)
+
+		// an input satisfying any one of the constraints is authorized
+		for i := range zs {
+			x, _ := field.SampleOrthogonal(zs[i])
+			if !authorizedRO(msk, csk, TensorInput(x, params.k, roModulus)) {
+				t.Fatalf("k=%d: input satisfying constraint %d is not authorized", params.k, i)
+			}
+		}
+
+		// very small probability of failure in this test case
+		x, _ := field.RandomVector(params.length)
+		if authorizedRO(msk, csk, TensorInput(x, params.k, roModulus)) {
+			t.Fatalf("k=%d: input satisfying no constraint is authorized", params.k)
+		}
+	}
+}
+
+func TestOrConstraintDDH(t *testing.T) {
+	p := elliptic.P256().Params().N
+	field, _ := linalg.NewField(p)
+
+	for _, params := range []struct{ length, k int }{
+		{4, 2},
+		{3, 3},
+	} {
+		zs := make([][]*big.Int, params.k)
+		for i := range zs {
+			zs[i], _ = field.RandomVector(params.length)
+		}
+
+		z, err := OrConstraint(p, zs...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		pp, msk, _ := ddhcprf.KeyGen(128, TensorLength(params.length, params.k))
+		csk, _ := msk.Constrain(z)
+
+		x, _ := field.SampleOrthogonal(zs[params.k-1])
+		if !authorizedDDH(pp, msk, csk, TensorInput(x, params.k, p)) {
+			t.Fatalf("k=%d: input satisfying the last constraint is not authorized", params.k)
+		}
+
+		// very small probability of failure in this test case
+		y, _ := field.RandomVector(params.length)
+		if authorizedDDH(pp, msk, csk, TensorInput(y, params.k, p)) {
+			t.Fatalf("k=%d: input satisfying no constraint is authorized", params.k)
+		}
+	}
+}
+
+func TestOrConstraintErrors(t *testing.T) {
+	z1 := []*big.Int{big.NewInt(1), big.NewInt(2)}
+	z2 := []*big.Int{big.NewInt(1)}
+
+	if _, err := OrConstraint(roModulus); err != ErrNoConstraints {
+		t.Fatalf("expected ErrNoConstraints, got %v", err)
+	}
+	if _, err := OrConstraint(roModulus, z1, z2); err != ErrLengthMismatch {
+		t.Fatalf("expected ErrLengthMismatch, got %v", err)
+	}
+}
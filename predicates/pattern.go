@@ -0,0 +1,181 @@
+// Package predicates encodes common predicates as inner-product
+// constraints: each encoder maps a predicate to a constraint vector z
+// and an input to a vector x such that <z, x> = 0 mod q exactly when
+// the input satisfies the predicate. The vectors can be passed to
+// MasterKey.Constrain and Eval of both rocprf and ddhcprf.
+package predicates
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+var (
+	ErrInvalidPattern  = errors.New("invalid pattern")
+	ErrInvalidInput    = errors.New("invalid input")
+	ErrInvalidAlphabet = errors.New("alphabet symbols must be distinct and must not contain the wildcard")
+	ErrModulusTooSmall = errors.New("modulus is too small for the predicate")
+)
+
+// Wildcard matches any symbol at its position in a pattern
+const Wildcard = '*'
+
+// BitPatternLength returns the length of the vectors that encode
+// bit strings of length l (and hence the key length for KeyGen)
+func BitPatternLength(l int) int {
+	return l + 1
+}
+
+// BitPatternConstraint returns the constraint vector z for a pattern
+// over {0, 1, *} such that <z, BitInput(s)> = 0 iff s matches the pattern.
+//
+// The input is encoded as x = (s_1, ..., s_l, 1) and the inner product
+// counts the fixed positions where s differs from the pattern:
+// SUM_{p_i = 0} s_i + SUM_{p_i = 1} (1 - s_i). The count is at most l,
+// so it is zero mod q only if it is zero, which requires q > l.
+// pattern: string over {0, 1, *}
+// q: inner product modulus
+func BitPatternConstraint(pattern string, q *big.Int) ([]*big.Int, error) {
+	l := len(pattern)
+	if err := checkModulus(q, l); err != nil {
+		return nil, err
+	}
+
+	z := make([]*big.Int, l+1)
+	ones := 0
+	for i := 0; i < l; i++ {
+		switch pattern[i] {
+		case '0':
+			z[i] = big.NewInt(1)
+		case '1':
+			z[i] = big.NewInt(-1)
+			ones++
+		case Wildcard:
+			z[i] = big.NewInt(0)
+		default:
+			return nil, fmt.Errorf("%w: unexpected symbol %q at position %d", ErrInvalidPattern, pattern[i], i)
+		}
+		z[i].Mod(z[i], q)
+	}
+	z[l] = big.NewInt(int64(ones))
+
+	return z, nil
+}
+
+// BitInput returns the input vector x = (s_1, ..., s_l, 1)
+// for a string s over {0, 1}
+func BitInput(s string) ([]*big.Int, error) {
+	x := make([]*big.Int, len(s)+1)
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '0':
+			x[i] = big.NewInt(0)
+		case '1':
+			x[i] = big.NewInt(1)
+		default:
+			return nil, fmt.Errorf("%w: unexpected symbol %q at position %d", ErrInvalidInput, s[i], i)
+		}
+	}
+	x[len(s)] = big.NewInt(1)
+
+	return x, nil
+}
+
+// Alphabet is a finite set of symbols for symbol string patterns
+type Alphabet struct {
+	symbols []rune
+	index   map[rune]int
+}
+
+// NewAlphabet returns the alphabet of the distinct symbols in symbols
+func NewAlphabet(symbols string) (*Alphabet, error) {
+	a := &Alphabet{}
+	a.index = make(map[rune]int)
+
+	for _, r := range symbols {
+		if _, ok := a.index[r]; ok || r == Wildcard {
+			return nil, ErrInvalidAlphabet
+		}
+		a.index[r] = len(a.symbols)
+		a.symbols = append(a.symbols, r)
+	}
+	if len(a.symbols) == 0 {
+		return nil, ErrInvalidAlphabet
+	}
+
+	return a, nil
+}
+
+// Size returns the number of symbols in the alphabet
+func (a *Alphabet) Size() int {
+	return len(a.symbols)
+}
+
+// PatternLength returns the length of the vectors that encode
+// symbol strings of length l (and hence the key length for KeyGen)
+func (a *Alphabet) PatternLength(l int) int {
+	return l * len(a.symbols)
+}
+
+// PatternConstraint returns the constraint vector z for a pattern over
+// the alphabet and the wildcard such that <z, Input(s)> = 0 iff s matches.
+//
+// Each symbol of the input is one-hot encoded and z has a one at every
+// symbol slot that differs from the pattern at a fixed position, so the
+// inner product counts mismatching positions. This requires q > l.
+// pattern: string over the alphabet and *
+// q: inner product modulus
+func (a *Alphabet) PatternConstraint(pattern string, q *big.Int) ([]*big.Int, error) {
+	runes := []rune(pattern)
+	if err := checkModulus(q, len(runes)); err != nil {
+		return nil, err
+	}
+
+	k := len(a.symbols)
+	z := make([]*big.Int, len(runes)*k)
+	for i, r := range runes {
+		fixed, ok := a.index[r]
+		if !ok && r != Wildcard {
+			return nil, fmt.Errorf("%w: unexpected symbol %q at position %d", ErrInvalidPattern, r, i)
+		}
+
+		for j := 0; j < k; j++ {
+			z[i*k+j] = big.NewInt(0)
+			if r != Wildcard && j != fixed {
+				z[i*k+j].SetInt64(1)
+			}
+		}
+	}
+
+	return z, nil
+}
+
+// Input returns the one-hot encoding of a string s over the alphabet
+func (a *Alphabet) Input(s string) ([]*big.Int, error) {
+	runes := []rune(s)
+
+	k := len(a.symbols)
+	x := make([]*big.Int, len(runes)*k)
+	for i, r := range runes {
+		index, ok := a.index[r]
+		if !ok {
+			return nil, fmt.Errorf("%w: unexpected symbol %q at position %d", ErrInvalidInput, r, i)
+		}
+
+		for j := 0; j < k; j++ {
+			x[i*k+j] = big.NewInt(0)
+		}
+		x[i*k+index].SetInt64(1)
+	}
+
+	return x, nil
+}
+
+// checkModulus checks that counts up to bound cannot wrap around mod q
+func checkModulus(q *big.Int, bound int) error {
+	if q.Cmp(big.NewInt(int64(bound))) <= 0 {
+		return ErrModulusTooSmall
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package predicates
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"math/big"
+	"testing"
+
+	ddhcprf "github.com/sachaservan/cprf/ddh-cprf"
+	"github.com/sachaservan/cprf/ddh-cprf/ec"
+	rocprf "github.com/sachaservan/cprf/ro-cprf"
+)
+
+var roModulus, _ = big.NewInt(0).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFF61", 16)
+
+// authorizedRO returns true if Eval == CEval under rocprf
+func authorizedRO(msk *rocprf.MasterKey, csk *rocprf.ConstrainedKey, x []*big.Int) bool {
+	return bytes.Equal(msk.Eval(x), csk.CEval(x))
+}
+
+// authorizedDDH returns true if Eval == CEval under ddhcprf
+func authorizedDDH(pp *ddhcprf.PublicParameters, msk *ddhcprf.MasterKey, csk *ddhcprf.ConstrainedKey, x []*big.Int) bool {
+	return ec.PointsEqual(msk.Eval(pp, x), csk.CEval(pp, x))
+}
+
+// allStrings returns all strings of length l over the symbols
+func allStrings(symbols string, l int) []string {
+	res := []string{""}
+	for i := 0; i < l; i++ {
+		next := make([]string, 0, len(res)*len(symbols))
+		for _, s := range res {
+			for _, r := range symbols {
+				next = append(next, s+string(r))
+			}
+		}
+		res = next
+	}
+	return res
+}
+
+// matches returns true if s matches the wildcard pattern
+func matches(pattern string, s string) bool {
+	p, r := []rune(pattern), []rune(s)
+	if len(p) != len(r) {
+		return false
+	}
+	for i := range p {
+		if p[i] != Wildcard && p[i] != r[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestBitPatternRO(t *testing.T) {
+	for _, pattern := range []string{"1*0**1", "******", "010110", "1*****"} {
+		z, err := BitPatternConstraint(pattern, roModulus)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		msk, _ := rocprf.KeyGen(roModulus, BitPatternLength(len(pattern)))
+		csk, _ := msk.Constrain(z)
+
+		for _, s := range allStrings("01", len(pattern)) {
+			x, _ := BitInput(s)
+			if authorizedRO(msk, csk, x) != matches(pattern, s) {
+				t.Fatalf("pattern %s: authorization of %s does not match the predicate", pattern, s)
+			}
+		}
+	}
+}
+
+func TestBitPatternDDH(t *testing.T) {
+	p := elliptic.P256().Params().N
+	pattern := "1*0*"
+
+	z, err := BitPatternConstraint(pattern, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pp, msk, _ := ddhcprf.KeyGen(128, BitPatternLength(len(pattern)))
+	csk, _ := msk.Constrain(z)
+
+	for _, s := range allStrings("01", len(pattern)) {
+		x, _ := BitInput(s)
+		if authorizedDDH(pp, msk, csk, x) != matches(pattern, s) {
+			t.Fatalf("pattern %s: authorization of %s does not match the predicate", pattern, s)
+		}
+	}
+}
+
+func TestSymbolPatternRO(t *testing.T) {
+	alphabet, _ := NewAlphabet("abc")
+
+	for _, pattern := range []string{"a*c", "***", "bca", "*b*"} {
+		z, err := alphabet.PatternConstraint(pattern, roModulus)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		msk, _ := rocprf.KeyGen(roModulus, alphabet.PatternLength(len(pattern)))
+		csk, _ := msk.Constrain(z)
+
+		for _, s := range allStrings("abc", len(pattern)) {
+			x, _ := alphabet.Input(s)
+			if authorizedRO(msk, csk, x) != matches(pattern, s) {
+				t.Fatalf("pattern %s: authorization of %s does not match the predicate", pattern, s)
+			}
+		}
+	}
+}
+
+func TestSymbolPatternDDH(t *testing.T) {
+	p := elliptic.P256().Params().N
+	alphabet, _ := NewAlphabet("xy")
+	pattern := "x*y"
+
+	z, err := alphabet.PatternConstraint(pattern, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pp, msk, _ := ddhcprf.KeyGen(128, alphabet.PatternLength(len(pattern)))
+	csk, _ := msk.Constrain(z)
+
+	for _, s := range allStrings("xy", len(pattern)) {
+		x, _ := alphabet.Input(s)
+		if authorizedDDH(pp, msk, csk, x) != matches(pattern, s) {
+			t.Fatalf("pattern %s: authorization of %s does not match the predicate", pattern, s)
+		}
+	}
+}
+
+func TestPatternErrors(t *testing.T) {
+	if _, err := BitPatternConstraint("10x", roModulus); err == nil {
+		t.Fatalf("expected an error for an invalid bit pattern")
+	}
+	if _, err := BitInput("1*0"); err == nil {
+		t.Fatalf("expected an error for an invalid bit string")
+	}
+	if _, err := BitPatternConstraint("101", big.NewInt(3)); err != ErrModulusTooSmall {
+		t.Fatalf("expected ErrModulusTooSmall, got %v", err)
+	}
+	if _, err := NewAlphabet("ab*"); err != ErrInvalidAlphabet {
+		t.Fatalf("expected ErrInvalidAlphabet, got %v", err)
+	}
+	if _, err := NewAlphabet("aba"); err != ErrInvalidAlphabet {
+		t.Fatalf("expected ErrInvalidAlphabet, got %v", err)
+	}
+
+	alphabet, _ := NewAlphabet("ab")
+	if _, err := alphabet.PatternConstraint("a*c", roModulus); err == nil {
+		t.Fatalf("expected an error for a symbol outside the alphabet")
+	}
+	if _, err := alphabet.Input("abc"); err == nil {
+		t.Fatalf("expected an error for a symbol outside the alphabet")
+	}
+}
@@ -0,0 +1,280 @@
+package predicates
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"net/netip"
+	"strings"
+)
+
+// BitPrefixConstraint returns the constraint vector z for all bit strings
+// of the given width starting with prefix, on inputs encoded by BitInput.
+// The key length is BitPatternLength(width).
+// prefix: bit string over {0, 1} of length at most width
+// width: length of the bit strings
+// q: inner product modulus
+func BitPrefixConstraint(prefix string, width int, q *big.Int) ([]*big.Int, error) {
+	if len(prefix) > width {
+		return nil, fmt.Errorf("%w: prefix %q is longer than %d bits", ErrInvalidPattern, prefix, width)
+	}
+	if strings.ContainsRune(prefix, Wildcard) {
+		return nil, fmt.Errorf("%w: prefix %q contains a wildcard", ErrInvalidPattern, prefix)
+	}
+	return BitPatternConstraint(prefix+strings.Repeat(string(Wildcard), width-len(prefix)), q)
+}
+
+// AddrLength returns the key length for addresses of the
+// given family: BitPatternLength(32) for IPv4 and
+// BitPatternLength(128) for IPv6
+func AddrLength(addr netip.Addr) int {
+	return BitPatternLength(addr.BitLen())
+}
+
+// AddrPrefixConstraint returns the constraint vector z for all addresses
+// in the CIDR prefix (e.g., 10.1.0.0/16) on inputs encoded by AddrInput.
+// IPv4 and IPv6 prefixes use vectors of different lengths.
+func AddrPrefixConstraint(prefix netip.Prefix, q *big.Int) ([]*big.Int, error) {
+	if !prefix.IsValid() {
+		return nil, fmt.Errorf("%w: invalid prefix %v", ErrInvalidPattern, prefix)
+	}
+
+	bits := addrBits(prefix.Masked().Addr())
+	return BitPrefixConstraint(bits[:prefix.Bits()], len(bits), q)
+}
+
+// AddrInput returns the input vector for an IPv4 or IPv6 address.
+// IPv4-mapped IPv6 addresses are encoded as IPv6 addresses.
+func AddrInput(addr netip.Addr) ([]*big.Int, error) {
+	if !addr.IsValid() {
+		return nil, fmt.Errorf("%w: invalid address %v", ErrInvalidInput, addr)
+	}
+	return BitInput(addrBits(addr))
+}
+
+// addrBits returns the bits of the address as a string over {0, 1}
+func addrBits(addr netip.Addr) string {
+	var sb strings.Builder
+	for _, b := range addr.AsSlice() {
+		fmt.Fprintf(&sb, "%08b", b)
+	}
+	return sb.String()
+}
+
+// DefaultComponentBits is the default number of hash bits per component
+const DefaultComponentBits = 64
+
+// Hierarchy encodes hierarchical identifiers, i.e., sequences of up to
+// MaxDepth components such as the segments of a path or the labels of a
+// DNS name (from the root), into fixed-length vectors.
+//
+// The input vector is the concatenation of a one-hot encoding of the depth
+// (MaxDepth+1 slots), the ComponentBits low bits of a hash of each component
+// (zero for absent components) and a constant one. Constraints fix the hash
+// bits of the components of a prefix and restrict the depth to a range, so
+// the inner product counts mismatching bits. Distinct components collide
+// with probability 2^-ComponentBits.
+type Hierarchy struct {
+	MaxDepth      int
+	ComponentBits int
+}
+
+// NewHierarchy returns an encoder for identifiers of up to maxDepth
+// components using DefaultComponentBits hash bits per component
+func NewHierarchy(maxDepth int) *Hierarchy {
+	return &Hierarchy{MaxDepth: maxDepth, ComponentBits: DefaultComponentBits}
+}
+
+// Length returns the length of the encoded vectors (and hence the
+// key length for KeyGen)
+func (h *Hierarchy) Length() int {
+	return (h.MaxDepth + 1) + h.MaxDepth*h.ComponentBits + 1
+}
+
+// Input returns the input vector for the identifier with the given components
+func (h *Hierarchy) Input(components []string) ([]*big.Int, error) {
+	if err := h.check(); err != nil {
+		return nil, err
+	}
+	if len(components) > h.MaxDepth {
+		return nil, fmt.Errorf("%w: %d components, at most %d supported", ErrInvalidInput, len(components), h.MaxDepth)
+	}
+
+	x := make([]*big.Int, h.Length())
+	for i := range x {
+		x[i] = big.NewInt(0)
+	}
+
+	x[len(components)].SetInt64(1)
+	for i, c := range components {
+		bits := h.componentBits(c)
+		for j, b := range bits {
+			if b {
+				x[h.bitIndex(i, j)].SetInt64(1)
+			}
+		}
+	}
+	x[len(x)-1].SetInt64(1)
+
+	return x, nil
+}
+
+// Constraint returns the constraint vector for identifiers whose first
+// components match prefix and whose depth is between minDepth and maxDepth.
+// A prefix component equal to the wildcard "*" matches any component.
+func (h *Hierarchy) Constraint(prefix []string, minDepth int, maxDepth int, q *big.Int) ([]*big.Int, error) {
+	if err := h.check(); err != nil {
+		return nil, err
+	}
+	if minDepth < len(prefix) || maxDepth < minDepth || maxDepth > h.MaxDepth {
+		return nil, fmt.Errorf("%w: depth range [%d, %d] for a prefix of %d components", ErrOutOfRange, minDepth, maxDepth, len(prefix))
+	}
+	if err := checkModulus(q, h.Length()); err != nil {
+		return nil, err
+	}
+
+	z := make([]*big.Int, h.Length())
+	for i := range z {
+		z[i] = big.NewInt(0)
+	}
+
+	// depths outside the range count as a mismatch
+	for d := 0; d <= h.MaxDepth; d++ {
+		if d < minDepth || d > maxDepth {
+			z[d].SetInt64(1)
+		}
+	}
+
+	// bit fixing on the hashes of the prefix components
+	ones := 0
+	for i, c := range prefix {
+		if c == string(Wildcard) {
+			continue
+		}
+		for j, b := range h.componentBits(c) {
+			if b {
+				z[h.bitIndex(i, j)].Sub(q, big.NewInt(1))
+				ones++
+			} else {
+				z[h.bitIndex(i, j)].SetInt64(1)
+			}
+		}
+	}
+	z[len(z)-1].SetInt64(int64(ones))
+
+	return z, nil
+}
+
+// PathInput returns the input vector for a slash-separated path
+// such as /tenants/acme/logs
+func (h *Hierarchy) PathInput(path string) ([]*big.Int, error) {
+	return h.Input(splitPath(path))
+}
+
+// PathConstraint returns the constraint vector for a path pattern.
+// Segments equal to * match any single segment and a final /** matches
+// any number of further segments, e.g., /tenants/acme/** matches
+// /tenants/acme and every path below it. Otherwise the depth is exact.
+func (h *Hierarchy) PathConstraint(pattern string, q *big.Int) ([]*big.Int, error) {
+	prefix := splitPath(pattern)
+
+	maxDepth := len(prefix)
+	if len(prefix) > 0 && prefix[len(prefix)-1] == "**" {
+		prefix = prefix[:len(prefix)-1]
+		maxDepth = h.MaxDepth
+	}
+	for _, c := range prefix {
+		if c == "**" {
+			return nil, fmt.Errorf("%w: ** is only supported at the end of %q", ErrInvalidPattern, pattern)
+		}
+	}
+
+	return h.Constraint(prefix, len(prefix), maxDepth, q)
+}
+
+// DNSInput returns the input vector for a DNS name such as www.corp.example.
+// Labels are compared case-insensitively and encoded from the root.
+func (h *Hierarchy) DNSInput(name string) ([]*big.Int, error) {
+	labels := splitDNS(name)
+	for _, l := range labels {
+		if l == string(Wildcard) {
+			return nil, fmt.Errorf("%w: wildcard in name %q", ErrInvalidInput, name)
+		}
+	}
+	return h.Input(labels)
+}
+
+// DNSConstraint returns the constraint vector for a DNS name pattern.
+// A leading *. matches every name strictly below the rest of the pattern,
+// e.g., *.corp.example matches a.corp.example and a.b.corp.example but
+// not corp.example itself. Otherwise the pattern matches a single name.
+func (h *Hierarchy) DNSConstraint(pattern string, q *big.Int) ([]*big.Int, error) {
+	prefix := splitDNS(pattern)
+
+	minDepth, maxDepth := len(prefix), len(prefix)
+	if len(prefix) > 0 && prefix[len(prefix)-1] == string(Wildcard) {
+		prefix = prefix[:len(prefix)-1]
+		minDepth, maxDepth = len(prefix)+1, h.MaxDepth
+	}
+	for _, l := range prefix {
+		if l == string(Wildcard) {
+			return nil, fmt.Errorf("%w: * is only supported as the first label of %q", ErrInvalidPattern, pattern)
+		}
+	}
+
+	return h.Constraint(prefix, minDepth, maxDepth, q)
+}
+
+func (h *Hierarchy) check() error {
+	if h.MaxDepth <= 0 || h.ComponentBits <= 0 || h.ComponentBits > 256 {
+		return fmt.Errorf("%w: hierarchy with depth %d and %d bits per component", ErrOutOfRange, h.MaxDepth, h.ComponentBits)
+	}
+	return nil
+}
+
+// bitIndex returns the position of bit j of component i in the vector
+func (h *Hierarchy) bitIndex(i int, j int) int {
+	return (h.MaxDepth + 1) + i*h.ComponentBits + j
+}
+
+// componentBits returns the first ComponentBits bits of the
+// hash of a component
+func (h *Hierarchy) componentBits(c string) []bool {
+	hasher := sha256.New()
+	hasher.Write([]byte("cprf hierarchy component"))
+	binary.Write(hasher, binary.BigEndian, uint64(len(c)))
+	hasher.Write([]byte(c))
+	hash := hasher.Sum(nil)
+
+	bits := make([]bool, h.ComponentBits)
+	for j := 0; j < h.ComponentBits; j++ {
+		bits[j] = (hash[j/8]>>uint(7-j%8))&1 == 1
+	}
+	return bits
+}
+
+// splitPath returns the non-empty segments of a slash-separated path
+func splitPath(path string) []string {
+	segments := make([]string, 0)
+	for _, s := range strings.Split(path, "/") {
+		if s != "" {
+			segments = append(segments, s)
+		}
+	}
+	return segments
+}
+
+// splitDNS returns the lower-cased labels of a DNS name from the root
+func splitDNS(name string) []string {
+	name = strings.TrimSuffix(strings.ToLower(name), ".")
+	if name == "" {
+		return []string{}
+	}
+
+	labels := strings.Split(name, ".")
+	for i, j := 0, len(labels)-1; i < j; i, j = i+1, j-1 {
+		labels[i], labels[j] = labels[j], labels[i]
+	}
+	return labels
+}
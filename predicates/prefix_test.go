@@ -0,0 +1,233 @@
+package predicates
+
+import (
+	"crypto/elliptic"
+	"math/big"
+	"net/netip"
+	"strings"
+	"testing"
+
+	ddhcprf "github.com/sachaservan/cprf/ddh-cprf"
+	rocprf "github.com/sachaservan/cprf/ro-cprf"
+)
+
+func TestBitPrefixRO(t *testing.T) {
+	width := 5
+	msk, _ := rocprf.KeyGen(roModulus, BitPatternLength(width))
+
+	for l := 0; l <= width; l++ {
+		for _, prefix := range allStrings("01", l) {
+			z, err := BitPrefixConstraint(prefix, width, roModulus)
+			if err != nil {
+				t.Fatal(err)
+			}
+			csk, _ := msk.Constrain(z)
+
+			for _, s := range allStrings("01", width) {
+				x, _ := BitInput(s)
+				if authorizedRO(msk, csk, x) != strings.HasPrefix(s, prefix) {
+					t.Fatalf("prefix %q: authorization of %s does not match the predicate", prefix, s)
+				}
+			}
+		}
+	}
+
+	if _, err := BitPrefixConstraint("010101", width, roModulus); err == nil {
+		t.Fatalf("expected an error for a prefix longer than the width")
+	}
+}
+
+func TestAddrPrefixRO(t *testing.T) {
+	for _, cidr := range []string{"10.1.2.0/24", "10.1.2.64/26", "10.1.2.7/32", "10.0.0.0/8", "0.0.0.0/0"} {
+		prefix := netip.MustParsePrefix(cidr)
+		z, err := AddrPrefixConstraint(prefix, roModulus)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		msk, _ := rocprf.KeyGen(roModulus, AddrLength(prefix.Addr()))
+		csk, _ := msk.Constrain(z)
+
+		// every address in 10.1.2.0/23 and a few outside of 10.0.0.0/8
+		addrs := []netip.Addr{netip.MustParseAddr("11.1.2.3"), netip.MustParseAddr("192.168.0.1")}
+		for a := netip.MustParseAddr("10.1.2.0"); a.Less(netip.MustParseAddr("10.1.4.0")); a = a.Next() {
+			addrs = append(addrs, a)
+		}
+
+		for _, addr := range addrs {
+			x, _ := AddrInput(addr)
+			if authorizedRO(msk, csk, x) != prefix.Contains(addr) {
+				t.Fatalf("prefix %v: authorization of %v does not match the predicate", prefix, addr)
+			}
+		}
+	}
+}
+
+func TestAddrPrefixIPv6RO(t *testing.T) {
+	prefix := netip.MustParsePrefix("2001:db8::ff00/121")
+	z, err := AddrPrefixConstraint(prefix, roModulus)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msk, _ := rocprf.KeyGen(roModulus, AddrLength(prefix.Addr()))
+	csk, _ := msk.Constrain(z)
+
+	for a := netip.MustParseAddr("2001:db8::fe00"); a.Less(netip.MustParseAddr("2001:db8::1:0")); a = a.Next() {
+		x, _ := AddrInput(a)
+		if authorizedRO(msk, csk, x) != prefix.Contains(a) {
+			t.Fatalf("prefix %v: authorization of %v does not match the predicate", prefix, a)
+		}
+	}
+
+	if len(z) != BitPatternLength(128) {
+		t.Fatalf("expected an IPv6 constraint of length %d, got %d", BitPatternLength(128), len(z))
+	}
+}
+
+// allPaths returns all component sequences of depth at most
+// maxDepth over the given components
+func allPaths(components []string, maxDepth int) [][]string {
+	res := [][]string{{}}
+	level := [][]string{{}}
+	for d := 1; d <= maxDepth; d++ {
+		next := make([][]string, 0)
+		for _, p := range level {
+			for _, c := range components {
+				next = append(next, append(append([]string{}, p...), c))
+			}
+		}
+		res = append(res, next...)
+		level = next
+	}
+	return res
+}
+
+func TestPathConstraintRO(t *testing.T) {
+	h := NewHierarchy(3)
+	msk, _ := rocprf.KeyGen(roModulus, h.Length())
+
+	for _, tc := range []struct {
+		pattern   string
+		predicate func(p []string) bool
+	}{
+		{"/a/**", func(p []string) bool { return len(p) >= 1 && p[0] == "a" }},
+		{"/a/b/**", func(p []string) bool { return len(p) >= 2 && p[0] == "a" && p[1] == "b" }},
+		{"/a/b", func(p []string) bool { return len(p) == 2 && p[0] == "a" && p[1] == "b" }},
+		{"/*/c", func(p []string) bool { return len(p) == 2 && p[1] == "c" }},
+		{"/*/c/**", func(p []string) bool { return len(p) >= 2 && p[1] == "c" }},
+		{"/", func(p []string) bool { return len(p) == 0 }},
+		{"/**", func(p []string) bool { return true }},
+	} {
+		z, err := h.PathConstraint(tc.pattern, roModulus)
+		if err != nil {
+			t.Fatal(err)
+		}
+		csk, _ := msk.Constrain(z)
+
+		for _, p := range allPaths([]string{"a", "b", "c"}, h.MaxDepth) {
+			path := "/" + strings.Join(p, "/")
+			x, err := h.PathInput(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if authorizedRO(msk, csk, x) != tc.predicate(p) {
+				t.Fatalf("pattern %s: authorization of %s does not match the predicate", tc.pattern, path)
+			}
+		}
+	}
+}
+
+func TestDNSConstraintRO(t *testing.T) {
+	h := NewHierarchy(4)
+	msk, _ := rocprf.KeyGen(roModulus, h.Length())
+
+	for _, tc := range []struct {
+		pattern   string
+		predicate func(name string) bool
+	}{
+		{"*.corp.example", func(name string) bool { return strings.HasSuffix(name, ".corp.example") }},
+		{"corp.example", func(name string) bool { return name == "corp.example" }},
+		{"*.example", func(name string) bool { return strings.HasSuffix(name, ".example") }},
+		{"www.corp.example", func(name string) bool { return name == "www.corp.example" }},
+	} {
+		z, err := h.DNSConstraint(tc.pattern, roModulus)
+		if err != nil {
+			t.Fatal(err)
+		}
+		csk, _ := msk.Constrain(z)
+
+		for _, labels := range allPaths([]string{"corp", "example", "www"}, h.MaxDepth) {
+			for i, j := 0, len(labels)-1; i < j; i, j = i+1, j-1 {
+				labels[i], labels[j] = labels[j], labels[i]
+			}
+			name := strings.Join(labels, ".")
+			x, err := h.DNSInput(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if authorizedRO(msk, csk, x) != tc.predicate(name) {
+				t.Fatalf("pattern %s: authorization of %q does not match the predicate", tc.pattern, name)
+			}
+		}
+	}
+}
+
+func TestDNSNormalization(t *testing.T) {
+	h := NewHierarchy(4)
+	a, _ := h.DNSInput("WWW.Corp.Example.")
+	b, _ := h.DNSInput("www.corp.example")
+	for i := range a {
+		if a[i].Cmp(b[i]) != 0 {
+			t.Fatalf("DNS names are not normalized")
+		}
+	}
+}
+
+func TestHierarchyErrors(t *testing.T) {
+	h := NewHierarchy(2)
+
+	if _, err := h.PathInput("/a/b/c"); err == nil {
+		t.Fatalf("expected an error for a path deeper than MaxDepth")
+	}
+	if _, err := h.PathConstraint("/a/**/b", roModulus); err == nil {
+		t.Fatalf("expected an error for ** before the last segment")
+	}
+	if _, err := h.DNSConstraint("a.*.example", roModulus); err == nil {
+		t.Fatalf("expected an error for a wildcard label not in first position")
+	}
+	if _, err := h.DNSConstraint("*.a.b", roModulus); err == nil {
+		t.Fatalf("expected an error for a pattern that cannot match within MaxDepth")
+	}
+	if _, err := h.PathConstraint("/a", big.NewInt(7)); err == nil {
+		t.Fatalf("expected an error for a modulus smaller than the mismatch bound")
+	}
+}
+
+func TestPathConstraintDDH(t *testing.T) {
+	p := elliptic.P256().Params().N
+	h := NewHierarchy(3)
+
+	z, err := h.PathConstraint("/tenants/acme/**", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pp, msk, _ := ddhcprf.KeyGen(128, h.Length())
+	csk, _ := msk.Constrain(z)
+
+	for _, tc := range []struct {
+		path       string
+		authorized bool
+	}{
+		{"/tenants/acme", true},
+		{"/tenants/acme/logs", true},
+		{"/tenants/other", false},
+		{"/tenants", false},
+	} {
+		x, _ := h.PathInput(tc.path)
+		if authorizedDDH(pp, msk, csk, x) != tc.authorized {
+			t.Fatalf("authorization of %s does not match the predicate", tc.path)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package predicates
+
+import (
+	"errors"
+	"math/big"
+)
+
+var (
+	ErrModulusNotPrime = errors.New("modulus must be prime")
+	ErrDegreeTooSmall  = errors.New("set is larger than the encoding degree")
+)
+
+// SetLength returns the length of the vectors that encode
+// membership in sets of up to degree elements
+func SetLength(degree int) int {
+	return degree + 1
+}
+
+// SetConstraint returns the constraint vector z for membership in set
+// such that <z, SetInput(t)> = 0 iff t is in the set (mod q).
+//
+// z holds the coefficients of the polynomial PROD_i (T - a_i), padded
+// with zeros up to the given degree, and the input is encoded as the
+// powers (1, t, ..., t^degree), so the inner product is the polynomial
+// evaluated at t. Since q is prime the polynomial has no roots besides
+// the elements of the set.
+// set: elements a_i of the set, at most degree of them
+// degree: maximum set size supported by the encoding
+// q: prime inner product modulus
+func SetConstraint(set []*big.Int, degree int, q *big.Int) ([]*big.Int, error) {
+	if !q.ProbablyPrime(20) {
+		return nil, ErrModulusNotPrime
+	}
+	if len(set) > degree {
+		return nil, ErrDegreeTooSmall
+	}
+
+	// coefficients of the polynomial, lowest degree first
+	z := make([]*big.Int, degree+1)
+	for k := 0; k <= degree; k++ {
+		z[k] = big.NewInt(0)
+	}
+	z[0].SetInt64(1)
+
+	// multiply by (T - a) for each element a
+	tmp := big.NewInt(0)
+	for i, a := range set {
+		for k := i + 1; k > 0; k-- {
+			// z[k] = z[k-1] - a*z[k]
+			tmp.Mul(a, z[k])
+			z[k].Sub(z[k-1], tmp).Mod(z[k], q)
+		}
+		z[0].Mul(z[0], a).Neg(z[0]).Mod(z[0], q)
+	}
+
+	return z, nil
+}
+
+// SetInput returns the input vector (1, t, ..., t^degree) mod q
+func SetInput(t *big.Int, degree int, q *big.Int) []*big.Int {
+	x := make([]*big.Int, degree+1)
+	x[0] = big.NewInt(1)
+	for k := 1; k <= degree; k++ {
+		x[k] = big.NewInt(0).Mul(x[k-1], t)
+		x[k].Mod(x[k], q)
+	}
+	return x
+}
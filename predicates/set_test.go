@@ -0,0 +1,103 @@
+package predicates
+
+import (
+	"crypto/elliptic"
+	"math/big"
+	"testing"
+
+	ddhcprf "github.com/sachaservan/cprf/ddh-cprf"
+	rocprf "github.com/sachaservan/cprf/ro-cprf"
+)
+
+func TestSetConstraintEvaluatesPolynomial(t *testing.T) {
+	q := big.NewInt(101)
+	set := []*big.Int{big.NewInt(3), big.NewInt(50), big.NewInt(99)}
+
+	z, err := SetConstraint(set, 5, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for v := int64(0); v < 101; v++ {
+		x := SetInput(big.NewInt(v), 5, q)
+
+		ip := big.NewInt(0)
+		for k := range z {
+			ip.Add(ip, big.NewInt(0).Mul(z[k], x[k]))
+		}
+		ip.Mod(ip, q)
+
+		expected := big.NewInt(1)
+		for _, a := range set {
+			expected.Mul(expected, big.NewInt(v-a.Int64()))
+		}
+		expected.Mod(expected, q)
+
+		if ip.Cmp(expected) != 0 {
+			t.Fatalf("inner product at %d is %v, expected %v", v, ip, expected)
+		}
+	}
+}
+
+func TestSetMembershipRO(t *testing.T) {
+	degree := 4
+	set := []*big.Int{big.NewInt(7), big.NewInt(1000), big.NewInt(123456789)}
+
+	z, err := SetConstraint(set, degree, roModulus)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msk, _ := rocprf.KeyGen(roModulus, SetLength(degree))
+	csk, _ := msk.Constrain(z)
+
+	for _, a := range set {
+		if !authorizedRO(msk, csk, SetInput(a, degree, roModulus)) {
+			t.Fatalf("member %v is not authorized", a)
+		}
+	}
+
+	// members are identified mod q
+	shifted := big.NewInt(0).Add(set[0], roModulus)
+	if !authorizedRO(msk, csk, SetInput(shifted, degree, roModulus)) {
+		t.Fatalf("member %v is not authorized mod q", shifted)
+	}
+
+	for _, v := range []int64{0, 6, 8, 999, 123456788} {
+		if authorizedRO(msk, csk, SetInput(big.NewInt(v), degree, roModulus)) {
+			t.Fatalf("non-member %d is authorized", v)
+		}
+	}
+}
+
+func TestSetMembershipDDH(t *testing.T) {
+	p := elliptic.P256().Params().N
+	degree := 3
+	set := []*big.Int{big.NewInt(2), big.NewInt(3), big.NewInt(5)}
+
+	z, err := SetConstraint(set, degree, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pp, msk, _ := ddhcprf.KeyGen(128, SetLength(degree))
+	csk, _ := msk.Constrain(z)
+
+	for v := int64(0); v < 7; v++ {
+		member := v == 2 || v == 3 || v == 5
+		if authorizedDDH(pp, msk, csk, SetInput(big.NewInt(v), degree, p)) != member {
+			t.Fatalf("authorization of %d does not match set membership", v)
+		}
+	}
+}
+
+func TestSetConstraintErrors(t *testing.T) {
+	set := []*big.Int{big.NewInt(1), big.NewInt(2)}
+
+	if _, err := SetConstraint(set, 1, roModulus); err != ErrDegreeTooSmall {
+		t.Fatalf("expected ErrDegreeTooSmall, got %v", err)
+	}
+	if _, err := SetConstraint(set, 2, big.NewInt(100)); err != ErrModulusNotPrime {
+		t.Fatalf("expected ErrModulusNotPrime, got %v", err)
+	}
+}
@@ -0,0 +1,55 @@
+package rocprf
+
+import (
+	"math/big"
+)
+
+// KeyGenAffine generates a CPRF key for affine predicates <z,x> = c
+// on input vectors of the given length. The key has one extra
+// coordinate used to homogenize the predicate into <z',x'> = 0
+// with z' = (z, -c) and x' = (x, 1).
+// modulus: inner product modulus
+// length: length of the input vector
+// Outputs a CPRF master key
+func KeyGenAffine(modulus *big.Int, length int) (*MasterKey, error) {
+	return KeyGen(modulus, length+1)
+}
+
+// ConstrainAffine outputs a constrained key for the predicate <z,x> = c.
+// The key must have been generated with KeyGenAffine for len(z).
+func (msk *MasterKey) ConstrainAffine(z []*big.Int, c *big.Int) (*ConstrainedKey, error) {
+	if len(z)+1 != msk.length {
+		return nil, ErrLengthMismatch
+	}
+
+	zh := make([]*big.Int, len(z)+1)
+	copy(zh, z)
+	zh[len(z)] = big.NewInt(0).Neg(c)
+	zh[len(z)].Mod(zh[len(z)], msk.modulus)
+
+	return msk.Constrain(zh)
+}
+
+// EvalAffine evaluates the CPRF on the homogenized input (x, 1)
+func (msk *MasterKey) EvalAffine(x []*big.Int) ([]byte, error) {
+	if len(x)+1 != msk.length {
+		return nil, ErrLengthMismatch
+	}
+	return msk.Eval(homogenize(x)), nil
+}
+
+// CEvalAffine evaluates the constrained key on the homogenized input (x, 1)
+func (csk *ConstrainedKey) CEvalAffine(x []*big.Int) ([]byte, error) {
+	if len(x)+1 != csk.length {
+		return nil, ErrLengthMismatch
+	}
+	return csk.CEval(homogenize(x)), nil
+}
+
+// homogenize returns the input vector (x, 1)
+func homogenize(x []*big.Int) []*big.Int {
+	xh := make([]*big.Int, len(x)+1)
+	copy(xh, x)
+	xh[len(x)] = big.NewInt(1)
+	return xh
+}
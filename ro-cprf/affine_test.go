@@ -0,0 +1,63 @@
+package rocprf
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/sachaservan/cprf/linalg"
+)
+
+func TestCPRFAffine(t *testing.T) {
+
+	modulus, _ := big.NewInt(0).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFF61", 16)
+	field, _ := linalg.NewField(modulus)
+
+	length := 10
+	msk, _ := KeyGenAffine(modulus, length)
+
+	z, _ := field.RandomVector(length)
+	c, _ := field.RandomElement()
+	csk, err := msk.ConstrainAffine(z, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for trial := 0; trial < 10; trial++ {
+
+		// x = x0 + k with <z,x0> = c and k in the kernel of z
+		x0, _ := field.Solve(linalg.Matrix{z}, linalg.Vector{c})
+		k, _ := field.SampleOrthogonal(z)
+		x, _ := field.Add(x0, k)
+
+		eval, _ := msk.EvalAffine(x)
+		ceval, _ := csk.CEvalAffine(x)
+		if !bytes.Equal(eval, ceval) {
+			t.Fatalf("EvalAffine and CEvalAffine are not equal on an authorized input")
+		}
+
+		// very small probability of failure in this test case
+		y, _ := field.RandomVector(length)
+		eval, _ = msk.EvalAffine(y)
+		ceval, _ = csk.CEvalAffine(y)
+		if bytes.Equal(eval, ceval) {
+			t.Fatalf("EvalAffine and CEvalAffine are equal on an unauthorized input")
+		}
+	}
+}
+
+func TestCPRFAffineLengthMismatch(t *testing.T) {
+
+	modulus, _ := big.NewInt(0).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFF61", 16)
+
+	length := 10
+	msk, _ := KeyGenAffine(modulus, length)
+	x, _ := generateRandomVector(length+1, modulus)
+
+	if _, err := msk.ConstrainAffine(x, big.NewInt(1)); err != ErrLengthMismatch {
+		t.Fatalf("expected ErrLengthMismatch, got %v", err)
+	}
+	if _, err := msk.EvalAffine(x); err != ErrLengthMismatch {
+		t.Fatalf("expected ErrLengthMismatch, got %v", err)
+	}
+}
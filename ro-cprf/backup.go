@@ -0,0 +1,411 @@
+package rocprf
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"math/big"
+
+	"github.com/sachaservan/cprf/shamir"
+)
+
+// A backup splits the encoding of a master key into t-of-n Shamir shares
+// over the key's modulus, for offline disaster recovery. Every share holds
+// the public part of the encoding (length, modulus and schema ID), a digest
+// of the whole key and one evaluation of the sharing polynomials. Recovery
+// checks the digest of the reconstructed key, so wrong shares are detected
+// and, given more than t shares, excluded.
+//
+// Recovery first decodes the shares, which excludes up to (m - t)/2 wrong
+// shares among m in polynomial time. Beyond that it searches subsets of t
+// shares for one that passes the digest check, and gives up after
+// MaxRecoveryTrials subsets.
+//
+// The encoding of a share ends in a SHA-256 tag over the rest of it, which
+// detects corruption in storage. The tag is not keyed: anyone can forge a
+// share with a valid tag, but not one that passes the digest check.
+
+var (
+	ErrInvalidShare       = errors.New("invalid backup share")
+	ErrNotEnoughShares    = errors.New("not enough consistent backup shares to recover the key")
+	ErrRecoveryFailed     = errors.New("no subset of the backup shares recovers the key")
+	ErrModulusNotPrime    = errors.New("backups require a prime modulus")
+	ErrInvalidBackupShape = errors.New("threshold must be between 1 and the number of shares")
+)
+
+const (
+	backupVersion   = 1
+	backupTagDomain = "ro-cprf backup share v1"
+	keyDigestDomain = "ro-cprf master key digest v1"
+)
+
+// MaxRecoveryTrials bounds the number of subsets of shares that recovery
+// tries when there are too many wrong shares to decode
+const MaxRecoveryTrials = 1 << 12
+
+// BackupShare is one share of a master key backup
+// index: evaluation point of the share, in 1, ..., total
+// threshold: number of shares required for recovery
+// header: public part of the key encoding
+// digest: digest of the key encoding
+// values: shares of the components of z0
+type BackupShare struct {
+	index     int
+	threshold int
+	total     int
+	header    []byte
+	digest    []byte
+	values    []*big.Int
+}
+
+// Backup splits the master key into total shares, any threshold of which recover it
+func (msk *MasterKey) Backup(threshold int, total int) ([]*BackupShare, error) {
+	if threshold < 1 || threshold > total {
+		return nil, ErrInvalidBackupShape
+	}
+	if !msk.modulus.ProbablyPrime(20) {
+		return nil, ErrModulusNotPrime
+	}
+
+	encoding, err := msk.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	header := msk.encodingHeader()
+	digest := keyDigest(encoding)
+
+	secret := make([]*big.Int, msk.length)
+	for i := range secret {
+		secret[i] = big.NewInt(0).Mod(msk.z0[i], msk.modulus)
+	}
+
+	parts, err := shamir.Split(secret, threshold, total, msk.modulus)
+	if err != nil {
+		return nil, err
+	}
+
+	shares := make([]*BackupShare, total)
+	for k, part := range parts {
+		share := &BackupShare{}
+		share.index = part.X
+		share.threshold = threshold
+		share.total = total
+		share.header = header
+		share.digest = digest
+		share.values = part.Values
+		shares[k] = share
+	}
+
+	return shares, nil
+}
+
+// Index returns the index of the share in 1, ..., total
+func (share *BackupShare) Index() int {
+	return share.index
+}
+
+// Threshold returns the number of shares required for recovery
+func (share *BackupShare) Threshold() int {
+	return share.threshold
+}
+
+// RecoverMasterKey recovers a master key from its backup shares. Shares
+// of another key or backup, and shares that are inconsistent with the
+// recovered key, are reported by their position in shares. Recovery may
+// fail with more than (m - t)/2 wrong shares among m shares of the backup
+// once MaxRecoveryTrials subsets have been tried.
+func RecoverMasterKey(shares []*BackupShare) (*MasterKey, []int, error) {
+	group, bad := largestBackupGroup(shares)
+	if len(group) == 0 || len(group) < shares[group[0]].threshold {
+		return nil, bad, ErrNotEnoughShares
+	}
+	first := shares[group[0]]
+
+	msk := &MasterKey{}
+	modulus, err := headerModulus(first.header)
+	if err != nil {
+		return nil, bad, err
+	}
+
+	parts := make([]*shamir.Share, len(group))
+	for k, pos := range group {
+		parts[k] = &shamir.Share{X: shares[pos].index, Values: shares[pos].values}
+	}
+
+	// recovers checks whether the candidate shares reconstruct the key
+	recovers := func(candidate []*shamir.Share) bool {
+		secret, err := shamir.Combine(candidate, modulus)
+		if err != nil {
+			return false
+		}
+		encoding := encodeBackupSecret(first.header, secret, modulus)
+		if !bytes.Equal(keyDigest(encoding), first.digest) {
+			return false
+		}
+		return msk.UnmarshalBinary(encoding) == nil
+	}
+
+	var found []*shamir.Share
+	if _, wrong, err := shamir.Decode(parts, first.threshold, modulus); err == nil {
+		var candidate []*shamir.Share
+		for k := range parts {
+			if !containsPosition(wrong, k) && len(candidate) < first.threshold {
+				candidate = append(candidate, parts[k])
+			}
+		}
+		if recovers(candidate) {
+			found = candidate
+		}
+	}
+
+	if found == nil {
+		trials := 0
+		forEachSubset(len(parts), first.threshold, func(subset []int) bool {
+			trials++
+			if trials > MaxRecoveryTrials {
+				return true
+			}
+			candidate := make([]*shamir.Share, len(subset))
+			for k, s := range subset {
+				candidate[k] = parts[s]
+			}
+			if !recovers(candidate) {
+				return false
+			}
+			found = candidate
+			return true
+		})
+	}
+	if found == nil {
+		return nil, bad, ErrRecoveryFailed
+	}
+
+	// shares off the polynomial through the subset are wrong
+	for k, pos := range group {
+		expected, err := shamir.Interpolate(found, parts[k].X, modulus)
+		if err != nil || !equalValues(expected, parts[k].Values) {
+			bad = append(bad, pos)
+		}
+	}
+
+	return msk, bad, nil
+}
+
+// largestBackupGroup returns the positions of the largest set of shares
+// of the same backup and the positions of all other shares
+func largestBackupGroup(shares []*BackupShare) ([]int, []int) {
+	var best []int
+	for k := range shares {
+		var group []int
+		for m := range shares {
+			if sameBackup(shares[k], shares[m]) {
+				group = append(group, m)
+			}
+		}
+		if len(group) > len(best) {
+			best = group
+		}
+	}
+
+	var rest []int
+	for k := range shares {
+		inBest := false
+		for _, m := range best {
+			inBest = inBest || m == k
+		}
+		if !inBest {
+			rest = append(rest, k)
+		}
+	}
+
+	return best, rest
+}
+
+func sameBackup(a, b *BackupShare) bool {
+	return a.threshold == b.threshold &&
+		a.total == b.total &&
+		len(a.values) == len(b.values) &&
+		bytes.Equal(a.header, b.header) &&
+		bytes.Equal(a.digest, b.digest)
+}
+
+func containsPosition(positions []int, k int) bool {
+	for _, m := range positions {
+		if m == k {
+			return true
+		}
+	}
+	return false
+}
+
+// forEachSubset calls f on the subsets of size k of 0, ..., n-1 in
+// lexicographic order until f returns true
+func forEachSubset(n int, k int, f func([]int) bool) {
+	subset := make([]int, k)
+	for i := range subset {
+		subset[i] = i
+	}
+	for {
+		if f(subset) {
+			return
+		}
+
+		i := k - 1
+		for i >= 0 && subset[i] == n-k+i {
+			i--
+		}
+		if i < 0 {
+			return
+		}
+		subset[i]++
+		for j := i + 1; j < k; j++ {
+			subset[j] = subset[j-1] + 1
+		}
+	}
+}
+
+// headerModulus returns the modulus recorded in a key encoding header
+func headerModulus(header []byte) (*big.Int, error) {
+	data := header[1:]
+	_, read := binary.Uvarint(data)
+	if read <= 0 {
+		return nil, ErrInvalidShare
+	}
+	data = data[read:]
+
+	modulusLen, read := binary.Uvarint(data)
+	if read <= 0 || modulusLen == 0 || modulusLen > uint64(len(data)-read) {
+		return nil, ErrInvalidShare
+	}
+	return big.NewInt(0).SetBytes(data[read : read+int(modulusLen)]), nil
+}
+
+// encodeBackupSecret appends the recovered components of z0 to the header
+func encodeBackupSecret(header []byte, secret []*big.Int, modulus *big.Int) []byte {
+	elemLen := (modulus.BitLen() + 7) / 8
+	elem := make([]byte, elemLen)
+
+	data := append([]byte(nil), header...)
+	for _, v := range secret {
+		data = append(data, v.FillBytes(elem)...)
+	}
+	return data
+}
+
+// keyDigest returns the digest of a master key encoding
+func keyDigest(encoding []byte) []byte {
+	hasher := sha256.New()
+	hasher.Write([]byte(keyDigestDomain))
+	hasher.Write(encoding)
+	return hasher.Sum(nil)
+}
+
+func equalValues(a, b []*big.Int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Cmp(b[i]) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// MarshalBinary encodes the share followed by its integrity tag
+func (share *BackupShare) MarshalBinary() ([]byte, error) {
+	modulus, err := headerModulus(share.header)
+	if err != nil {
+		return nil, err
+	}
+	elemLen := (modulus.BitLen() + 7) / 8
+
+	data := []byte{backupVersion}
+	data = binary.AppendUvarint(data, uint64(share.index))
+	data = binary.AppendUvarint(data, uint64(share.threshold))
+	data = binary.AppendUvarint(data, uint64(share.total))
+	data = binary.AppendUvarint(data, uint64(len(share.header)))
+	data = append(data, share.header...)
+	data = append(data, share.digest...)
+	data = binary.AppendUvarint(data, uint64(len(share.values)))
+
+	elem := make([]byte, elemLen)
+	for _, v := range share.values {
+		data = append(data, v.FillBytes(elem)...)
+	}
+
+	return append(data, backupTag(data)...), nil
+}
+
+// UnmarshalBinary decodes a share encoded by MarshalBinary,
+// returning ErrInvalidShare if its integrity tag does not match
+func (share *BackupShare) UnmarshalBinary(data []byte) error {
+	if len(data) < 1+sha256.Size || data[0] != backupVersion {
+		return ErrInvalidShare
+	}
+	body := data[:len(data)-sha256.Size]
+	if !bytes.Equal(backupTag(body), data[len(body):]) {
+		return ErrInvalidShare
+	}
+	data = body[1:]
+
+	fields := make([]uint64, 4)
+	for f := range fields {
+		v, read := binary.Uvarint(data)
+		if read <= 0 {
+			return ErrInvalidShare
+		}
+		fields[f] = v
+		data = data[read:]
+	}
+	index, threshold, total, headerLen := fields[0], fields[1], fields[2], fields[3]
+	if index == 0 || index > total || threshold == 0 || threshold > total || headerLen > uint64(len(data)) {
+		return ErrInvalidShare
+	}
+	header := append([]byte(nil), data[:headerLen]...)
+	data = data[headerLen:]
+
+	if len(header) < 1 || len(data) < sha256.Size {
+		return ErrInvalidShare
+	}
+	modulus, err := headerModulus(header)
+	if err != nil {
+		return err
+	}
+	digest := append([]byte(nil), data[:sha256.Size]...)
+	data = data[sha256.Size:]
+
+	count, read := binary.Uvarint(data)
+	if read <= 0 {
+		return ErrInvalidShare
+	}
+	data = data[read:]
+
+	elemLen := (modulus.BitLen() + 7) / 8
+	if count > uint64(len(data))/uint64(elemLen) || uint64(len(data)) != count*uint64(elemLen) {
+		return ErrInvalidShare
+	}
+	values := make([]*big.Int, count)
+	for i := range values {
+		values[i] = big.NewInt(0).SetBytes(data[:elemLen])
+		data = data[elemLen:]
+	}
+
+	share.index = int(index)
+	share.threshold = int(threshold)
+	share.total = int(total)
+	share.header = header
+	share.digest = digest
+	share.values = values
+
+	return nil
+}
+
+// backupTag returns the integrity tag of an encoded share
+func backupTag(data []byte) []byte {
+	hasher := sha256.New()
+	hasher.Write([]byte(backupTagDomain))
+	hasher.Write(data)
+	return hasher.Sum(nil)
+}
@@ -0,0 +1,218 @@
+package rocprf
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"math/big"
+	"testing"
+)
+
+func TestMarshalMasterKey(t *testing.T) {
+	modulus, _ := big.NewInt(0).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFF61", 16)
+
+	length := 10
+	msk, _ := KeyGen(modulus, length)
+	msk.SetSchema("example/v1/0011223344556677")
+
+	data, err := msk.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := &MasterKey{}
+	if err := res.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if res.Schema() != msk.Schema() {
+		t.Fatalf("schema ID was not preserved")
+	}
+
+	x, _ := generateRandomVector(length, modulus)
+	if !bytes.Equal(msk.Eval(x), res.Eval(x)) {
+		t.Fatalf("Eval of the decoded key differs")
+	}
+
+	if err := res.UnmarshalBinary(data[:len(data)-1]); err != ErrInvalidKeyEncoding {
+		t.Fatalf("expected ErrInvalidKeyEncoding for a truncated key, got %v", err)
+	}
+
+	// a length whose byte length wraps to 0 mod 2^64 with no components:
+	// 2 byte modulus, empty schema and epoch 0
+	overflow := binary.AppendUvarint([]byte{keyEncodingVersion}, 1<<63)
+	overflow = binary.AppendUvarint(overflow, 2)
+	overflow = append(overflow, 0x01, 0x00)
+	overflow = binary.AppendUvarint(overflow, 0)
+	overflow = binary.AppendUvarint(overflow, 0)
+	if err := res.UnmarshalBinary(overflow); err != ErrInvalidKeyEncoding {
+		t.Fatalf("expected ErrInvalidKeyEncoding for an overflowing length, got %v", err)
+	}
+}
+
+func TestBackupRecover(t *testing.T) {
+	modulus, _ := big.NewInt(0).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFF61", 16)
+
+	length := 10
+	msk, _ := KeyGen(modulus, length)
+	msk.SetSchema("example/v1/0011223344556677")
+
+	shares, err := msk.Backup(3, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// round trip every share through its encoding
+	for k, share := range shares {
+		data, err := share.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		shares[k] = &BackupShare{}
+		if err := shares[k].UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, subset := range [][]int{{0, 1, 2}, {4, 2, 0}, {1, 3, 4}, {0, 1, 2, 3, 4}} {
+		given := make([]*BackupShare, len(subset))
+		for k, s := range subset {
+			given[k] = shares[s]
+		}
+
+		res, bad, err := RecoverMasterKey(given)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(bad) != 0 {
+			t.Fatalf("correct shares reported as wrong: %v", bad)
+		}
+		if res.Schema() != msk.Schema() {
+			t.Fatalf("schema ID was not recovered")
+		}
+
+		for trial := 0; trial < 5; trial++ {
+			x, _ := generateRandomVector(length, modulus)
+			if !bytes.Equal(msk.Eval(x), res.Eval(x)) {
+				t.Fatalf("Eval of the recovered key differs")
+			}
+		}
+	}
+
+	if _, _, err := RecoverMasterKey(shares[:2]); err != ErrNotEnoughShares {
+		t.Fatalf("expected ErrNotEnoughShares, got %v", err)
+	}
+}
+
+func TestBackupWrongShares(t *testing.T) {
+	modulus, _ := big.NewInt(0).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFF61", 16)
+
+	length := 10
+	msk, _ := KeyGen(modulus, length)
+	other, _ := KeyGen(modulus, length)
+	shares, _ := msk.Backup(2, 4)
+	otherShares, _ := other.Backup(2, 4)
+
+	// corruption in storage is detected by the tag
+	data, _ := shares[0].MarshalBinary()
+	data[len(data)/2] ^= 1
+	if err := (&BackupShare{}).UnmarshalBinary(data); err != ErrInvalidShare {
+		t.Fatalf("expected ErrInvalidShare for a corrupted share, got %v", err)
+	}
+
+	// a share with a wrong value but a consistent encoding
+	wrong := &BackupShare{}
+	*wrong = *shares[1]
+	wrong.values = append([]*big.Int(nil), shares[1].values...)
+	wrong.values[3] = big.NewInt(0).Add(wrong.values[3], big.NewInt(1))
+
+	if _, _, err := RecoverMasterKey([]*BackupShare{shares[0], wrong}); err != ErrRecoveryFailed {
+		t.Fatalf("expected ErrRecoveryFailed with a wrong share, got %v", err)
+	}
+
+	// with an extra share the wrong one is excluded and reported
+	res, bad, err := RecoverMasterKey([]*BackupShare{shares[0], wrong, shares[2]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bad) != 1 || bad[0] != 1 {
+		t.Fatalf("expected the wrong share at position 1 to be reported, got %v", bad)
+	}
+	x, _ := generateRandomVector(length, modulus)
+	if !bytes.Equal(msk.Eval(x), res.Eval(x)) {
+		t.Fatalf("Eval of the recovered key differs")
+	}
+
+	// shares of another key are excluded and reported
+	res, bad, err = RecoverMasterKey([]*BackupShare{otherShares[0], shares[3], shares[1]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bad) != 1 || bad[0] != 0 {
+		t.Fatalf("expected the share of another key to be reported, got %v", bad)
+	}
+	if !bytes.Equal(msk.Eval(x), res.Eval(x)) {
+		t.Fatalf("Eval of the recovered key differs")
+	}
+}
+
+func TestBackupManyWrongShares(t *testing.T) {
+	modulus, _ := big.NewInt(0).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFF61", 16)
+
+	length := 6
+	msk, _ := KeyGen(modulus, length)
+	shares, _ := msk.Backup(5, 25)
+
+	// ten wrong shares among 25 are decoded without a subset search
+	for k := 0; k < 20; k += 2 {
+		wrong := &BackupShare{}
+		*wrong = *shares[k]
+		wrong.values = append([]*big.Int(nil), shares[k].values...)
+		wrong.values[k%length] = big.NewInt(0).Add(wrong.values[k%length], big.NewInt(1))
+		shares[k] = wrong
+	}
+
+	res, bad, err := RecoverMasterKey(shares)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bad) != 10 {
+		t.Fatalf("expected 10 wrong shares to be reported, got %v", bad)
+	}
+	x, _ := generateRandomVector(length, modulus)
+	if !bytes.Equal(msk.Eval(x), res.Eval(x)) {
+		t.Fatalf("Eval of the recovered key differs")
+	}
+}
+
+func TestBackupShareUnmarshalCountOverflow(t *testing.T) {
+	modulus, _ := big.NewInt(0).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFF61", 16)
+	msk, _ := KeyGen(modulus, 4)
+	shares, _ := msk.Backup(2, 3)
+
+	// a value count whose byte length is 2^64 with no values
+	empty := &BackupShare{}
+	*empty = *shares[0]
+	empty.values = nil
+	data, _ := empty.MarshalBinary()
+	body := data[:len(data)-sha256.Size-1]
+	body = binary.AppendUvarint(body, 1<<60)
+	data = append(body, backupTag(body)...)
+
+	if err := (&BackupShare{}).UnmarshalBinary(data); err != ErrInvalidShare {
+		t.Fatalf("expected ErrInvalidShare, got %v", err)
+	}
+}
+
+func TestBackupErrors(t *testing.T) {
+	length := 4
+	msk, _ := KeyGen(big.NewInt(1<<20), length)
+	if _, err := msk.Backup(2, 3); err != ErrModulusNotPrime {
+		t.Fatalf("expected ErrModulusNotPrime, got %v", err)
+	}
+
+	modulus, _ := big.NewInt(0).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFF61", 16)
+	msk, _ = KeyGen(modulus, length)
+	if _, err := msk.Backup(4, 3); err != ErrInvalidBackupShape {
+		t.Fatalf("expected ErrInvalidBackupShape, got %v", err)
+	}
+}
@@ -11,20 +11,28 @@ import (
 // length: length of the inner product
 // modulus: inner product modulus
 // z0: master key
+// schema: ID of the schema inputs are encoded with, if any
+// epoch: number of times the key has been rotated
 type MasterKey struct {
 	length  int
 	modulus *big.Int
 	z0      []*big.Int
+	schema  string
+	epoch   uint64
 }
 
 // Constrained key for the CPRF
 // length: length of the inner product
 // modulus: inner product modulus
 // z1: constrained key
+// schema: ID of the schema inputs are encoded with, if any
+// epoch: epoch of the master key the key was constrained from
 type ConstrainedKey struct {
 	length  int
 	modulus *big.Int
 	z1      []*big.Int
+	schema  string
+	epoch   uint64
 }
 
 // KeyGen generates a new CPRF key
@@ -59,6 +67,8 @@ func (msk *MasterKey) Constrain(z []*big.Int) (*ConstrainedKey, error) {
 	csk := &ConstrainedKey{}
 	csk.modulus = modulus
 	csk.length = length
+	csk.schema = msk.schema
+	csk.epoch = msk.epoch
 	csk.z1 = make([]*big.Int, length)
 
 	delta, err := generateRandomBigInt(modulus)
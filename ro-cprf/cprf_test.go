@@ -7,6 +7,8 @@ import (
 	"math/big"
 	mrand "math/rand"
 	"testing"
+
+	"github.com/sachaservan/cprf/linalg"
 )
 
 func generateRandomBit() int {
@@ -78,6 +80,31 @@ func TestCPRFUnauthorized(t *testing.T) {
 
 }
 
+func TestCPRFOrthogonalInputs(t *testing.T) {
+
+	modulus, _ := big.NewInt(0).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFF61", 16)
+	field, _ := linalg.NewField(modulus)
+
+	length := 10
+	msk, _ := KeyGen(modulus, length)
+
+	// arbitrary (dense) constraint
+	z, _ := field.RandomVector(length)
+	csk, _ := msk.Constrain(z)
+
+	for trial := 0; trial < 10; trial++ {
+		x, _ := field.SampleOrthogonal(z)
+		if !bytes.Equal(msk.Eval(x), csk.CEval(x)) {
+			t.Fatalf("Eval and CEval are not equal on an authorized input")
+		}
+
+		y, _ := field.SampleNonOrthogonal(z)
+		if bytes.Equal(msk.Eval(y), csk.CEval(y)) {
+			t.Fatalf("Eval and CEval are equal on an unauthorized input")
+		}
+	}
+}
+
 func BenchmarkEval(b *testing.B) {
 	modulus, _ := big.NewInt(0).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFF61", 16)
 
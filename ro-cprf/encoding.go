@@ -0,0 +1,105 @@
+package rocprf
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/big"
+)
+
+var (
+	ErrInvalidKeyEncoding = errors.New("invalid master key encoding")
+)
+
+const keyEncodingVersion = 2
+
+// MarshalBinary encodes the master key as its length, modulus, schema
+// ID, epoch and the components of z0 as fixed-width integers
+func (msk *MasterKey) MarshalBinary() ([]byte, error) {
+	data := msk.encodingHeader()
+
+	elemLen := (msk.modulus.BitLen() + 7) / 8
+	elem := make([]byte, elemLen)
+	for i := 0; i < msk.length; i++ {
+		v := big.NewInt(0).Mod(msk.z0[i], msk.modulus)
+		data = append(data, v.FillBytes(elem)...)
+	}
+
+	return data, nil
+}
+
+// encodingHeader returns the encoding of the master key up to the components of z0
+func (msk *MasterKey) encodingHeader() []byte {
+	data := []byte{keyEncodingVersion}
+	data = binary.AppendUvarint(data, uint64(msk.length))
+	modulus := msk.modulus.Bytes()
+	data = binary.AppendUvarint(data, uint64(len(modulus)))
+	data = append(data, modulus...)
+	data = binary.AppendUvarint(data, uint64(len(msk.schema)))
+	data = append(data, msk.schema...)
+	data = binary.AppendUvarint(data, msk.epoch)
+	return data
+}
+
+// UnmarshalBinary decodes a master key encoded by MarshalBinary.
+// Version 1 encodings, which have no epoch, are also accepted.
+func (msk *MasterKey) UnmarshalBinary(data []byte) error {
+	if len(data) < 1 || data[0] < 1 || data[0] > keyEncodingVersion {
+		return ErrInvalidKeyEncoding
+	}
+	version := data[0]
+	data = data[1:]
+
+	length, read := binary.Uvarint(data)
+	if read <= 0 {
+		return ErrInvalidKeyEncoding
+	}
+	data = data[read:]
+
+	modulusLen, read := binary.Uvarint(data)
+	if read <= 0 || modulusLen == 0 || modulusLen > uint64(len(data)-read) {
+		return ErrInvalidKeyEncoding
+	}
+	modulus := big.NewInt(0).SetBytes(data[read : read+int(modulusLen)])
+	data = data[read+int(modulusLen):]
+	if modulus.Sign() == 0 {
+		return ErrInvalidKeyEncoding
+	}
+
+	schemaLen, read := binary.Uvarint(data)
+	if read <= 0 || schemaLen > uint64(len(data)-read) {
+		return ErrInvalidKeyEncoding
+	}
+	schema := string(data[read : read+int(schemaLen)])
+	data = data[read+int(schemaLen):]
+
+	epoch := uint64(0)
+	if version >= 2 {
+		epoch, read = binary.Uvarint(data)
+		if read <= 0 {
+			return ErrInvalidKeyEncoding
+		}
+		data = data[read:]
+	}
+
+	elemLen := (modulus.BitLen() + 7) / 8
+	if length == 0 || length > uint64(len(data))/uint64(elemLen) || uint64(len(data)) != length*uint64(elemLen) {
+		return ErrInvalidKeyEncoding
+	}
+
+	z0 := make([]*big.Int, length)
+	for i := range z0 {
+		z0[i] = big.NewInt(0).SetBytes(data[:elemLen])
+		data = data[elemLen:]
+		if z0[i].Cmp(modulus) >= 0 {
+			return ErrInvalidKeyEncoding
+		}
+	}
+
+	msk.length = int(length)
+	msk.modulus = modulus
+	msk.z0 = z0
+	msk.schema = schema
+	msk.epoch = epoch
+
+	return nil
+}
@@ -0,0 +1,143 @@
+package rocprf
+
+import (
+	"crypto/sha256"
+	"errors"
+	"hash"
+	"math/big"
+	"sync"
+)
+
+var (
+	ErrLengthMismatch = errors.New("input length does not match key length")
+	ErrShortBuffer    = errors.New("destination buffer is too short")
+)
+
+// OutputSize is the size in bytes of a CPRF output
+const OutputSize = sha256.Size
+
+// Evaluator evaluates the CPRF under a fixed key and reuses
+// its scratch buffers across calls. Scratch state is kept in
+// a sync.Pool so an Evaluator is safe for concurrent use.
+type Evaluator struct {
+	modulus *big.Int
+	mu      *big.Int // Barrett constant floor(2^(2b) / modulus)
+	bits    int      // bit length b of the modulus
+	length  int
+	zb      []*big.Int // key reduced into [0, modulus)
+	pool    sync.Pool
+}
+
+// scratch state for a single evaluation
+type evalScratch struct {
+	tmp    big.Int
+	k      big.Int
+	q      big.Int
+	r      big.Int
+	buf    []byte
+	hasher hash.Hash
+}
+
+// NewEvaluator returns an Evaluator bound to the master key
+func (msk *MasterKey) NewEvaluator() *Evaluator {
+	return newEvaluator(msk.modulus, msk.length, msk.z0)
+}
+
+// NewEvaluator returns an Evaluator bound to the constrained key
+func (csk *ConstrainedKey) NewEvaluator() *Evaluator {
+	return newEvaluator(csk.modulus, csk.length, csk.z1)
+}
+
+func newEvaluator(modulus *big.Int, length int, zb []*big.Int) *Evaluator {
+	e := &Evaluator{}
+	e.modulus = modulus
+	e.length = length
+	e.bits = modulus.BitLen()
+	e.mu = big.NewInt(1)
+	e.mu.Lsh(e.mu, uint(2*e.bits)).Div(e.mu, modulus)
+
+	// z1 = z0 - z*Delta is not reduced by Constrain
+	e.zb = make([]*big.Int, length)
+	for i := 0; i < length; i++ {
+		e.zb[i] = big.NewInt(0).Mod(zb[i], modulus)
+	}
+
+	e.pool.New = func() interface{} {
+		return &evalScratch{hasher: sha256.New()}
+	}
+	return e
+}
+
+// Eval evaluates the CPRF on x and returns a freshly allocated output
+func (e *Evaluator) Eval(x []*big.Int) ([]byte, error) {
+	dst := make([]byte, OutputSize)
+	if err := e.EvalInto(dst, x); err != nil {
+		return nil, err
+	}
+	return dst, nil
+}
+
+// EvalInto evaluates the CPRF on x and writes the output into dst,
+// which must hold at least OutputSize bytes. Once the scratch buffers
+// have grown to fit the key, EvalInto does not allocate.
+func (e *Evaluator) EvalInto(dst []byte, x []*big.Int) error {
+	if len(dst) < OutputSize {
+		return ErrShortBuffer
+	}
+	if len(x) != e.length {
+		return ErrLengthMismatch
+	}
+
+	s := e.pool.Get().(*evalScratch)
+	defer e.pool.Put(s)
+
+	// inner product mod the modulus
+	s.k.SetInt64(0)
+	for i := 0; i < e.length; i++ {
+		if x[i].Sign() < 0 || x[i].BitLen() > e.bits {
+			// rare inputs outside [0, 2^b) take the allocating path
+			s.tmp.Mul(e.zb[i], x[i])
+			s.k.Add(&s.k, &s.tmp).Mod(&s.k, e.modulus)
+			continue
+		}
+		s.tmp.Mul(e.zb[i], x[i])
+		s.k.Add(&s.k, &s.tmp)
+		e.reduce(s)
+	}
+
+	// same encoding as hashSHA256: minimal big-endian bytes of k
+	// followed by those of each coordinate of x
+	s.hasher.Reset()
+	s.write(&s.k)
+	for i := 0; i < e.length; i++ {
+		s.write(x[i])
+	}
+	s.hasher.Sum(dst[:0])
+
+	return nil
+}
+
+// reduce sets s.k to s.k mod the modulus using Barrett reduction,
+// which avoids the allocations made by big.Int division.
+// Requires 0 <= s.k < 2^(2b).
+func (e *Evaluator) reduce(s *evalScratch) {
+	// operands are never aliased since big.Int.Mul
+	// allocates when the receiver is also an argument
+	s.q.Rsh(&s.k, uint(e.bits-1))
+	s.r.Mul(&s.q, e.mu)
+	s.q.Rsh(&s.r, uint(e.bits+1))
+	s.r.Mul(&s.q, e.modulus)
+	s.k.Sub(&s.k, &s.r)
+	for s.k.Cmp(e.modulus) >= 0 {
+		s.k.Sub(&s.k, e.modulus)
+	}
+}
+
+// write hashes the minimal big-endian encoding of v
+func (s *evalScratch) write(v *big.Int) {
+	l := (v.BitLen() + 7) / 8
+	if cap(s.buf) < l {
+		s.buf = make([]byte, l)
+	}
+	s.hasher.Write(v.FillBytes(s.buf[:l]))
+}
@@ -0,0 +1,147 @@
+package rocprf
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"sync"
+	"testing"
+)
+
+func TestEvaluatorMatchesEval(t *testing.T) {
+
+	modulus, _ := big.NewInt(0).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFF61", 16)
+
+	length := 10
+	msk, _ := KeyGen(modulus, length)
+	z, _ := generateRandomVector(length, modulus)
+	csk, _ := msk.Constrain(z)
+
+	mev := msk.NewEvaluator()
+	cev := csk.NewEvaluator()
+
+	dst := make([]byte, OutputSize)
+	for trial := 0; trial < 20; trial++ {
+		x, _ := generateRandomVector(length, modulus)
+
+		// coordinates outside [0, modulus) are not reduced by Eval
+		if trial%2 == 1 {
+			x[0] = big.NewInt(0).Mul(modulus, modulus)
+			x[1] = big.NewInt(-7)
+		}
+
+		if err := mev.EvalInto(dst, x); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(dst, msk.Eval(x)) {
+			t.Fatalf("Evaluator and Eval are not equal")
+		}
+
+		if err := cev.EvalInto(dst, x); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(dst, csk.CEval(x)) {
+			t.Fatalf("Evaluator and CEval are not equal")
+		}
+	}
+}
+
+func TestEvaluatorErrors(t *testing.T) {
+
+	modulus, _ := big.NewInt(0).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFF61", 16)
+
+	length := 10
+	msk, _ := KeyGen(modulus, length)
+	ev := msk.NewEvaluator()
+
+	x, _ := generateRandomVector(length, modulus)
+	if err := ev.EvalInto(make([]byte, OutputSize-1), x); err != ErrShortBuffer {
+		t.Fatalf("expected ErrShortBuffer, got %v", err)
+	}
+	if err := ev.EvalInto(make([]byte, OutputSize), x[:length-1]); err != ErrLengthMismatch {
+		t.Fatalf("expected ErrLengthMismatch, got %v", err)
+	}
+}
+
+func TestEvaluatorConcurrent(t *testing.T) {
+
+	modulus, _ := big.NewInt(0).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFF61", 16)
+
+	length := 50
+	msk, _ := KeyGen(modulus, length)
+	ev := msk.NewEvaluator()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			dst := make([]byte, OutputSize)
+			for i := 0; i < 50; i++ {
+				x, _ := generateRandomVector(length, modulus)
+				if err := ev.EvalInto(dst, x); err != nil {
+					errs <- err
+					return
+				}
+				if !bytes.Equal(dst, msk.Eval(x)) {
+					errs <- fmt.Errorf("concurrent Evaluator output differs from Eval")
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Fatal(err)
+	}
+}
+
+func TestEvaluatorZeroAllocs(t *testing.T) {
+
+	modulus, _ := big.NewInt(0).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFF61", 16)
+
+	length := 100
+	msk, _ := KeyGen(modulus, length)
+	ev := msk.NewEvaluator()
+	x, _ := generateRandomVector(length, modulus)
+	dst := make([]byte, OutputSize)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		ev.EvalInto(dst, x)
+	})
+
+	if allocs != 0 {
+		t.Fatalf("EvalInto allocated %v times per call", allocs)
+	}
+}
+
+func BenchmarkEvalInto(b *testing.B) {
+	modulus, _ := big.NewInt(0).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFF61", 16)
+
+	// Run the benchmark for different parameter sets
+	for _, params := range []struct{ length int }{
+		{10},
+		{50},
+		{100},
+		{500},
+		{1000},
+	} {
+		b.Run(fmt.Sprintf("length=%d", params.length), func(b *testing.B) {
+
+			msk, _ := KeyGen(modulus, params.length)
+			x, _ := generateRandomVector(params.length, modulus)
+			ev := msk.NewEvaluator()
+			dst := make([]byte, OutputSize)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				ev.EvalInto(dst, x)
+			}
+		})
+	}
+}
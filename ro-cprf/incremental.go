@@ -0,0 +1,78 @@
+package rocprf
+
+import (
+	"errors"
+	"math/big"
+)
+
+var (
+	ErrIndexOutOfRange = errors.New("coordinate index is out of range")
+)
+
+// IncrementalEval caches the inner product between a key and an input
+// vector so that changing a single coordinate of the input costs O(1)
+// instead of recomputing the full O(length) inner product.
+// Computing the output still hashes the whole input.
+type IncrementalEval struct {
+	modulus *big.Int
+	zb      []*big.Int
+	x       []*big.Int
+	k       *big.Int // cached inner product <zb, x>
+}
+
+// NewIncrementalEval returns an IncrementalEval of the master key on x
+func (msk *MasterKey) NewIncrementalEval(x []*big.Int) (*IncrementalEval, error) {
+	return newIncrementalEval(msk.modulus, msk.length, msk.z0, x)
+}
+
+// NewIncrementalEval returns an IncrementalEval of the constrained key on x
+func (csk *ConstrainedKey) NewIncrementalEval(x []*big.Int) (*IncrementalEval, error) {
+	return newIncrementalEval(csk.modulus, csk.length, csk.z1, x)
+}
+
+func newIncrementalEval(
+	modulus *big.Int,
+	length int,
+	zb []*big.Int,
+	x []*big.Int) (*IncrementalEval, error) {
+
+	if len(x) != length {
+		return nil, ErrLengthMismatch
+	}
+
+	ie := &IncrementalEval{}
+	ie.modulus = modulus
+	ie.zb = zb
+	ie.x = make([]*big.Int, length)
+	ie.k = big.NewInt(0)
+
+	tmp := big.NewInt(0)
+	for i := 0; i < length; i++ {
+		ie.x[i] = big.NewInt(0).Set(x[i])
+		tmp.Mul(zb[i], x[i])
+		ie.k.Add(ie.k, tmp).Mod(ie.k, modulus)
+	}
+
+	return ie, nil
+}
+
+// Update sets coordinate i of the input to value
+func (ie *IncrementalEval) Update(i int, value *big.Int) error {
+	if i < 0 || i >= len(ie.x) {
+		return ErrIndexOutOfRange
+	}
+
+	// k += zb[i] * (value - x[i])
+	diff := big.NewInt(0).Sub(value, ie.x[i])
+	diff.Mul(diff, ie.zb[i])
+	ie.k.Add(ie.k, diff).Mod(ie.k, ie.modulus)
+
+	ie.x[i].Set(value)
+
+	return nil
+}
+
+// Output returns the CPRF output on the current input
+func (ie *IncrementalEval) Output() []byte {
+	return hashSHA256(ie.k, ie.x)
+}
@@ -0,0 +1,72 @@
+package rocprf
+
+import (
+	"bytes"
+	"math/big"
+	mrand "math/rand"
+	"testing"
+)
+
+func TestIncrementalEvalMatchesEval(t *testing.T) {
+
+	modulus, _ := big.NewInt(0).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFF61", 16)
+
+	length := 50
+	msk, _ := KeyGen(modulus, length)
+	z, _ := generateRandomVector(length, modulus)
+	csk, _ := msk.Constrain(z)
+
+	x, _ := generateRandomVector(length, modulus)
+	mie, err := msk.NewIncrementalEval(x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cie, err := csk.NewIncrementalEval(x)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for trial := 0; trial < 200; trial++ {
+		i := mrand.Intn(length)
+		value, _ := generateRandomBigInt(modulus)
+		if trial%10 == 0 {
+			value = big.NewInt(0)
+		}
+
+		x[i] = value
+		mie.Update(i, value)
+		cie.Update(i, value)
+
+		if !bytes.Equal(mie.Output(), msk.Eval(x)) {
+			t.Fatalf("IncrementalEval and Eval are not equal after %d updates", trial+1)
+		}
+		if !bytes.Equal(cie.Output(), csk.CEval(x)) {
+			t.Fatalf("IncrementalEval and CEval are not equal after %d updates", trial+1)
+		}
+	}
+}
+
+func TestIncrementalEvalOwnsInput(t *testing.T) {
+
+	modulus, _ := big.NewInt(0).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFF61", 16)
+
+	length := 10
+	msk, _ := KeyGen(modulus, length)
+
+	x, _ := generateRandomVector(length, modulus)
+	expected := msk.Eval(x)
+	ie, _ := msk.NewIncrementalEval(x)
+
+	// mutating the caller's vector must not affect the cached state
+	x[0].Add(x[0], big.NewInt(1))
+	if !bytes.Equal(ie.Output(), expected) {
+		t.Fatalf("IncrementalEval changed when the input vector was mutated")
+	}
+
+	if err := ie.Update(length, big.NewInt(1)); err != ErrIndexOutOfRange {
+		t.Fatalf("expected ErrIndexOutOfRange, got %v", err)
+	}
+	if _, err := msk.NewIncrementalEval(x[:length-1]); err != ErrLengthMismatch {
+		t.Fatalf("expected ErrLengthMismatch, got %v", err)
+	}
+}
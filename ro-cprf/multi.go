@@ -0,0 +1,65 @@
+package rocprf
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+var (
+	ErrNoConstraints = errors.New("at least one constraint vector is required")
+)
+
+// ConstrainMulti outputs a constrained key for the conjunction
+// <z_1,x> = 0 AND ... AND <z_k,x> = 0, i.e., for the orthogonal
+// complement of the span of the z_j.
+//
+// The constrained key is z0 - SUM_j Delta_j z_j for independent random
+// Delta_j. Security caveats:
+//   - The security proof of the construction only covers a single
+//     constraint vector; this variant is not covered by it.
+//   - The key equals the single-vector constrained key for the folded
+//     constraint z_1 + SUM_{j>1} (Delta_j/Delta_1) z_j, so an input outside
+//     the span's complement is still authorized with probability 1/modulus.
+//   - When using more than length-1 independent vectors the complement
+//     is trivial and only the zero vector is authorized.
+func (msk *MasterKey) ConstrainMulti(zs [][]*big.Int) (*ConstrainedKey, error) {
+	if len(zs) == 0 {
+		return nil, ErrNoConstraints
+	}
+
+	length := msk.length
+	modulus := msk.modulus
+
+	for j := 0; j < len(zs); j++ {
+		if len(zs[j]) != length {
+			return nil, ErrLengthMismatch
+		}
+	}
+
+	csk := &ConstrainedKey{}
+	csk.modulus = modulus
+	csk.length = length
+	csk.schema = msk.schema
+	csk.epoch = msk.epoch
+	csk.z1 = make([]*big.Int, length)
+	for i := 0; i < length; i++ {
+		csk.z1[i] = big.NewInt(0).Set(msk.z0[i])
+	}
+
+	// the constraint key is computed as z0 - SUM_j z_j*Delta_j
+	tmp := big.NewInt(0)
+	for j := 0; j < len(zs); j++ {
+		delta, err := generateRandomBigInt(modulus)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate delta_%d for constraint: %w", j, err)
+		}
+
+		for i := 0; i < length; i++ {
+			tmp.Mul(delta, zs[j][i])
+			csk.z1[i].Sub(csk.z1[i], tmp)
+		}
+	}
+
+	return csk, nil
+}
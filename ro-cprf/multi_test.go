@@ -0,0 +1,65 @@
+package rocprf
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/sachaservan/cprf/linalg"
+)
+
+func TestConstrainMulti(t *testing.T) {
+
+	modulus, _ := big.NewInt(0).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFF61", 16)
+	field, _ := linalg.NewField(modulus)
+
+	length := 10
+	msk, _ := KeyGen(modulus, length)
+
+	zs := make(linalg.Matrix, 3)
+	for i := range zs {
+		zs[i], _ = field.RandomVector(length)
+	}
+
+	csk, err := msk.ConstrainMulti([][]*big.Int{zs[0], zs[1], zs[2]})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for trial := 0; trial < 10; trial++ {
+
+		// inputs in the intersection of the orthogonal complements
+		x, _ := field.SampleKernel(zs)
+		if !bytes.Equal(msk.Eval(x), csk.CEval(x)) {
+			t.Fatalf("Eval and CEval are not equal on an input satisfying all constraints")
+		}
+
+		// inputs satisfying all but one of the constraints
+		for skip := 0; skip < len(zs); skip++ {
+			rest := make(linalg.Matrix, 0, len(zs)-1)
+			rest = append(rest, zs[:skip]...)
+			rest = append(rest, zs[skip+1:]...)
+
+			y, _ := field.SampleKernel(rest)
+			if bytes.Equal(msk.Eval(y), csk.CEval(y)) {
+				t.Fatalf("Eval and CEval are equal on an input violating constraint %d", skip)
+			}
+		}
+	}
+}
+
+func TestConstrainMultiErrors(t *testing.T) {
+
+	modulus, _ := big.NewInt(0).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFF61", 16)
+
+	length := 10
+	msk, _ := KeyGen(modulus, length)
+	z, _ := generateRandomVector(length-1, modulus)
+
+	if _, err := msk.ConstrainMulti(nil); err != ErrNoConstraints {
+		t.Fatalf("expected ErrNoConstraints, got %v", err)
+	}
+	if _, err := msk.ConstrainMulti([][]*big.Int{z}); err != ErrLengthMismatch {
+		t.Fatalf("expected ErrLengthMismatch, got %v", err)
+	}
+}
@@ -0,0 +1,197 @@
+package rocprf
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/sachaservan/cprf/paillier"
+)
+
+// Oblivious constraining lets a client obtain a key constrained to z
+// without the issuer learning z. Since z1 = z0 - Delta*z is affine in z,
+// the issuer can compute it on Paillier encryptions of z:
+//
+//  1. The client sends its Paillier public key and Enc(z_i) for each i.
+//  2. The issuer returns Enc(w_i) with w_i = z0_i + Delta(q - z_i) + q*rho_i
+//     for a random Delta and random rho_i in [0, q*2^StatisticalSecurity).
+//  3. The client decrypts and sets z1_i = w_i mod q = z0_i - Delta*z_i mod q.
+//
+// The integer w_i is less than the Paillier modulus N, so no wraparound
+// occurs, and rho_i statistically hides everything about z0_i + Delta(q - z_i)
+// but its residue mod q. The issuer only sees ciphertexts. Privacy of the
+// master key holds against semi-honest clients: a malicious client can
+// encrypt values outside [0, q), and proofs of plaintext range would be
+// needed to prevent this.
+
+var (
+	ErrPaillierModulusTooSmall = errors.New("paillier modulus is too small")
+	ErrProtocolState           = errors.New("protocol message out of order")
+	ErrInvalidConstrainMessage = errors.New("invalid oblivious constrain message")
+)
+
+// StatisticalSecurity is the statistical hiding parameter in bits
+const StatisticalSecurity = 128
+
+// ConstrainRequest is the client's message
+type ConstrainRequest struct {
+	PublicKey  *paillier.PublicKey
+	EncryptedZ []*big.Int
+}
+
+// ConstrainResponse is the issuer's message
+type ConstrainResponse struct {
+	EncryptedKey []*big.Int
+	Schema       string
+	Epoch        uint64
+}
+
+// ConstrainClient is the client side of an oblivious Constrain.
+// A client is used once: Request, then Finish.
+type ConstrainClient struct {
+	sk      *paillier.PrivateKey
+	modulus *big.Int
+	z       []*big.Int
+	sent    bool
+	done    bool
+}
+
+// NewConstrainClient returns a client requesting a key constrained to z
+// sk: the client's Paillier key, with a modulus of at least paillier.MinKeySize
+// and at least 2*bits(modulus) + StatisticalSecurity + 2 bits
+// modulus: inner product modulus of the master key
+func NewConstrainClient(sk *paillier.PrivateKey, modulus *big.Int, z []*big.Int) (*ConstrainClient, error) {
+	if err := checkPaillierModulus(&sk.PublicKey, modulus); err != nil {
+		return nil, err
+	}
+
+	c := &ConstrainClient{}
+	c.sk = sk
+	c.modulus = modulus
+	c.z = make([]*big.Int, len(z))
+	for i := range z {
+		c.z[i] = big.NewInt(0).Mod(z[i], modulus)
+	}
+	return c, nil
+}
+
+// Request returns the request message with the encrypted constraint
+func (c *ConstrainClient) Request() (*ConstrainRequest, error) {
+	if c.sent {
+		return nil, ErrProtocolState
+	}
+
+	req := &ConstrainRequest{}
+	req.PublicKey = &c.sk.PublicKey
+	req.EncryptedZ = make([]*big.Int, len(c.z))
+	for i := range c.z {
+		var err error
+		req.EncryptedZ[i], err = c.sk.Encrypt(rand.Reader, c.z[i])
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt constraint component %d: %w", i, err)
+		}
+	}
+
+	c.sent = true
+	return req, nil
+}
+
+// Finish decrypts the response and returns the constrained key
+func (c *ConstrainClient) Finish(resp *ConstrainResponse) (*ConstrainedKey, error) {
+	if !c.sent || c.done {
+		return nil, ErrProtocolState
+	}
+	if resp == nil || len(resp.EncryptedKey) != len(c.z) {
+		return nil, ErrInvalidConstrainMessage
+	}
+
+	csk := &ConstrainedKey{}
+	csk.modulus = c.modulus
+	csk.length = len(c.z)
+	csk.schema = resp.Schema
+	csk.epoch = resp.Epoch
+	csk.z1 = make([]*big.Int, csk.length)
+	for i := range resp.EncryptedKey {
+		w, err := c.sk.Decrypt(resp.EncryptedKey[i])
+		if err != nil {
+			return nil, ErrInvalidConstrainMessage
+		}
+		csk.z1[i] = w.Mod(w, c.modulus)
+	}
+
+	c.done = true
+	return csk, nil
+}
+
+// RespondConstrain answers an oblivious Constrain request with
+// encryptions of the components of a constrained key
+func (msk *MasterKey) RespondConstrain(req *ConstrainRequest) (*ConstrainResponse, error) {
+	if req == nil || req.PublicKey == nil || req.PublicKey.N == nil {
+		return nil, ErrInvalidConstrainMessage
+	}
+	if len(req.EncryptedZ) != msk.length {
+		return nil, ErrLengthMismatch
+	}
+
+	q := msk.modulus
+	pk := req.PublicKey
+	if err := checkPaillierModulus(pk, q); err != nil {
+		return nil, err
+	}
+	for i := range req.EncryptedZ {
+		if err := pk.Validate(req.EncryptedZ[i]); err != nil {
+			return nil, ErrInvalidConstrainMessage
+		}
+	}
+
+	delta, err := generateRandomBigInt(q)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate delta for constraint: %w", err)
+	}
+
+	// rho_i is sampled from [0, q*2^StatisticalSecurity)
+	rhoBound := big.NewInt(0).Lsh(q, StatisticalSecurity)
+
+	negDelta := big.NewInt(0).Neg(delta)
+	deltaQ := big.NewInt(0).Mul(delta, q)
+
+	resp := &ConstrainResponse{}
+	resp.Schema = msk.schema
+	resp.Epoch = msk.epoch
+	resp.EncryptedKey = make([]*big.Int, msk.length)
+	for i := 0; i < msk.length; i++ {
+		rho, err := generateRandomBigInt(rhoBound)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate mask for component %d: %w", i, err)
+		}
+
+		// m = z0_i + Delta*q + q*rho_i
+		m := big.NewInt(0).Mul(q, rho)
+		m.Add(m, deltaQ)
+		m.Add(m, big.NewInt(0).Mod(msk.z0[i], q))
+
+		// Enc(-Delta*z_i + m)
+		c := pk.MulPlaintext(req.EncryptedZ[i], negDelta)
+		c = pk.AddPlaintext(c, m)
+		resp.EncryptedKey[i], err = pk.Rerandomize(rand.Reader, c)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return resp, nil
+}
+
+// checkPaillierModulus checks that N has at least paillier.MinKeySize bits
+// and that N > q^2 * 2^(StatisticalSecurity+2), which bounds the plaintexts
+// w_i = z0_i + Delta(q - z_i) + q*rho_i
+func checkPaillierModulus(pk *paillier.PublicKey, q *big.Int) error {
+	if pk.N.BitLen() < paillier.MinKeySize {
+		return ErrPaillierModulusTooSmall
+	}
+	if pk.N.BitLen() < 2*q.BitLen()+StatisticalSecurity+2 {
+		return ErrPaillierModulusTooSmall
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package rocprf
+
+import (
+	"bytes"
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	"github.com/sachaservan/cprf/linalg"
+	"github.com/sachaservan/cprf/paillier"
+)
+
+func TestObliviousConstrain(t *testing.T) {
+
+	modulus, _ := big.NewInt(0).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFF61", 16)
+	field, _ := linalg.NewField(modulus)
+
+	length := 10
+	msk, _ := KeyGen(modulus, length)
+	msk.SetSchema("users/v1/0011223344556677")
+
+	sk, err := paillier.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	z, _ := field.RandomVector(length)
+	client, err := NewConstrainClient(sk, modulus, z)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the issuer only sees the request
+	req, err := client.Request()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := msk.RespondConstrain(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	csk, err := client.Finish(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if csk.Schema() != msk.Schema() {
+		t.Fatalf("constrained key has schema %q, expected %q", csk.Schema(), msk.Schema())
+	}
+
+	for trial := 0; trial < 10; trial++ {
+		x, _ := field.SampleOrthogonal(z)
+		if !bytes.Equal(msk.Eval(x), csk.CEval(x)) {
+			t.Fatalf("Eval and CEval are not equal on an authorized input")
+		}
+
+		y, _ := field.SampleNonOrthogonal(z)
+		if bytes.Equal(msk.Eval(y), csk.CEval(y)) {
+			t.Fatalf("Eval and CEval are equal on an unauthorized input")
+		}
+	}
+}
+
+func TestObliviousConstrainErrors(t *testing.T) {
+
+	modulus, _ := big.NewInt(0).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFF61", 16)
+
+	length := 4
+	msk, _ := KeyGen(modulus, length)
+	z, _ := generateRandomVector(length, modulus)
+
+	// a 2048 bit modulus is too small for a 1000 bit inner product modulus
+	large := big.NewInt(0).Lsh(big.NewInt(1), 1000)
+	large.Add(large, big.NewInt(1))
+	sk, _ := paillier.GenerateKey(rand.Reader, 2048)
+	if _, err := NewConstrainClient(sk, large, z); err != ErrPaillierModulusTooSmall {
+		t.Fatalf("expected ErrPaillierModulusTooSmall, got %v", err)
+	}
+
+	client, _ := NewConstrainClient(sk, modulus, z)
+	if _, err := client.Finish(&ConstrainResponse{}); err != ErrProtocolState {
+		t.Fatalf("expected ErrProtocolState before the request, got %v", err)
+	}
+
+	req, _ := client.Request()
+	if _, err := client.Request(); err != ErrProtocolState {
+		t.Fatalf("expected ErrProtocolState for a second request, got %v", err)
+	}
+
+	// a 1024 bit modulus is below paillier.MinKeySize even though it
+	// is large enough for the inner product modulus
+	p, _ := rand.Prime(rand.Reader, 512)
+	q, _ := rand.Prime(rand.Reader, 512)
+	small := &ConstrainRequest{PublicKey: paillier.NewPublicKey(big.NewInt(0).Mul(p, q)), EncryptedZ: req.EncryptedZ}
+	if _, err := msk.RespondConstrain(small); err != ErrPaillierModulusTooSmall {
+		t.Fatalf("expected ErrPaillierModulusTooSmall, got %v", err)
+	}
+
+	// requests of the wrong length or with invalid ciphertexts
+	short := &ConstrainRequest{PublicKey: req.PublicKey, EncryptedZ: req.EncryptedZ[1:]}
+	if _, err := msk.RespondConstrain(short); err != ErrLengthMismatch {
+		t.Fatalf("expected ErrLengthMismatch, got %v", err)
+	}
+	invalid := &ConstrainRequest{PublicKey: req.PublicKey, EncryptedZ: append([]*big.Int{big.NewInt(0)}, req.EncryptedZ[1:]...)}
+	if _, err := msk.RespondConstrain(invalid); err != ErrInvalidConstrainMessage {
+		t.Fatalf("expected ErrInvalidConstrainMessage, got %v", err)
+	}
+
+	if _, err := client.Finish(&ConstrainResponse{EncryptedKey: req.EncryptedZ[1:]}); err != ErrInvalidConstrainMessage {
+		t.Fatalf("expected ErrInvalidConstrainMessage for a short response, got %v", err)
+	}
+
+	resp, _ := msk.RespondConstrain(req)
+	if _, err := client.Finish(resp); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Finish(resp); err != ErrProtocolState {
+		t.Fatalf("expected ErrProtocolState after finishing, got %v", err)
+	}
+}
@@ -0,0 +1,173 @@
+package rocprf
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// Rotation replaces z0 by z0 + r for a fresh random r. Since a constrained
+// key is z1 = z0 - Delta z, its holder can move it to the new master key by
+// adding r, without learning z0 or anything about z. The update token
+// carrying r must therefore be sent over a private channel to constrained
+// key holders only: together with the old master key it gives the new one,
+// and a holder of a key constrained for another epoch cannot use it.
+//
+// Outputs under the new key are unrelated to outputs under the old one.
+// Evaluators created before an update keep evaluating the old key.
+
+var (
+	ErrEpochMismatch       = errors.New("update token is for another epoch")
+	ErrInvalidUpdateToken  = errors.New("invalid update token")
+	ErrUpdateTokenMismatch = errors.New("update token does not match the key")
+)
+
+const updateTokenVersion = 1
+
+// UpdateToken moves constrained keys from epoch From to epoch To = From+1
+// modulus: inner product modulus
+// r: offset added to every key
+type UpdateToken struct {
+	From    uint64
+	To      uint64
+	modulus *big.Int
+	r       []*big.Int
+}
+
+// Epoch returns the number of times the master key has been rotated
+func (msk *MasterKey) Epoch() uint64 {
+	return msk.epoch
+}
+
+// Epoch returns the epoch of the master key the key was constrained from
+func (csk *ConstrainedKey) Epoch() uint64 {
+	return csk.epoch
+}
+
+// Rotate returns the master key z0 + r of the next epoch for a random r
+// and the token that updates constrained keys to it. msk is unchanged.
+func (msk *MasterKey) Rotate() (*MasterKey, *UpdateToken, error) {
+
+	modulus := msk.modulus
+
+	token := &UpdateToken{}
+	token.From = msk.epoch
+	token.To = msk.epoch + 1
+	token.modulus = modulus
+	token.r = make([]*big.Int, msk.length)
+
+	next := &MasterKey{}
+	next.length = msk.length
+	next.modulus = modulus
+	next.schema = msk.schema
+	next.epoch = token.To
+	next.z0 = make([]*big.Int, msk.length)
+
+	var err error
+	for i := 0; i < msk.length; i++ {
+		token.r[i], err = generateRandomBigInt(modulus)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to generate rotation offset %d: %w", i, err)
+		}
+		next.z0[i] = big.NewInt(0).Add(msk.z0[i], token.r[i])
+		next.z0[i].Mod(next.z0[i], modulus)
+	}
+
+	return next, token, nil
+}
+
+// ApplyUpdate moves the constrained key to the next epoch with the
+// token of the rotation of its master key
+func (csk *ConstrainedKey) ApplyUpdate(token *UpdateToken) error {
+	if token.From != csk.epoch {
+		return ErrEpochMismatch
+	}
+	if len(token.r) != csk.length || token.modulus.Cmp(csk.modulus) != 0 {
+		return ErrUpdateTokenMismatch
+	}
+
+	for i := 0; i < csk.length; i++ {
+		csk.z1[i] = big.NewInt(0).Add(csk.z1[i], token.r[i])
+		csk.z1[i].Mod(csk.z1[i], csk.modulus)
+	}
+	csk.epoch = token.To
+
+	return nil
+}
+
+// MarshalBinary encodes the token as its epochs, modulus
+// and the components of r as fixed-width integers
+func (token *UpdateToken) MarshalBinary() ([]byte, error) {
+	data := []byte{updateTokenVersion}
+	data = binary.AppendUvarint(data, token.From)
+	data = binary.AppendUvarint(data, token.To)
+	modulus := token.modulus.Bytes()
+	data = binary.AppendUvarint(data, uint64(len(modulus)))
+	data = append(data, modulus...)
+	data = binary.AppendUvarint(data, uint64(len(token.r)))
+
+	elem := make([]byte, len(modulus))
+	for _, v := range token.r {
+		data = append(data, v.FillBytes(elem)...)
+	}
+
+	return data, nil
+}
+
+// UnmarshalBinary decodes a token encoded by MarshalBinary
+func (token *UpdateToken) UnmarshalBinary(data []byte) error {
+	if len(data) < 1 || data[0] != updateTokenVersion {
+		return ErrInvalidUpdateToken
+	}
+	data = data[1:]
+
+	from, read := binary.Uvarint(data)
+	if read <= 0 {
+		return ErrInvalidUpdateToken
+	}
+	data = data[read:]
+
+	to, read := binary.Uvarint(data)
+	if read <= 0 || to != from+1 {
+		return ErrInvalidUpdateToken
+	}
+	data = data[read:]
+
+	modulusLen, read := binary.Uvarint(data)
+	if read <= 0 || modulusLen == 0 || modulusLen > uint64(len(data)-read) {
+		return ErrInvalidUpdateToken
+	}
+	modulus := big.NewInt(0).SetBytes(data[read : read+int(modulusLen)])
+	data = data[read+int(modulusLen):]
+	if modulus.Sign() == 0 {
+		return ErrInvalidUpdateToken
+	}
+
+	length, read := binary.Uvarint(data)
+	if read <= 0 {
+		return ErrInvalidUpdateToken
+	}
+	data = data[read:]
+
+	elemLen := int(modulusLen)
+	if length > uint64(len(data))/uint64(elemLen) || uint64(len(data)) != length*uint64(elemLen) {
+		return ErrInvalidUpdateToken
+	}
+
+	r := make([]*big.Int, length)
+	for i := range r {
+		r[i] = big.NewInt(0).SetBytes(data[:elemLen])
+		data = data[elemLen:]
+		if r[i].Cmp(modulus) >= 0 {
+			return ErrInvalidUpdateToken
+		}
+	}
+
+	token.From = from
+	token.To = to
+	token.modulus = modulus
+	token.r = r
+
+	return nil
+}
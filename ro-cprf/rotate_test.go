@@ -0,0 +1,157 @@
+package rocprf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/big"
+	"testing"
+
+	"github.com/sachaservan/cprf/linalg"
+)
+
+func TestRotate(t *testing.T) {
+
+	modulus, _ := big.NewInt(0).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFF61", 16)
+	field, _ := linalg.NewField(modulus)
+
+	length := 10
+	msk, _ := KeyGen(modulus, length)
+	msk.SetSchema("example/v1/0011223344556677")
+
+	z, _ := field.RandomVector(length)
+	csk, _ := msk.Constrain(z)
+
+	x, _ := field.SampleOrthogonal(z)
+	y, _ := field.SampleNonOrthogonal(z)
+	oldX := msk.Eval(x)
+	oldY := msk.Eval(y)
+
+	next, token, err := msk.Rotate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msk.Epoch() != 0 || next.Epoch() != 1 || token.From != 0 || token.To != 1 {
+		t.Fatalf("unexpected epochs %d, %d and token %d -> %d", msk.Epoch(), next.Epoch(), token.From, token.To)
+	}
+	if next.Schema() != msk.Schema() {
+		t.Fatalf("schema ID was not preserved")
+	}
+
+	// the old key is unchanged
+	if !bytes.Equal(msk.Eval(x), oldX) {
+		t.Fatalf("Rotate changed the old key")
+	}
+
+	// old outputs no longer match
+	if bytes.Equal(next.Eval(x), oldX) || bytes.Equal(next.Eval(y), oldY) {
+		t.Fatalf("outputs of the rotated key match the old outputs")
+	}
+
+	// round trip the token through its encoding
+	data, err := token.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := &UpdateToken{}
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := csk.ApplyUpdate(decoded); err != nil {
+		t.Fatal(err)
+	}
+	if csk.Epoch() != 1 {
+		t.Fatalf("updated key has epoch %d", csk.Epoch())
+	}
+
+	if !bytes.Equal(next.Eval(x), csk.CEval(x)) {
+		t.Fatalf("Eval and CEval are not equal on an authorized input after the update")
+	}
+	if bytes.Equal(next.Eval(y), csk.CEval(y)) {
+		t.Fatalf("Eval and CEval are equal on an unauthorized input after the update")
+	}
+	if bytes.Equal(csk.CEval(x), oldX) {
+		t.Fatalf("updated constrained key matches the old output")
+	}
+
+	// a token cannot be applied twice
+	if err := csk.ApplyUpdate(token); err != ErrEpochMismatch {
+		t.Fatalf("expected ErrEpochMismatch, got %v", err)
+	}
+
+	// keys constrained in the new epoch carry it
+	csk2, _ := next.Constrain(z)
+	if csk2.Epoch() != 1 {
+		t.Fatalf("key constrained from the rotated key has epoch %d", csk2.Epoch())
+	}
+}
+
+func TestRotateChain(t *testing.T) {
+
+	modulus, _ := big.NewInt(0).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFF61", 16)
+	field, _ := linalg.NewField(modulus)
+
+	length := 10
+	msk, _ := KeyGen(modulus, length)
+	z, _ := field.RandomVector(length)
+	csk, _ := msk.Constrain(z)
+
+	tokens := make([]*UpdateToken, 3)
+	for e := range tokens {
+		msk, tokens[e], _ = msk.Rotate()
+	}
+
+	// tokens must be applied in order
+	if err := csk.ApplyUpdate(tokens[1]); err != ErrEpochMismatch {
+		t.Fatalf("expected ErrEpochMismatch, got %v", err)
+	}
+	for _, token := range tokens {
+		if err := csk.ApplyUpdate(token); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	x, _ := field.SampleOrthogonal(z)
+	if !bytes.Equal(msk.Eval(x), csk.CEval(x)) {
+		t.Fatalf("Eval and CEval are not equal after three rotations")
+	}
+
+	// the epoch survives serialization
+	data, _ := msk.MarshalBinary()
+	res := &MasterKey{}
+	res.UnmarshalBinary(data)
+	if res.Epoch() != 3 {
+		t.Fatalf("decoded key has epoch %d", res.Epoch())
+	}
+}
+
+func TestUpdateTokenErrors(t *testing.T) {
+
+	modulus, _ := big.NewInt(0).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFF61", 16)
+	field, _ := linalg.NewField(modulus)
+
+	msk, _ := KeyGen(modulus, 10)
+	other, _ := KeyGen(modulus, 5)
+	z, _ := field.RandomVector(5)
+	csk, _ := other.Constrain(z)
+
+	_, token, _ := msk.Rotate()
+	if err := csk.ApplyUpdate(token); err != ErrUpdateTokenMismatch {
+		t.Fatalf("expected ErrUpdateTokenMismatch, got %v", err)
+	}
+
+	data, _ := token.MarshalBinary()
+	if err := (&UpdateToken{}).UnmarshalBinary(data[:len(data)-1]); err != ErrInvalidUpdateToken {
+		t.Fatalf("expected ErrInvalidUpdateToken for a truncated token, got %v", err)
+	}
+
+	// a length whose byte length is 2^64 with no values
+	empty := &UpdateToken{}
+	*empty = *token
+	empty.r = nil
+	data, _ = empty.MarshalBinary()
+	data = binary.AppendUvarint(data[:len(data)-1], 1<<60)
+	if err := (&UpdateToken{}).UnmarshalBinary(data); err != ErrInvalidUpdateToken {
+		t.Fatalf("expected ErrInvalidUpdateToken for an overflowing length, got %v", err)
+	}
+}
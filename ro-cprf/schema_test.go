@@ -0,0 +1,30 @@
+package rocprf
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestSchemaPropagates(t *testing.T) {
+
+	modulus, _ := big.NewInt(0).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFF61", 16)
+
+	length := 10
+	msk, _ := KeyGen(modulus, length)
+	if msk.Schema() != "" {
+		t.Fatalf("new key has schema %q", msk.Schema())
+	}
+
+	msk.SetSchema("users/v1/0011223344556677")
+
+	z, _ := generateRandomVector(length, modulus)
+	csk, _ := msk.Constrain(z)
+	if csk.Schema() != msk.Schema() {
+		t.Fatalf("constrained key has schema %q, expected %q", csk.Schema(), msk.Schema())
+	}
+
+	csk, _ = msk.ConstrainMulti([][]*big.Int{z, z})
+	if csk.Schema() != msk.Schema() {
+		t.Fatalf("multi-constrained key has schema %q, expected %q", csk.Schema(), msk.Schema())
+	}
+}
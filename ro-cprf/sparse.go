@@ -0,0 +1,56 @@
+package rocprf
+
+import (
+	"errors"
+	"math/big"
+)
+
+var (
+	ErrInvalidSparseIndex = errors.New("sparse indices must be strictly increasing and less than the key length")
+)
+
+// SparseEntry is a coordinate of a sparse input vector
+// Index: position of the coordinate in the dense vector
+// Value: value of the coordinate
+type SparseEntry struct {
+	Index int
+	Value *big.Int
+}
+
+// EvalSparse evaluates the CPRF on the sparse vector x, given as
+// entries sorted by index; all omitted coordinates are zero.
+// The output equals Eval on the corresponding dense vector.
+func (msk *MasterKey) EvalSparse(x []SparseEntry) ([]byte, error) {
+	return commonEvalSparse(msk.modulus, msk.length, msk.z0, x)
+}
+
+// CEvalSparse is the constrained key analogue of EvalSparse
+func (csk *ConstrainedKey) CEvalSparse(x []SparseEntry) ([]byte, error) {
+	return commonEvalSparse(csk.modulus, csk.length, csk.z1, x)
+}
+
+func commonEvalSparse(
+	modulus *big.Int,
+	length int,
+	zb []*big.Int,
+	x []SparseEntry) ([]byte, error) {
+
+	// zero coordinates encode to no bytes in hashSHA256, so hashing
+	// the nonzero values in index order matches the dense encoding
+	values := make([]*big.Int, len(x))
+
+	tmp := big.NewInt(0)
+	k := big.NewInt(0) // inner product result
+	for i := 0; i < len(x); i++ {
+		index := x[i].Index
+		if index < 0 || index >= length || (i > 0 && index <= x[i-1].Index) {
+			return nil, ErrInvalidSparseIndex
+		}
+
+		tmp.Mul(zb[index], x[i].Value)
+		k.Add(k, tmp).Mod(k, modulus)
+		values[i] = x[i].Value
+	}
+
+	return hashSHA256(k, values), nil
+}
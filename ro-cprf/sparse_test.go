@@ -0,0 +1,135 @@
+package rocprf
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	mrand "math/rand"
+	"testing"
+)
+
+// generateRandomSparse returns a random sparse vector with nnz
+// entries together with its dense representation
+func generateRandomSparse(length int, nnz int, max *big.Int) ([]SparseEntry, []*big.Int) {
+	dense := make([]*big.Int, length)
+	for i := 0; i < length; i++ {
+		dense[i] = big.NewInt(0)
+	}
+
+	for _, index := range mrand.Perm(length)[:nnz] {
+		dense[index], _ = generateRandomBigInt(max)
+	}
+
+	sparse := make([]SparseEntry, 0, nnz)
+	for i := 0; i < length; i++ {
+		if dense[i].Sign() != 0 {
+			sparse = append(sparse, SparseEntry{Index: i, Value: dense[i]})
+		}
+	}
+
+	return sparse, dense
+}
+
+func TestEvalSparseMatchesDense(t *testing.T) {
+
+	modulus, _ := big.NewInt(0).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFF61", 16)
+
+	length := 100000
+	msk, _ := KeyGen(modulus, length)
+	z, _ := generateRandomVector(length, modulus)
+	csk, _ := msk.Constrain(z)
+
+	for trial := 0; trial < 5; trial++ {
+		sparse, dense := generateRandomSparse(length, 40, modulus)
+
+		eval, err := msk.EvalSparse(sparse)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(eval, msk.Eval(dense)) {
+			t.Fatalf("EvalSparse and Eval are not equal")
+		}
+
+		ceval, err := csk.CEvalSparse(sparse)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(ceval, csk.CEval(dense)) {
+			t.Fatalf("CEvalSparse and CEval are not equal")
+		}
+	}
+}
+
+func TestEvalSparseAuthorized(t *testing.T) {
+
+	modulus, _ := big.NewInt(0).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFF61", 16)
+
+	length := 100000
+	msk, _ := KeyGen(modulus, length)
+
+	// z is supported on the even coordinates and x on the odd ones
+	z := make([]*big.Int, length)
+	for i := 0; i < length; i++ {
+		z[i] = big.NewInt(0)
+		if i%2 == 0 {
+			z[i], _ = generateRandomBigInt(modulus)
+		}
+	}
+	csk, _ := msk.Constrain(z)
+
+	x := []SparseEntry{
+		{Index: 1, Value: big.NewInt(5)},
+		{Index: 77, Value: big.NewInt(12)},
+		{Index: 99999, Value: big.NewInt(3)},
+	}
+
+	eval, _ := msk.EvalSparse(x)
+	ceval, _ := csk.CEvalSparse(x)
+	if !bytes.Equal(eval, ceval) {
+		t.Fatalf("EvalSparse and CEvalSparse are not equal")
+	}
+}
+
+func TestEvalSparseInvalidIndex(t *testing.T) {
+
+	modulus, _ := big.NewInt(0).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFF61", 16)
+
+	length := 10
+	msk, _ := KeyGen(modulus, length)
+
+	for _, x := range [][]SparseEntry{
+		{{Index: -1, Value: big.NewInt(1)}},
+		{{Index: length, Value: big.NewInt(1)}},
+		{{Index: 3, Value: big.NewInt(1)}, {Index: 3, Value: big.NewInt(2)}},
+		{{Index: 4, Value: big.NewInt(1)}, {Index: 2, Value: big.NewInt(2)}},
+	} {
+		if _, err := msk.EvalSparse(x); err != ErrInvalidSparseIndex {
+			t.Fatalf("expected ErrInvalidSparseIndex, got %v", err)
+		}
+	}
+}
+
+func BenchmarkEvalSparse(b *testing.B) {
+	modulus, _ := big.NewInt(0).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFF61", 16)
+	length := 100000
+
+	msk, _ := KeyGen(modulus, length)
+
+	// Run the benchmark for different parameter sets
+	for _, params := range []struct{ nnz int }{
+		{10},
+		{50},
+		{100},
+	} {
+		b.Run(fmt.Sprintf("nnz=%d", params.nnz), func(b *testing.B) {
+
+			x, _ := generateRandomSparse(length, params.nnz, modulus)
+
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				msk.EvalSparse(x)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package rocprf
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/sachaservan/cprf/internal/coords"
+)
+
+var (
+	ErrTruncatedStream = coords.ErrTruncatedStream
+	ErrCoordinateCount = coords.ErrCoordinateCount
+	ErrInvalidWidth    = errors.New("coordinate width must be positive")
+)
+
+// EvalReader evaluates the CPRF on an input vector read from r.
+// The stream must contain exactly length coordinates, each encoded
+// as an unsigned big-endian integer of width bytes.
+// The output equals Eval on the same vector.
+//
+// The hash input places the inner product before the coordinates, so
+// the coordinates must be seen twice: the stream is read from its current
+// offset, rewound and read again. Memory use does not depend on the
+// length of the vector. Streams that cannot seek, such as pipes, must be
+// spooled to a file first.
+func (msk *MasterKey) EvalReader(r io.ReadSeeker, width int) ([]byte, error) {
+	return commonEvalReader(msk.modulus, msk.length, msk.z0, r, width)
+}
+
+// CEvalReader is the constrained key analogue of EvalReader
+func (csk *ConstrainedKey) CEvalReader(r io.ReadSeeker, width int) ([]byte, error) {
+	return commonEvalReader(csk.modulus, csk.length, csk.z1, r, width)
+}
+
+func commonEvalReader(
+	modulus *big.Int,
+	length int,
+	zb []*big.Int,
+	r io.ReadSeeker,
+	width int) ([]byte, error) {
+
+	if width <= 0 {
+		return nil, ErrInvalidWidth
+	}
+
+	start, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, fmt.Errorf("stream is not seekable: %w", err)
+	}
+
+	// first pass: inner product
+	tmp := big.NewInt(0)
+	xi := big.NewInt(0)
+	k := big.NewInt(0) // inner product result
+	err = coords.Read(r, width, length, func(i int, coord []byte) {
+		xi.SetBytes(coord)
+		tmp.Mul(zb[i], xi)
+		k.Add(k, tmp).Mod(k, modulus)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// same encoding as hashSHA256
+	hasher := sha256.New()
+	hasher.Write(k.Bytes())
+
+	// second pass: hash the coordinates
+	if _, err := r.Seek(start, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to rewind stream: %w", err)
+	}
+	err = coords.Read(r, width, length, func(i int, coord []byte) {
+		hasher.Write(coords.TrimLeadingZeros(coord))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return hasher.Sum(nil), nil
+}
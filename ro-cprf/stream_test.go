@@ -0,0 +1,119 @@
+package rocprf
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/big"
+	"testing"
+)
+
+// encodeVector encodes x as fixed-width big-endian coordinates
+func encodeVector(x []*big.Int, width int) []byte {
+	buf := make([]byte, 0, len(x)*width)
+	for i := 0; i < len(x); i++ {
+		buf = append(buf, x[i].FillBytes(make([]byte, width))...)
+	}
+	return buf
+}
+
+// pipeReader is a reader whose Seek always fails, like a pipe
+type pipeReader struct {
+	r io.Reader
+}
+
+func (o *pipeReader) Read(p []byte) (int, error) {
+	return o.r.Read(p)
+}
+
+func (o *pipeReader) Seek(offset int64, whence int) (int64, error) {
+	return 0, errors.New("illegal seek")
+}
+
+func TestEvalReaderMatchesEval(t *testing.T) {
+
+	modulus, _ := big.NewInt(0).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFF61", 16)
+	width := 16
+
+	length := 1000
+	msk, _ := KeyGen(modulus, length)
+	z, _ := generateRandomVector(length, modulus)
+	csk, _ := msk.Constrain(z)
+
+	x, _ := generateRandomVector(length, modulus)
+	x[3] = big.NewInt(0)
+	x[4] = big.NewInt(1)
+	data := encodeVector(x, width)
+
+	eval, err := msk.EvalReader(bytes.NewReader(data), width)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(eval, msk.Eval(x)) {
+		t.Fatalf("EvalReader and Eval are not equal")
+	}
+
+	ceval, err := csk.CEvalReader(bytes.NewReader(data), width)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(ceval, csk.CEval(x)) {
+		t.Fatalf("CEvalReader and CEval are not equal")
+	}
+}
+
+func TestEvalReaderSeeksFromCurrentOffset(t *testing.T) {
+
+	modulus, _ := big.NewInt(0).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFF61", 16)
+	width := 16
+
+	length := 10
+	msk, _ := KeyGen(modulus, length)
+	x, _ := generateRandomVector(length, modulus)
+
+	header := []byte("header")
+	r := bytes.NewReader(append(header, encodeVector(x, width)...))
+	r.Seek(int64(len(header)), io.SeekStart)
+
+	eval, err := msk.EvalReader(r, width)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(eval, msk.Eval(x)) {
+		t.Fatalf("EvalReader and Eval are not equal")
+	}
+}
+
+func TestEvalReaderErrors(t *testing.T) {
+
+	modulus, _ := big.NewInt(0).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFF61", 16)
+	width := 16
+
+	length := 10
+	msk, _ := KeyGen(modulus, length)
+	x, _ := generateRandomVector(length+1, modulus)
+	data := encodeVector(x, width)
+
+	for _, tc := range []struct {
+		data []byte
+		err  error
+	}{
+		{data[:length*width-3], ErrTruncatedStream},
+		{data[:(length-1)*width], ErrCoordinateCount},
+		{data, ErrCoordinateCount},
+		{data[:length*width+1], ErrCoordinateCount},
+	} {
+		if _, err := msk.EvalReader(bytes.NewReader(tc.data), width); !errors.Is(err, tc.err) {
+			t.Fatalf("expected %v, got %v", tc.err, err)
+		}
+	}
+
+	// streams that cannot seek are rejected rather than buffered
+	if _, err := msk.EvalReader(&pipeReader{bytes.NewReader(data)}, width); err == nil {
+		t.Fatalf("expected an error for a stream that cannot seek")
+	}
+
+	if _, err := msk.EvalReader(bytes.NewReader(data), 0); err != ErrInvalidWidth {
+		t.Fatalf("expected ErrInvalidWidth, got %v", err)
+	}
+}
@@ -0,0 +1,377 @@
+package rocprf
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+)
+
+// A threshold key splits z0 into additive shares z0 = SUM_k share_k (mod q)
+// held by N parties, so that no single host ever holds z0. Since the
+// construction is linear in z0, each party computes its part of the inner
+// product <z0,x> or of the constrained key z0 - Delta z on its own.
+//
+// Opening the raw parts would reveal <share_k,x> for every queried x, and
+// from enough queries share_k itself. Parties therefore add pseudorandom
+// masks that sum to zero over all parties, derived from seeds shared
+// pairwise during key generation, so the combiner only learns the sum.
+// The protocols are secure against a semi-honest combiner and parties.
+
+var (
+	ErrPartyIndex          = errors.New("party index out of range")
+	ErrSetupIncomplete     = errors.New("pairwise seeds have not been received from all parties")
+	ErrDuplicateShare      = errors.New("duplicate message from party")
+	ErrMissingShares       = errors.New("a message from every party is required")
+	ErrParameterMismatch   = errors.New("messages were produced with different parameters")
+	ErrInvalidPairwiseSeed = errors.New("pairwise seed must be PairwiseSeedSize bytes")
+)
+
+// PairwiseSeedSize is the size in bytes of the seeds shared by pairs of parties
+const PairwiseSeedSize = 32
+
+// Party holds one share of a threshold master key
+// index: index of the party in 0, ..., parties-1
+// parties: number of parties
+// share: additive share of z0
+// seeds: seeds shared with each other party (nil at the own index)
+type Party struct {
+	index   int
+	parties int
+	length  int
+	modulus *big.Int
+	share   []*big.Int
+	seeds   [][]byte
+}
+
+// SeedMessage carries the seed a party shares with another party.
+// It must be sent over a private channel.
+type SeedMessage struct {
+	From int
+	To   int
+	Seed []byte
+}
+
+// PartialEval is a party's masked part of the inner product <z0,x>
+type PartialEval struct {
+	Index   int
+	Parties int
+	Value   *big.Int
+}
+
+// PartialConstrainedKey is a party's masked part of the constrained key z0 - Delta z
+type PartialConstrainedKey struct {
+	Index   int
+	Parties int
+	Z1      []*big.Int
+}
+
+// NewParty samples the share of party index of a threshold key and
+// the seeds it shares with the parties of higher index. The returned
+// messages must be delivered to those parties with Receive.
+// modulus: inner product modulus
+// length: length of the input vector
+func NewParty(modulus *big.Int, length int, index int, parties int) (*Party, []*SeedMessage, error) {
+	if parties < 1 || index < 0 || index >= parties {
+		return nil, nil, ErrPartyIndex
+	}
+
+	party := &Party{}
+	party.index = index
+	party.parties = parties
+	party.length = length
+	party.modulus = modulus
+	party.share = make([]*big.Int, length)
+	party.seeds = make([][]byte, parties)
+
+	var err error
+	for i := 0; i < length; i++ {
+		party.share[i], err = generateRandomBigInt(modulus)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to generate key share component %d: %w", i, err)
+		}
+	}
+
+	msgs := make([]*SeedMessage, 0, parties-index-1)
+	for j := index + 1; j < parties; j++ {
+		seed := make([]byte, PairwiseSeedSize)
+		if _, err := io.ReadFull(rand.Reader, seed); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate pairwise seed: %w", err)
+		}
+		party.seeds[j] = seed
+
+		msg := &SeedMessage{}
+		msg.From = index
+		msg.To = j
+		msg.Seed = append([]byte(nil), seed...)
+		msgs = append(msgs, msg)
+	}
+
+	return party, msgs, nil
+}
+
+// KeyGenThreshold runs the key generation of all parties in process
+// and delivers the seed messages between them
+func KeyGenThreshold(modulus *big.Int, length int, parties int) ([]*Party, error) {
+	res := make([]*Party, parties)
+	var msgs []*SeedMessage
+	for k := 0; k < parties; k++ {
+		party, out, err := NewParty(modulus, length, k, parties)
+		if err != nil {
+			return nil, err
+		}
+		res[k] = party
+		msgs = append(msgs, out...)
+	}
+
+	for _, msg := range msgs {
+		if err := res[msg.To].Receive(msg); err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
+// Index returns the index of the party
+func (party *Party) Index() int {
+	return party.index
+}
+
+// Receive stores the seed sent by a party of lower index
+func (party *Party) Receive(msg *SeedMessage) error {
+	if msg.To != party.index || msg.From < 0 || msg.From >= party.index {
+		return ErrPartyIndex
+	}
+	if len(msg.Seed) != PairwiseSeedSize {
+		return ErrInvalidPairwiseSeed
+	}
+	if party.seeds[msg.From] != nil {
+		return ErrDuplicateShare
+	}
+
+	party.seeds[msg.From] = append([]byte(nil), msg.Seed...)
+	return nil
+}
+
+// Ready returns true once the party shares a seed with every other party
+func (party *Party) Ready() bool {
+	for j, seed := range party.seeds {
+		if j != party.index && seed == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// PartialEval returns the masked part <share,x> + mask(x) of the inner product
+func (party *Party) PartialEval(x []*big.Int) (*PartialEval, error) {
+	if !party.Ready() {
+		return nil, ErrSetupIncomplete
+	}
+	if len(x) != party.length {
+		return nil, ErrLengthMismatch
+	}
+
+	modulus := party.modulus
+	masks := party.masks("eval", encodeMaskContext(x, modulus), 1)
+
+	tmp := big.NewInt(0)
+	k := big.NewInt(0).Set(masks[0])
+	for i := 0; i < party.length; i++ {
+		tmp.Mul(party.share[i], x[i])
+		k.Add(k, tmp).Mod(k, modulus)
+	}
+
+	res := &PartialEval{}
+	res.Index = party.index
+	res.Parties = party.parties
+	res.Value = k
+
+	return res, nil
+}
+
+// PartialConstrain returns the masked part share - Delta_k z + mask of the
+// constrained key for a fresh random share Delta_k of Delta. All parties
+// must use the same session, which must not be reused for another key.
+func (party *Party) PartialConstrain(session []byte, z []*big.Int) (*PartialConstrainedKey, error) {
+	if !party.Ready() {
+		return nil, ErrSetupIncomplete
+	}
+	if len(z) != party.length {
+		return nil, ErrLengthMismatch
+	}
+
+	modulus := party.modulus
+
+	delta, err := generateRandomBigInt(modulus)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate delta share for constraint: %w", err)
+	}
+
+	context := append(binary.AppendUvarint(nil, uint64(len(session))), session...)
+	context = append(context, encodeMaskContext(z, modulus)...)
+	masks := party.masks("constrain", context, party.length)
+
+	res := &PartialConstrainedKey{}
+	res.Index = party.index
+	res.Parties = party.parties
+	res.Z1 = make([]*big.Int, party.length)
+	for i := 0; i < party.length; i++ {
+		res.Z1[i] = big.NewInt(0).Mul(delta, z[i])
+		res.Z1[i].Sub(party.share[i], res.Z1[i])
+		res.Z1[i].Add(res.Z1[i], masks[i]).Mod(res.Z1[i], modulus)
+	}
+
+	return res, nil
+}
+
+// CombineEval combines the partial evaluations of all parties into Eval(x)
+func CombineEval(modulus *big.Int, x []*big.Int, partials []*PartialEval) ([]byte, error) {
+	indices := make([]int, len(partials))
+	for k, partial := range partials {
+		indices[k] = partial.Index
+		if partial.Parties != len(partials) {
+			return nil, ErrMissingShares
+		}
+	}
+	if err := checkIndices(indices); err != nil {
+		return nil, err
+	}
+
+	k := big.NewInt(0)
+	for _, partial := range partials {
+		k.Add(k, partial.Value).Mod(k, modulus)
+	}
+
+	return hashSHA256(k, x), nil
+}
+
+// CombineConstrain combines the partial constrained keys of all parties
+// into the constrained key z0 - Delta z for Delta = SUM_k Delta_k
+func CombineConstrain(modulus *big.Int, partials []*PartialConstrainedKey) (*ConstrainedKey, error) {
+	if len(partials) == 0 {
+		return nil, ErrMissingShares
+	}
+
+	length := len(partials[0].Z1)
+	indices := make([]int, len(partials))
+	for k, partial := range partials {
+		indices[k] = partial.Index
+		if partial.Parties != len(partials) {
+			return nil, ErrMissingShares
+		}
+		if len(partial.Z1) != length {
+			return nil, ErrParameterMismatch
+		}
+	}
+	if err := checkIndices(indices); err != nil {
+		return nil, err
+	}
+
+	csk := &ConstrainedKey{}
+	csk.modulus = modulus
+	csk.length = length
+	csk.z1 = make([]*big.Int, length)
+	for i := 0; i < length; i++ {
+		csk.z1[i] = big.NewInt(0)
+		for _, partial := range partials {
+			csk.z1[i].Add(csk.z1[i], partial.Z1[i]).Mod(csk.z1[i], modulus)
+		}
+	}
+
+	return csk, nil
+}
+
+// checkIndices checks that indices is a permutation of 0, ..., len(indices)-1
+func checkIndices(indices []int) error {
+	if len(indices) == 0 {
+		return ErrMissingShares
+	}
+
+	seen := make([]bool, len(indices))
+	for _, k := range indices {
+		if k < 0 || k >= len(indices) {
+			return ErrPartyIndex
+		}
+		if seen[k] {
+			return ErrDuplicateShare
+		}
+		seen[k] = true
+	}
+	return nil
+}
+
+// masks returns count masks for the context that sum to zero over all
+// parties: the mask of party k is SUM_j +-PRF(seed_kj, context), with a
+// plus sign if k < j and a minus sign otherwise
+func (party *Party) masks(label string, context []byte, count int) []*big.Int {
+	modulus := party.modulus
+
+	res := make([]*big.Int, count)
+	for e := range res {
+		res[e] = big.NewInt(0)
+	}
+
+	for j, seed := range party.seeds {
+		if j == party.index {
+			continue
+		}
+		for e, m := range expandMasks(seed, label, context, count, modulus) {
+			if party.index < j {
+				res[e].Add(res[e], m)
+			} else {
+				res[e].Sub(res[e], m)
+			}
+			res[e].Mod(res[e], modulus)
+		}
+	}
+
+	return res
+}
+
+// expandMasks derives count elements mod modulus from the seed with
+// HMAC-SHA256, using 128 extra bits per element so that the reduction
+// mod modulus is statistically close to uniform
+func expandMasks(seed []byte, label string, context []byte, count int, modulus *big.Int) []*big.Int {
+	blocks := (modulus.BitLen() + 128 + 255) / 256
+
+	mac := hmac.New(sha256.New, seed)
+	mac.Write([]byte(label))
+	mac.Write(binary.AppendUvarint(nil, uint64(len(context))))
+	mac.Write(context)
+	prefix := mac.Sum(nil)
+
+	res := make([]*big.Int, count)
+	counter := make([]byte, 8)
+	for e := 0; e < count; e++ {
+		buf := make([]byte, 0, blocks*sha256.Size)
+		for b := 0; b < blocks; b++ {
+			binary.BigEndian.PutUint64(counter, uint64(e*blocks+b))
+			mac := hmac.New(sha256.New, prefix)
+			mac.Write(counter)
+			buf = mac.Sum(buf)
+		}
+		res[e] = big.NewInt(0).SetBytes(buf)
+		res[e].Mod(res[e], modulus)
+	}
+
+	return res
+}
+
+// encodeMaskContext encodes a vector unambiguously after reducing each
+// coordinate mod q, so that inputs with the same residues get the same
+// masks and inputs with different residues (such as x and -x) do not
+func encodeMaskContext(v []*big.Int, q *big.Int) []byte {
+	data := binary.AppendUvarint(nil, uint64(len(v)))
+	for _, vi := range v {
+		b := big.NewInt(0).Mod(vi, q).Bytes()
+		data = binary.AppendUvarint(data, uint64(len(b)))
+		data = append(data, b...)
+	}
+	return data
+}
@@ -0,0 +1,204 @@
+package rocprf
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/sachaservan/cprf/linalg"
+)
+
+// combinedMasterKey returns the master key the parties jointly hold
+func combinedMasterKey(modulus *big.Int, parties []*Party) *MasterKey {
+	length := parties[0].length
+
+	msk := &MasterKey{}
+	msk.modulus = modulus
+	msk.length = length
+	msk.z0 = make([]*big.Int, length)
+	for i := 0; i < length; i++ {
+		msk.z0[i] = big.NewInt(0)
+		for _, party := range parties {
+			msk.z0[i].Add(msk.z0[i], party.share[i]).Mod(msk.z0[i], modulus)
+		}
+	}
+	return msk
+}
+
+func thresholdEval(t *testing.T, modulus *big.Int, parties []*Party, x []*big.Int) []byte {
+	partials := make([]*PartialEval, len(parties))
+	for k, party := range parties {
+		var err error
+		partials[k], err = party.PartialEval(x)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// deliver out of order
+	partials[0], partials[len(partials)-1] = partials[len(partials)-1], partials[0]
+
+	y, err := CombineEval(modulus, x, partials)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return y
+}
+
+func TestThresholdEval(t *testing.T) {
+
+	modulus, _ := big.NewInt(0).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFF61", 16)
+	field, _ := linalg.NewField(modulus)
+
+	length := 10
+	for _, n := range []int{1, 2, 5} {
+		parties, err := KeyGenThreshold(modulus, length, n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		msk := combinedMasterKey(modulus, parties)
+
+		for trial := 0; trial < 5; trial++ {
+			x, _ := field.RandomVector(length)
+			if !bytes.Equal(thresholdEval(t, modulus, parties, x), msk.Eval(x)) {
+				t.Fatalf("threshold Eval and Eval are not equal for %d parties", n)
+			}
+		}
+	}
+}
+
+func TestThresholdConstrain(t *testing.T) {
+
+	modulus, _ := big.NewInt(0).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFF61", 16)
+	field, _ := linalg.NewField(modulus)
+
+	length := 10
+	parties, _ := KeyGenThreshold(modulus, length, 3)
+	msk := combinedMasterKey(modulus, parties)
+
+	z, _ := field.RandomVector(length)
+	partials := make([]*PartialConstrainedKey, len(parties))
+	for k, party := range parties {
+		var err error
+		partials[k], err = party.PartialConstrain([]byte("session 1"), z)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	csk, err := CombineConstrain(modulus, partials)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for trial := 0; trial < 5; trial++ {
+		x, _ := field.SampleOrthogonal(z)
+		if !bytes.Equal(thresholdEval(t, modulus, parties, x), csk.CEval(x)) {
+			t.Fatalf("threshold Eval and CEval are not equal on an authorized input")
+		}
+
+		y, _ := field.SampleNonOrthogonal(z)
+		if bytes.Equal(thresholdEval(t, modulus, parties, y), csk.CEval(y)) {
+			t.Fatalf("threshold Eval and CEval are equal on an unauthorized input")
+		}
+		if !bytes.Equal(msk.Eval(y), thresholdEval(t, modulus, parties, y)) {
+			t.Fatalf("threshold Eval and Eval are not equal")
+		}
+	}
+}
+
+func TestThresholdMasking(t *testing.T) {
+
+	modulus, _ := big.NewInt(0).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFF61", 16)
+	field, _ := linalg.NewField(modulus)
+
+	length := 10
+	parties, _ := KeyGenThreshold(modulus, length, 3)
+
+	// the opened part of a party is not its unmasked inner product
+	x, _ := field.RandomVector(length)
+	partial, _ := parties[1].PartialEval(x)
+	unmasked, _ := field.Dot(parties[1].share, x)
+	if partial.Value.Cmp(unmasked) == 0 {
+		t.Fatalf("partial evaluation is not masked")
+	}
+}
+
+func TestThresholdMaskingNegatedInput(t *testing.T) {
+
+	modulus, _ := big.NewInt(0).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFF61", 16)
+	field, _ := linalg.NewField(modulus)
+
+	length := 10
+	parties, _ := KeyGenThreshold(modulus, length, 3)
+	party := parties[1]
+
+	x, _ := field.RandomVector(length)
+	neg := make([]*big.Int, length)
+	shifted := make([]*big.Int, length)
+	for i := range x {
+		neg[i] = big.NewInt(0).Neg(x[i])
+		shifted[i] = big.NewInt(0).Add(x[i], modulus)
+	}
+
+	// with the same mask on x and -x the sum of the partials would be
+	// twice the mask, which opens <share, x>
+	px, _ := party.PartialEval(x)
+	pneg, _ := party.PartialEval(neg)
+	mask := party.masks("eval", encodeMaskContext(x, modulus), 1)[0]
+	sum := big.NewInt(0).Add(px.Value, pneg.Value)
+	sum.Mod(sum, modulus)
+	if sum.Cmp(big.NewInt(0).Mod(big.NewInt(0).Lsh(mask, 1), modulus)) == 0 {
+		t.Fatalf("partial evaluations of x and -x use the same mask")
+	}
+
+	// inputs with the same residues are the same input
+	pshifted, _ := party.PartialEval(shifted)
+	if pshifted.Value.Cmp(px.Value) != 0 {
+		t.Fatalf("partial evaluations of x and x+q differ")
+	}
+}
+
+func TestThresholdErrors(t *testing.T) {
+
+	modulus, _ := big.NewInt(0).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFF61", 16)
+	field, _ := linalg.NewField(modulus)
+	length := 10
+
+	if _, _, err := NewParty(modulus, length, 3, 3); err != ErrPartyIndex {
+		t.Fatalf("expected ErrPartyIndex, got %v", err)
+	}
+
+	// parties that have not received all seeds
+	party, msgs, _ := NewParty(modulus, length, 2, 3)
+	if len(msgs) != 0 {
+		t.Fatalf("expected no seed messages from the last party, got %d", len(msgs))
+	}
+	x, _ := field.RandomVector(length)
+	if _, err := party.PartialEval(x); err != ErrSetupIncomplete {
+		t.Fatalf("expected ErrSetupIncomplete, got %v", err)
+	}
+
+	parties, _ := KeyGenThreshold(modulus, length, 3)
+	if _, err := parties[0].PartialEval(x[1:]); err != ErrLengthMismatch {
+		t.Fatalf("expected ErrLengthMismatch, got %v", err)
+	}
+
+	partials := make([]*PartialEval, len(parties))
+	for k, party := range parties {
+		partials[k], _ = party.PartialEval(x)
+	}
+
+	if _, err := CombineEval(modulus, x, partials[:2]); err != ErrMissingShares {
+		t.Fatalf("expected ErrMissingShares, got %v", err)
+	}
+	if _, err := CombineEval(modulus, x, []*PartialEval{partials[0], partials[1], partials[1]}); err != ErrDuplicateShare {
+		t.Fatalf("expected ErrDuplicateShare, got %v", err)
+	}
+
+	// a seed delivered twice
+	_, msgs, _ = NewParty(modulus, length, 0, 3)
+	if err := parties[1].Receive(msgs[0]); err != ErrDuplicateShare {
+		t.Fatalf("expected ErrDuplicateShare, got %v", err)
+	}
+}
@@ -0,0 +1,404 @@
+// Package schema declares typed record attributes and deterministically
+// encodes records into input vectors for the inner-product CPRFs.
+//
+// Each attribute occupies a fixed range of slots of the input vector:
+//   - Enum attributes are one-hot encoded over their declared values.
+//   - Int attributes are one-hot encoded over their range [Min, Max].
+//   - Bool attributes are one-hot encoded over (false, true).
+//   - String attributes are hashed to a field element h and encoded as
+//     the powers (h, h^2, ..., h^Degree), which supports membership in
+//     sets of up to Degree strings.
+//
+// The last slot is the constant one. A schema is identified by its name,
+// version and a fingerprint of its attributes; keys record the ID of the
+// schema they were built for with SetSchema.
+package schema
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+var (
+	ErrInvalidSchema    = errors.New("invalid schema")
+	ErrUnknownAttribute = errors.New("unknown attribute")
+	ErrMissingAttribute = errors.New("missing attribute")
+	ErrInvalidValue     = errors.New("invalid attribute value")
+	ErrSchemaMismatch   = errors.New("key was not built for this schema")
+)
+
+// MaxIntRange is the largest number of values of an Int attribute
+const MaxIntRange = 1 << 12
+
+// Kind is the type of an attribute
+type Kind int
+
+const (
+	Enum Kind = iota
+	Int
+	String
+	Bool
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Enum:
+		return "enum"
+	case Int:
+		return "int"
+	case String:
+		return "string"
+	case Bool:
+		return "bool"
+	}
+	return fmt.Sprintf("Kind(%d)", int(k))
+}
+
+// Attribute is a typed record attribute
+// Name: attribute name
+// Kind: attribute type
+// Values: possible values of an Enum attribute
+// Min, Max: range of an Int attribute
+// Degree: largest set size supported by a String attribute
+type Attribute struct {
+	Name   string
+	Kind   Kind
+	Values []string
+	Min    int64
+	Max    int64
+	Degree int
+}
+
+// EnumAttribute returns an Enum attribute with the given values
+func EnumAttribute(name string, values ...string) Attribute {
+	return Attribute{Name: name, Kind: Enum, Values: values}
+}
+
+// IntAttribute returns an Int attribute with values in [min, max]
+func IntAttribute(name string, min int64, max int64) Attribute {
+	return Attribute{Name: name, Kind: Int, Min: min, Max: max}
+}
+
+// StringAttribute returns a String attribute supporting
+// membership tests in sets of up to degree strings
+func StringAttribute(name string, degree int) Attribute {
+	return Attribute{Name: name, Kind: String, Degree: degree}
+}
+
+// BoolAttribute returns a Bool attribute
+func BoolAttribute(name string) Attribute {
+	return Attribute{Name: name, Kind: Bool}
+}
+
+// Width returns the number of slots of the attribute
+func (a *Attribute) Width() int {
+	switch a.Kind {
+	case Enum:
+		return len(a.Values)
+	case Int:
+		if !a.validRange() {
+			return 0
+		}
+		return int(a.Max-a.Min) + 1
+	case String:
+		return a.Degree
+	case Bool:
+		return 2
+	}
+	return 0
+}
+
+// validRange returns true if the range of an Int attribute is non-empty and
+// has fewer than MaxIntRange values. The difference is taken in uint64 so
+// that ranges such as [MinInt64, MaxInt64] cannot wrap around.
+func (a *Attribute) validRange() bool {
+	return a.Max >= a.Min && uint64(a.Max)-uint64(a.Min) < MaxIntRange
+}
+
+// Index returns the slot of value v within the attribute for one-hot
+// encoded attributes (Enum, Int and Bool)
+func (a *Attribute) Index(v interface{}) (int, error) {
+	switch a.Kind {
+	case Enum:
+		s, ok := v.(string)
+		if !ok {
+			return 0, fmt.Errorf("%w: %s expects a string, got %T", ErrInvalidValue, a.Name, v)
+		}
+		for i, value := range a.Values {
+			if value == s {
+				return i, nil
+			}
+		}
+		return 0, fmt.Errorf("%w: %q is not a value of %s", ErrInvalidValue, s, a.Name)
+
+	case Int:
+		var i int64
+		switch n := v.(type) {
+		case int:
+			i = int64(n)
+		case int64:
+			i = n
+		default:
+			return 0, fmt.Errorf("%w: %s expects an integer, got %T", ErrInvalidValue, a.Name, v)
+		}
+		if i < a.Min || i > a.Max {
+			return 0, fmt.Errorf("%w: %d is outside the range [%d, %d] of %s", ErrInvalidValue, i, a.Min, a.Max, a.Name)
+		}
+		return int(i - a.Min), nil
+
+	case Bool:
+		b, ok := v.(bool)
+		if !ok {
+			return 0, fmt.Errorf("%w: %s expects a bool, got %T", ErrInvalidValue, a.Name, v)
+		}
+		if b {
+			return 1, nil
+		}
+		return 0, nil
+	}
+
+	return 0, fmt.Errorf("%w: %s is not one-hot encoded", ErrInvalidValue, a.Name)
+}
+
+func (a *Attribute) check() error {
+	if a.Name == "" {
+		return fmt.Errorf("%w: attribute without a name", ErrInvalidSchema)
+	}
+
+	switch a.Kind {
+	case Enum:
+		if len(a.Values) == 0 {
+			return fmt.Errorf("%w: enum %s has no values", ErrInvalidSchema, a.Name)
+		}
+		seen := make(map[string]bool)
+		for _, v := range a.Values {
+			if seen[v] {
+				return fmt.Errorf("%w: enum %s has duplicate value %q", ErrInvalidSchema, a.Name, v)
+			}
+			seen[v] = true
+		}
+	case Int:
+		if !a.validRange() {
+			return fmt.Errorf("%w: int %s has range [%d, %d]", ErrInvalidSchema, a.Name, a.Min, a.Max)
+		}
+	case String:
+		if a.Degree <= 0 {
+			return fmt.Errorf("%w: string %s has degree %d", ErrInvalidSchema, a.Name, a.Degree)
+		}
+	case Bool:
+	default:
+		return fmt.Errorf("%w: attribute %s has unknown kind %d", ErrInvalidSchema, a.Name, int(a.Kind))
+	}
+
+	return nil
+}
+
+// Schema is a versioned list of attributes
+type Schema struct {
+	name       string
+	version    int
+	attributes []Attribute
+	offsets    []int
+	index      map[string]int
+	length     int
+	id         string
+}
+
+// Record maps attribute names to values: string for Enum and String
+// attributes, int or int64 for Int attributes and bool for Bool attributes
+type Record map[string]interface{}
+
+// New returns the schema with the given name, version and attributes
+func New(name string, version int, attributes ...Attribute) (*Schema, error) {
+	if name == "" {
+		return nil, fmt.Errorf("%w: schema without a name", ErrInvalidSchema)
+	}
+	if len(attributes) == 0 {
+		return nil, fmt.Errorf("%w: schema %s has no attributes", ErrInvalidSchema, name)
+	}
+
+	s := &Schema{}
+	s.name = name
+	s.version = version
+	s.attributes = make([]Attribute, len(attributes))
+	s.offsets = make([]int, len(attributes))
+	s.index = make(map[string]int)
+
+	for i, a := range attributes {
+		if err := a.check(); err != nil {
+			return nil, err
+		}
+		if _, ok := s.index[a.Name]; ok {
+			return nil, fmt.Errorf("%w: duplicate attribute %s", ErrInvalidSchema, a.Name)
+		}
+
+		a.Values = append([]string(nil), a.Values...)
+		s.attributes[i] = a
+		s.offsets[i] = s.length
+		s.index[a.Name] = i
+		s.length += a.Width()
+	}
+
+	// constant slot
+	s.length++
+
+	s.id = fmt.Sprintf("%s/v%d/%x", name, version, s.fingerprint()[:8])
+
+	return s, nil
+}
+
+// Name returns the name of the schema
+func (s *Schema) Name() string {
+	return s.name
+}
+
+// Version returns the version of the schema
+func (s *Schema) Version() int {
+	return s.version
+}
+
+// ID returns the identifier of the schema, which consists of its name,
+// version and a fingerprint of its attributes, e.g., "users/v2/1f2e3d4c5b6a7980"
+func (s *Schema) ID() string {
+	return s.id
+}
+
+// Length returns the length of the encoded records (and hence the key
+// length for KeyGen)
+func (s *Schema) Length() int {
+	return s.length
+}
+
+// ConstantSlot returns the index of the constant one slot
+func (s *Schema) ConstantSlot() int {
+	return s.length - 1
+}
+
+// Attributes returns the attributes of the schema in slot order
+func (s *Schema) Attributes() []Attribute {
+	return append([]Attribute(nil), s.attributes...)
+}
+
+// Lookup returns the attribute with the given name and the
+// index of its first slot
+func (s *Schema) Lookup(name string) (*Attribute, int, error) {
+	i, ok := s.index[name]
+	if !ok {
+		return nil, 0, fmt.Errorf("%w: %s", ErrUnknownAttribute, name)
+	}
+	return &s.attributes[i], s.offsets[i], nil
+}
+
+// Check returns ErrSchemaMismatch unless id is the ID of the schema
+func (s *Schema) Check(id string) error {
+	if id != s.id {
+		return fmt.Errorf("%w: key has schema %q, expected %q", ErrSchemaMismatch, id, s.id)
+	}
+	return nil
+}
+
+// Encode returns the input vector for the record. Every attribute of the
+// schema must be present in the record and the record must not have any
+// other attributes.
+// q: inner product modulus
+func (s *Schema) Encode(record Record, q *big.Int) ([]*big.Int, error) {
+	for name := range record {
+		if _, ok := s.index[name]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownAttribute, name)
+		}
+	}
+
+	x := make([]*big.Int, s.length)
+	for i := range x {
+		x[i] = big.NewInt(0)
+	}
+
+	for i := range s.attributes {
+		a := &s.attributes[i]
+		v, ok := record[a.Name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrMissingAttribute, a.Name)
+		}
+
+		if a.Kind == String {
+			str, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("%w: %s expects a string, got %T", ErrInvalidValue, a.Name, v)
+			}
+			h := HashToField(str, q)
+			x[s.offsets[i]].Set(h)
+			for j := 1; j < a.Degree; j++ {
+				x[s.offsets[i]+j].Mul(x[s.offsets[i]+j-1], h).Mod(x[s.offsets[i]+j], q)
+			}
+			continue
+		}
+
+		j, err := a.Index(v)
+		if err != nil {
+			return nil, err
+		}
+		x[s.offsets[i]+j].SetInt64(1)
+	}
+
+	x[s.ConstantSlot()].SetInt64(1)
+
+	return x, nil
+}
+
+// HashToField maps a string to Z_q by reducing 128 bits more than
+// the size of q of SHA-256 output in counter mode, so that the
+// result is statistically close to uniform
+func HashToField(value string, q *big.Int) *big.Int {
+	size := (q.BitLen()+7)/8 + 16
+
+	buf := make([]byte, 0, size+sha256.Size)
+	for counter := uint32(0); len(buf) < size; counter++ {
+		hasher := sha256.New()
+		hasher.Write([]byte("cprf schema hash-to-field"))
+		binary.Write(hasher, binary.BigEndian, counter)
+		binary.Write(hasher, binary.BigEndian, uint64(len(value)))
+		hasher.Write([]byte(value))
+		buf = hasher.Sum(buf)
+	}
+
+	h := big.NewInt(0).SetBytes(buf[:size])
+	return h.Mod(h, q)
+}
+
+// fingerprint hashes an unambiguous encoding of the schema
+func (s *Schema) fingerprint() []byte {
+	hasher := sha256.New()
+
+	writeString := func(v string) {
+		binary.Write(hasher, binary.BigEndian, uint64(len(v)))
+		hasher.Write([]byte(v))
+	}
+	writeInt := func(v int64) {
+		binary.Write(hasher, binary.BigEndian, v)
+	}
+
+	writeString(s.name)
+	writeInt(int64(s.version))
+	writeInt(int64(len(s.attributes)))
+	for _, a := range s.attributes {
+		writeString(a.Name)
+		writeInt(int64(a.Kind))
+		switch a.Kind {
+		case Enum:
+			writeInt(int64(len(a.Values)))
+			for _, v := range a.Values {
+				writeString(v)
+			}
+		case Int:
+			writeInt(a.Min)
+			writeInt(a.Max)
+		case String:
+			writeInt(int64(a.Degree))
+		}
+	}
+
+	return hasher.Sum(nil)
+}
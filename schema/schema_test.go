@@ -0,0 +1,190 @@
+package schema
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+
+	rocprf "github.com/sachaservan/cprf/ro-cprf"
+)
+
+var testModulus, _ = big.NewInt(0).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFF61", 16)
+
+func testSchema(t *testing.T) *Schema {
+	s, err := New("employees", 1,
+		EnumAttribute("dept", "eng", "sales", "ops"),
+		IntAttribute("level", 1, 5),
+		StringAttribute("region", 3),
+		BoolAttribute("contractor"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestEncode(t *testing.T) {
+	s := testSchema(t)
+	if s.Length() != 3+5+3+2+1 {
+		t.Fatalf("unexpected length %d", s.Length())
+	}
+
+	record := Record{"dept": "sales", "level": 3, "region": "eu", "contractor": false}
+	x, err := s.Encode(record, testModulus)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := HashToField("eu", testModulus)
+	h2 := big.NewInt(0).Mul(h, h)
+	h2.Mod(h2, testModulus)
+	h3 := big.NewInt(0).Mul(h2, h)
+	h3.Mod(h3, testModulus)
+
+	expected := []*big.Int{
+		big.NewInt(0), big.NewInt(1), big.NewInt(0), // dept
+		big.NewInt(0), big.NewInt(0), big.NewInt(1), big.NewInt(0), big.NewInt(0), // level
+		h, h2, h3, // region
+		big.NewInt(1), big.NewInt(0), // contractor
+		big.NewInt(1), // constant
+	}
+	for i := range expected {
+		if x[i].Cmp(expected[i]) != 0 {
+			t.Fatalf("slot %d is %v, expected %v", i, x[i], expected[i])
+		}
+	}
+
+	// encoding is deterministic
+	y, _ := s.Encode(record, testModulus)
+	for i := range x {
+		if x[i].Cmp(y[i]) != 0 {
+			t.Fatalf("encoding is not deterministic")
+		}
+	}
+}
+
+func TestEncodeErrors(t *testing.T) {
+	s := testSchema(t)
+	valid := Record{"dept": "eng", "level": int64(5), "region": "us", "contractor": true}
+	if _, err := s.Encode(valid, testModulus); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		update   Record
+		expected error
+	}{
+		{Record{"dept": "hr"}, ErrInvalidValue},
+		{Record{"dept": 1}, ErrInvalidValue},
+		{Record{"level": 6}, ErrInvalidValue},
+		{Record{"level": "3"}, ErrInvalidValue},
+		{Record{"region": 3}, ErrInvalidValue},
+		{Record{"contractor": "yes"}, ErrInvalidValue},
+		{Record{"team": "a"}, ErrUnknownAttribute},
+	} {
+		record := Record{}
+		for k, v := range valid {
+			record[k] = v
+		}
+		for k, v := range tc.update {
+			record[k] = v
+		}
+		if _, err := s.Encode(record, testModulus); !errors.Is(err, tc.expected) {
+			t.Fatalf("record %v: expected %v, got %v", tc.update, tc.expected, err)
+		}
+	}
+
+	if _, err := s.Encode(Record{"dept": "eng"}, testModulus); !errors.Is(err, ErrMissingAttribute) {
+		t.Fatalf("expected ErrMissingAttribute, got %v", err)
+	}
+}
+
+func TestInvalidSchema(t *testing.T) {
+	for _, attrs := range [][]Attribute{
+		{},
+		{EnumAttribute("a")},
+		{EnumAttribute("a", "x", "x")},
+		{IntAttribute("a", 3, 2)},
+		{IntAttribute("a", 0, MaxIntRange)},
+		{IntAttribute("a", math.MinInt64, math.MaxInt64)},
+		{IntAttribute("a", -1, math.MaxInt64)},
+		{StringAttribute("a", 0)},
+		{BoolAttribute("")},
+		{BoolAttribute("a"), BoolAttribute("a")},
+		{{Name: "a", Kind: Kind(9)}},
+	} {
+		if _, err := New("s", 1, attrs...); !errors.Is(err, ErrInvalidSchema) {
+			t.Fatalf("attributes %v: expected ErrInvalidSchema, got %v", attrs, err)
+		}
+	}
+}
+
+func TestID(t *testing.T) {
+	s := testSchema(t)
+
+	same := testSchema(t)
+	if s.ID() != same.ID() {
+		t.Fatalf("equal schemas have different IDs")
+	}
+
+	bumped, _ := New("employees", 2, s.Attributes()...)
+	attrs := s.Attributes()
+	attrs[0] = EnumAttribute("dept", "eng", "sales", "ops", "hr")
+	changed, _ := New("employees", 1, attrs...)
+
+	for _, other := range []*Schema{bumped, changed} {
+		if s.ID() == other.ID() {
+			t.Fatalf("different schemas have the same ID %s", s.ID())
+		}
+		if err := other.Check(s.ID()); !errors.Is(err, ErrSchemaMismatch) {
+			t.Fatalf("expected ErrSchemaMismatch, got %v", err)
+		}
+	}
+	if err := s.Check(same.ID()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHashToField(t *testing.T) {
+	q := big.NewInt(1000003)
+	a := HashToField("eu", q)
+	if a.Cmp(HashToField("eu", q)) != 0 {
+		t.Fatalf("hash to field is not deterministic")
+	}
+	if a.Cmp(HashToField("us", q)) == 0 {
+		t.Fatalf("distinct strings hash to the same element")
+	}
+	if a.Sign() < 0 || a.Cmp(q) >= 0 {
+		t.Fatalf("hash %v is not reduced mod %v", a, q)
+	}
+}
+
+func TestEncodeCPRF(t *testing.T) {
+	s := testSchema(t)
+	msk, _ := rocprf.KeyGen(testModulus, s.Length())
+	msk.SetSchema(s.ID())
+
+	// constraint: dept == eng, i.e., the sales and ops slots are zero
+	_, offset, _ := s.Lookup("dept")
+	z := make([]*big.Int, s.Length())
+	for i := range z {
+		z[i] = big.NewInt(0)
+	}
+	z[offset+1].SetInt64(1)
+	z[offset+2].SetInt64(1)
+
+	csk, _ := msk.Constrain(z)
+	if err := s.Check(csk.Schema()); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, dept := range []string{"eng", "sales", "ops"} {
+		x, _ := s.Encode(Record{"dept": dept, "level": 2, "region": "eu", "contractor": false}, testModulus)
+		authorized := bytes.Equal(msk.Eval(x), csk.CEval(x))
+		if authorized != (dept == "eng") {
+			t.Fatalf("authorization of dept %s does not match the predicate", dept)
+		}
+	}
+}
@@ -0,0 +1,327 @@
+// Package shamir implements Shamir's t-of-n secret sharing of vectors of
+// elements of a prime field without external dependencies.
+//
+// Every element of the secret is the constant term of its own random
+// polynomial of degree threshold-1, and share i holds the evaluations of
+// all polynomials at x = i for i = 1, ..., n. Any threshold shares determine
+// the secret and fewer reveal nothing about it. Shares carry no integrity
+// protection; callers that need to detect wrong shares must add their own.
+// Decode corrects up to (n - threshold)/2 wrong shares among n.
+package shamir
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/sachaservan/cprf/linalg"
+)
+
+var (
+	ErrModulusNotPrime  = errors.New("modulus must be prime")
+	ErrInvalidThreshold = errors.New("threshold must be between 1 and the number of shares")
+	ErrTooManyShares    = errors.New("number of shares must be smaller than the modulus")
+	ErrNotEnoughShares  = errors.New("not enough shares")
+	ErrDuplicateShare   = errors.New("shares must have distinct indices")
+	ErrLengthMismatch   = errors.New("shares must have the same length")
+	ErrInvalidIndex     = errors.New("share index must be positive")
+	ErrTooManyErrors    = errors.New("too many wrong shares to decode")
+)
+
+// Share is the evaluation at X of the polynomials sharing a secret vector
+type Share struct {
+	X      int
+	Values []*big.Int
+}
+
+// Split splits the secret vector into n shares, any threshold of which
+// reconstruct it.
+// q: prime modulus of the field
+func Split(secret []*big.Int, threshold int, n int, q *big.Int) ([]*Share, error) {
+	if !q.ProbablyPrime(20) {
+		return nil, ErrModulusNotPrime
+	}
+	if threshold < 1 || threshold > n {
+		return nil, ErrInvalidThreshold
+	}
+	if big.NewInt(int64(n)).Cmp(q) >= 0 {
+		return nil, ErrTooManyShares
+	}
+
+	shares := make([]*Share, n)
+	for i := range shares {
+		shares[i] = &Share{}
+		shares[i].X = i + 1
+		shares[i].Values = make([]*big.Int, len(secret))
+	}
+
+	coeffs := make([]*big.Int, threshold)
+	for e, s := range secret {
+		coeffs[0] = big.NewInt(0).Mod(s, q)
+		for k := 1; k < threshold; k++ {
+			c, err := rand.Int(rand.Reader, q)
+			if err != nil {
+				return nil, fmt.Errorf("failed to generate polynomial coefficient: %w", err)
+			}
+			coeffs[k] = c
+		}
+
+		for _, share := range shares {
+			share.Values[e] = evalPolynomial(coeffs, big.NewInt(int64(share.X)), q)
+		}
+	}
+
+	return shares, nil
+}
+
+// Combine reconstructs the secret from shares by interpolation. With at
+// least threshold correct shares the result is the secret; wrong or too few
+// shares give an unrelated vector, which Combine cannot detect.
+// q: prime modulus of the field
+func Combine(shares []*Share, q *big.Int) ([]*big.Int, error) {
+	return Interpolate(shares, 0, q)
+}
+
+// Interpolate returns the values at x of the polynomials of the lowest
+// degree through the shares. For x = 0 this is the secret, and for
+// x = i it is what share i is expected to hold.
+// q: prime modulus of the field
+func Interpolate(shares []*Share, x int, q *big.Int) ([]*big.Int, error) {
+	if err := checkShares(shares); err != nil {
+		return nil, err
+	}
+	length := len(shares[0].Values)
+
+	// Lagrange coefficients l_k(x) = PROD_{m != k} (x - x_m) / (x_k - x_m)
+	lagrange := make([]*big.Int, len(shares))
+	bx := big.NewInt(int64(x))
+	for k, sk := range shares {
+		num := big.NewInt(1)
+		den := big.NewInt(1)
+		tmp := big.NewInt(0)
+		for m, sm := range shares {
+			if m == k {
+				continue
+			}
+			xm := big.NewInt(int64(sm.X))
+			num.Mul(num, tmp.Sub(bx, xm)).Mod(num, q)
+			den.Mul(den, tmp.Sub(big.NewInt(int64(sk.X)), xm)).Mod(den, q)
+		}
+		inv := big.NewInt(0).ModInverse(den, q)
+		if inv == nil {
+			// indices are distinct and smaller than q when shares come from Split
+			return nil, ErrDuplicateShare
+		}
+		lagrange[k] = num.Mul(num, inv).Mod(num, q)
+	}
+
+	res := make([]*big.Int, length)
+	tmp := big.NewInt(0)
+	for e := 0; e < length; e++ {
+		res[e] = big.NewInt(0)
+		for k, share := range shares {
+			tmp.Mul(lagrange[k], share.Values[e])
+			res[e].Add(res[e], tmp).Mod(res[e], q)
+		}
+	}
+
+	return res, nil
+}
+
+// Decode reconstructs the secret from shares of which up to
+// (len(shares) - threshold)/2 may be wrong, and returns the positions in
+// shares of the wrong ones. It runs the Berlekamp-Welch decoder on a random
+// linear combination of the shared polynomials, so its cost is polynomial
+// in the number of shares. With more wrong shares it returns
+// ErrTooManyErrors, or, if they happen to be consistent with another
+// polynomial of degree threshold-1, a wrong secret that callers must detect.
+// q: prime modulus of the field
+func Decode(shares []*Share, threshold int, q *big.Int) ([]*big.Int, []int, error) {
+	if err := checkShares(shares); err != nil {
+		return nil, nil, err
+	}
+	if threshold < 1 || len(shares) < threshold {
+		return nil, nil, ErrNotEnoughShares
+	}
+
+	field, err := linalg.NewField(q)
+	if err != nil {
+		return nil, nil, ErrModulusNotPrime
+	}
+
+	// y_k = SUM_e r_e Values_k[e] lies on a polynomial of degree
+	// threshold-1 for the right shares, and a wrong share is off it
+	// except with probability 1/q
+	r, err := field.RandomVector(len(shares[0].Values))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate combination coefficients: %w", err)
+	}
+	xs := make([]*big.Int, len(shares))
+	ys := make([]*big.Int, len(shares))
+	for k, share := range shares {
+		xs[k] = big.NewInt(int64(share.X))
+		ys[k] = big.NewInt(0)
+		for e := range r {
+			tmp := big.NewInt(0).Mul(r[e], share.Values[e])
+			ys[k].Add(ys[k], tmp).Mod(ys[k], q)
+		}
+	}
+
+	P, err := berlekampWelch(field, xs, ys, threshold, (len(shares)-threshold)/2)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var good []*Share
+	var bad []int
+	for k := range shares {
+		if evalPolynomial(P, xs[k], q).Cmp(ys[k]) == 0 {
+			good = append(good, shares[k])
+		} else {
+			bad = append(bad, k)
+		}
+	}
+
+	// every right share lies on the polynomials through threshold of them
+	basis := good[:threshold]
+	for k, share := range shares {
+		expected, err := Interpolate(basis, share.X, q)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !equalValues(expected, share.Values, q) && !containsIndex(bad, k) {
+			bad = append(bad, k)
+		}
+	}
+	if len(shares)-len(bad) < threshold {
+		return nil, nil, ErrTooManyErrors
+	}
+
+	secret, err := Combine(basis, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return secret, bad, nil
+}
+
+// berlekampWelch returns the coefficients of the polynomial P of degree
+// less than k with P(xs_i) = ys_i for all but at most e points. It solves
+// Q(xs_i) = ys_i E(xs_i) for Q of degree less than k+e and monic E of
+// degree e, and returns P = Q/E.
+func berlekampWelch(field *linalg.Field, xs, ys []*big.Int, k int, e int) ([]*big.Int, error) {
+	q := field.Modulus()
+
+	// unknowns Q_0, ..., Q_{k+e-1}, E_0, ..., E_{e-1}
+	m := make(linalg.Matrix, len(xs))
+	b := make(linalg.Vector, len(xs))
+	for i := range xs {
+		m[i] = make(linalg.Vector, k+2*e)
+		pow := big.NewInt(1)
+		for j := 0; j < k+e; j++ {
+			m[i][j] = big.NewInt(0).Set(pow)
+			if j < e {
+				m[i][k+e+j] = big.NewInt(0).Mul(ys[i], pow)
+				m[i][k+e+j].Neg(m[i][k+e+j]).Mod(m[i][k+e+j], q)
+			}
+			if j == e {
+				b[i] = big.NewInt(0).Mul(ys[i], pow)
+				b[i].Mod(b[i], q)
+			}
+			pow.Mul(pow, xs[i]).Mod(pow, q)
+		}
+	}
+
+	sol, err := field.Solve(m, b)
+	if err == linalg.ErrNoSolution {
+		return nil, ErrTooManyErrors
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	E := append(append([]*big.Int{}, sol[k+e:]...), big.NewInt(1))
+	P, rem := dividePolynomial(sol[:k+e], E, q)
+	for _, c := range rem {
+		if c.Sign() != 0 {
+			return nil, ErrTooManyErrors
+		}
+	}
+	return P, nil
+}
+
+// dividePolynomial returns the quotient and remainder of a by the
+// monic polynomial d, coefficients lowest degree first, mod q
+func dividePolynomial(a, d []*big.Int, q *big.Int) ([]*big.Int, []*big.Int) {
+	rem := make([]*big.Int, len(a))
+	for i := range a {
+		rem[i] = big.NewInt(0).Mod(a[i], q)
+	}
+	if len(a) < len(d) {
+		return nil, rem
+	}
+
+	quo := make([]*big.Int, len(a)-len(d)+1)
+	tmp := big.NewInt(0)
+	for i := len(quo) - 1; i >= 0; i-- {
+		c := big.NewInt(0).Set(rem[i+len(d)-1])
+		quo[i] = c
+		for j := range d {
+			tmp.Mul(c, d[j])
+			rem[i+j].Sub(rem[i+j], tmp).Mod(rem[i+j], q)
+		}
+	}
+	return quo, rem[:len(d)-1]
+}
+
+// checkShares checks that shares is nonempty and that the
+// shares have distinct positive indices and the same length
+func checkShares(shares []*Share) error {
+	if len(shares) == 0 {
+		return ErrNotEnoughShares
+	}
+
+	length := len(shares[0].Values)
+	seen := make(map[int]bool, len(shares))
+	for _, share := range shares {
+		if share.X <= 0 {
+			return ErrInvalidIndex
+		}
+		if seen[share.X] {
+			return ErrDuplicateShare
+		}
+		seen[share.X] = true
+		if len(share.Values) != length {
+			return ErrLengthMismatch
+		}
+	}
+	return nil
+}
+
+func equalValues(a, b []*big.Int, q *big.Int) bool {
+	for i := range a {
+		if b[i] == nil || big.NewInt(0).Mod(b[i], q).Cmp(a[i]) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func containsIndex(indices []int, k int) bool {
+	for _, i := range indices {
+		if i == k {
+			return true
+		}
+	}
+	return false
+}
+
+// evalPolynomial evaluates the polynomial with the given
+// coefficients, lowest degree first, at x mod q
+func evalPolynomial(coeffs []*big.Int, x *big.Int, q *big.Int) *big.Int {
+	res := big.NewInt(0)
+	for k := len(coeffs) - 1; k >= 0; k-- {
+		res.Mul(res, x).Add(res, coeffs[k]).Mod(res, q)
+	}
+	return res
+}
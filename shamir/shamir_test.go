@@ -0,0 +1,161 @@
+package shamir
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+)
+
+func randomVector(length int, q *big.Int) []*big.Int {
+	v := make([]*big.Int, length)
+	for i := range v {
+		v[i], _ = rand.Int(rand.Reader, q)
+	}
+	return v
+}
+
+func equalVectors(a, b []*big.Int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Cmp(b[i]) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSplitCombine(t *testing.T) {
+	q, _ := big.NewInt(0).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFF61", 16)
+	secret := randomVector(8, q)
+
+	for _, params := range []struct{ threshold, n int }{
+		{1, 1},
+		{1, 3},
+		{2, 3},
+		{3, 5},
+		{5, 5},
+	} {
+		shares, err := Split(secret, params.threshold, params.n, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// every window of threshold consecutive shares, and all of them
+		for start := 0; start+params.threshold <= params.n; start++ {
+			res, err := Combine(shares[start:start+params.threshold], q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !equalVectors(res, secret) {
+				t.Fatalf("%d-of-%d: shares %d.. do not reconstruct the secret", params.threshold, params.n, start)
+			}
+		}
+		res, _ := Combine(shares, q)
+		if !equalVectors(res, secret) {
+			t.Fatalf("%d-of-%d: all shares do not reconstruct the secret", params.threshold, params.n)
+		}
+
+		// fewer shares give another vector
+		if params.threshold > 1 {
+			res, _ := Combine(shares[:params.threshold-1], q)
+			if equalVectors(res, secret) {
+				t.Fatalf("%d-of-%d: too few shares reconstruct the secret", params.threshold, params.n)
+			}
+		}
+	}
+}
+
+func TestInterpolate(t *testing.T) {
+	q, _ := big.NewInt(0).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFF61", 16)
+	secret := randomVector(4, q)
+	shares, _ := Split(secret, 3, 6, q)
+
+	for _, share := range shares[3:] {
+		values, err := Interpolate(shares[:3], share.X, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !equalVectors(values, share.Values) {
+			t.Fatalf("interpolated values of share %d are wrong", share.X)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	q, _ := big.NewInt(0).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFF61", 16)
+
+	for _, params := range []struct{ threshold, n, wrong int }{
+		{1, 1, 0}, {2, 3, 0}, {2, 4, 1}, {3, 7, 2}, {10, 40, 15},
+	} {
+		secret := randomVector(5, q)
+		shares, _ := Split(secret, params.threshold, params.n, q)
+
+		// corrupt every other share, one coordinate each
+		var expected []int
+		for k := 0; len(expected) < params.wrong; k += 2 {
+			shares[k].Values[k%5].Add(shares[k].Values[k%5], big.NewInt(1))
+			expected = append(expected, k)
+		}
+
+		res, bad, err := Decode(shares, params.threshold, q)
+		if err != nil {
+			t.Fatalf("%d-of-%d with %d wrong: %v", params.threshold, params.n, params.wrong, err)
+		}
+		if !equalVectors(res, secret) {
+			t.Fatalf("%d-of-%d with %d wrong: decoded another secret", params.threshold, params.n, params.wrong)
+		}
+		if len(bad) != len(expected) {
+			t.Fatalf("%d-of-%d: expected wrong shares %v, got %v", params.threshold, params.n, expected, bad)
+		}
+		for _, k := range expected {
+			if !containsIndex(bad, k) {
+				t.Fatalf("%d-of-%d: wrong share %d not reported in %v", params.threshold, params.n, k, bad)
+			}
+		}
+	}
+}
+
+func TestDecodeTooManyErrors(t *testing.T) {
+	q, _ := big.NewInt(0).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFF61", 16)
+	secret := randomVector(3, q)
+	shares, _ := Split(secret, 3, 7, q)
+
+	// three wrong shares are more than (7-3)/2
+	for _, k := range []int{0, 2, 4} {
+		shares[k].Values[0] = randomVector(1, q)[0]
+	}
+	if _, _, err := Decode(shares, 3, q); err != ErrTooManyErrors {
+		t.Fatalf("expected ErrTooManyErrors, got %v", err)
+	}
+	if _, _, err := Decode(shares[:2], 3, q); err != ErrNotEnoughShares {
+		t.Fatalf("expected ErrNotEnoughShares, got %v", err)
+	}
+}
+
+func TestSplitErrors(t *testing.T) {
+	q := big.NewInt(7)
+	secret := []*big.Int{big.NewInt(3)}
+
+	if _, err := Split(secret, 2, 3, big.NewInt(8)); err != ErrModulusNotPrime {
+		t.Fatalf("expected ErrModulusNotPrime, got %v", err)
+	}
+	if _, err := Split(secret, 4, 3, q); err != ErrInvalidThreshold {
+		t.Fatalf("expected ErrInvalidThreshold, got %v", err)
+	}
+	if _, err := Split(secret, 0, 3, q); err != ErrInvalidThreshold {
+		t.Fatalf("expected ErrInvalidThreshold, got %v", err)
+	}
+	if _, err := Split(secret, 2, 7, q); err != ErrTooManyShares {
+		t.Fatalf("expected ErrTooManyShares, got %v", err)
+	}
+
+	shares, _ := Split(secret, 2, 3, q)
+	if _, err := Combine(nil, q); err != ErrNotEnoughShares {
+		t.Fatalf("expected ErrNotEnoughShares, got %v", err)
+	}
+	if _, err := Combine([]*Share{shares[0], shares[0]}, q); err != ErrDuplicateShare {
+		t.Fatalf("expected ErrDuplicateShare, got %v", err)
+	}
+}